@@ -0,0 +1,72 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	pkgerrors "github.com/pkg/errors"
+
+	"k8-plugin-multicloud/gc"
+)
+
+// ListOrphansHandler scans the cloud regions for objects created by the
+// plugin which don't belong to a stored VNF
+func ListOrphansHandler(w http.ResponseWriter, r *http.Request) {
+	orphans, err := gc.Scan()
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Scan orphaned resources error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := OrphansResponse{
+		Orphans: orphans,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Parsing output of orphaned resources error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+	}
+}
+
+// DeleteOrphansHandler deletes the orphans reported by the last scan for
+// longer than the grace period, or only those of a VNF when the vnf_id query
+// parameter is passed
+func DeleteOrphansHandler(w http.ResponseWriter, r *http.Request) {
+	orphans, err := gc.DeleteOrphans(r.URL.Query().Get("vnf_id"), gc.GracePeriod)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Delete orphaned resources error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := OrphansResponse{
+		Orphans: orphans,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Parsing output of orphaned resources error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	pkgerrors "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+
+	"k8-plugin-multicloud/csar"
+	"k8-plugin-multicloud/db"
+	"k8-plugin-multicloud/krd"
+)
+
+// AdoptHandler creates a VNF instance from Kubernetes objects that already
+// exist in a cluster, so they can be managed through the VNF instances API
+func AdoptHandler(w http.ResponseWriter, r *http.Request) {
+	var resource AdoptVnfRequest
+
+	if r.Body == nil {
+		http.Error(w, "Body empty", http.StatusBadRequest)
+		return
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&resource)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	err = validateBody(resource)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	// Checked again when the namespace is claimed for the tenant
+	tenantName := tenantFromRequest(r)
+	if tenantName != "" {
+		_, _, err = authorizeNamespace(tenantName, resource.CloudRegionID, resource.Namespace)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+
+	kubeclient, err := GetVNFClient(os.Getenv("KUBE_CONFIG_DIR") + "/" + resource.CloudRegionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resourceNameMap := resource.Resources
+	if resource.LabelSelector != "" {
+		resourceNameMap, err = csar.ListResources(resource.Namespace, resource.LabelSelector, &kubeclient)
+		if err != nil {
+			werr := pkgerrors.Wrap(err, "Adopt VNF error")
+			http.Error(w, werr.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if len(resourceNameMap) == 0 {
+			http.Error(w, "No resources match "+resource.LabelSelector, http.StatusNotFound)
+			return
+		}
+	} else {
+		missing, err := csar.FindMissingResources(resourceNameMap, resource.Namespace, &kubeclient)
+		if err != nil {
+			werr := pkgerrors.Wrap(err, "Adopt VNF error")
+			http.Error(w, werr.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if len(missing) > 0 {
+			http.Error(w, fmt.Sprintf("Resources not found: %v", missing), http.StatusNotFound)
+			return
+		}
+	}
+
+	owned, err := ownedResources(resourceNameMap, resource.CloudRegionID, resource.Namespace, &kubeclient)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Adopt VNF error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if len(owned) > 0 {
+		http.Error(w, fmt.Sprintf("Resources owned by another VNF: %v", owned), http.StatusConflict)
+		return
+	}
+
+	// uuid
+	externalVNFID := string(uuid.NewUUID())
+
+	// cloud1-default-uuid
+	internalVNFID := resource.CloudRegionID + "-" + resource.Namespace + "-" + externalVNFID
+
+	if resource.Relabel {
+		err = csar.LabelVNF(resourceNameMap, resource.CsarID, resource.CloudRegionID, resource.Namespace, externalVNFID, &kubeclient)
+		if err != nil {
+			werr := pkgerrors.Wrap(err, "Adopt VNF error")
+			http.Error(w, werr.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	log.Printf("Cloud Region ID: %s, Namespace: %s, Adopted VNF ID: %s ", resource.CloudRegionID, resource.Namespace, externalVNFID)
+
+	storagePolicy := resource.StoragePolicy
+	if storagePolicy == "" {
+		storagePolicy = csar.StoragePolicyDelete
+	}
+
+	// The namespace is locked from its claim until the VNF is registered in it
+	unlock := db.LockNamespace(resource.CloudRegionID, resource.Namespace)
+	defer unlock()
+
+	claim, ok := claimNamespace(w, tenantName, resource.CloudRegionID, resource.Namespace)
+	if !ok {
+		return
+	}
+
+	record := db.VNFRecord{
+		CsarID:        resource.CsarID,
+		CloudRegionID: resource.CloudRegionID,
+		Namespace:     resource.Namespace,
+		StoragePolicy: storagePolicy,
+		Tenant:        tenantName,
+		Resources:     resourceNameMap,
+	}
+
+	err = db.WriteVNFRecord(internalVNFID, record)
+	if err != nil {
+		releaseClaim(tenantName, resource.CloudRegionID, resource.Namespace, claim)
+
+		werr := pkgerrors.Wrap(err, "Adopt VNF error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = db.AddNamespaceVNF(resource.CloudRegionID, resource.Namespace, internalVNFID)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Record VNF namespace error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	csar.RecordRevision(internalVNFID, externalVNFID, record, csar.OperationAdopt)
+
+	resp := CreateVnfResponse{
+		VNFID:         externalVNFID,
+		CloudRegionID: resource.CloudRegionID,
+		Namespace:     resource.Namespace,
+		VNFComponents: resourceNameMap,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Parsing output of adopted VNF error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+	}
+}
+
+// ownedResources returns the objects which already belong to a VNF, either
+// labelled by the plugin or stored in the record of a VNF of the namespace
+func ownedResources(resources map[string][]string, cloudRegionID string, namespace string,
+	kubeclient *kubernetes.Clientset) (map[string][]string, error) {
+
+	managed, err := csar.ListResources(namespace, krd.ManagedBySelector, kubeclient)
+	if err != nil {
+		return nil, err
+	}
+
+	recorded, err := db.ListVNFResources(cloudRegionID + "-" + namespace + "-")
+	if err != nil {
+		return nil, err
+	}
+
+	owned := make(map[string][]string)
+	for resourceName, names := range resources {
+		labelled := make(map[string]bool)
+		for _, name := range managed[resourceName] {
+			labelled[name] = true
+		}
+
+		for _, name := range names {
+			if labelled[name] || recorded[name] {
+				owned[resourceName] = append(owned[resourceName], name)
+			}
+		}
+	}
+
+	return owned, nil
+}
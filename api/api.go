@@ -22,6 +22,7 @@ import (
 	"github.com/gorilla/mux"
 	pkgerrors "github.com/pkg/errors"
 
+	"k8-plugin-multicloud/auth"
 	"k8-plugin-multicloud/db"
 	"k8-plugin-multicloud/krd"
 )
@@ -99,6 +100,11 @@ func CheckInitialSettings() error {
 		return pkgerrors.Cause(err)
 	}
 
+	err = LoadAuthenticator()
+	if err != nil {
+		return pkgerrors.Cause(err)
+	}
+
 	return nil
 }
 
@@ -108,12 +114,52 @@ func NewRouter(kubeconfig string) (s *mux.Router) {
 
 	vnfInstanceHandler := router.PathPrefix("/v1/vnf_instances").Subrouter()
 	vnfInstanceHandler.HandleFunc("/", CreateHandler).Methods("POST").Name("VNFCreation")
+	vnfInstanceHandler.HandleFunc("/adopt", AdoptHandler).Methods("POST")
 	vnfInstanceHandler.HandleFunc("/{cloudRegionID}/{namespace}", ListHandler).Methods("GET")
 	vnfInstanceHandler.HandleFunc("/{cloudRegionID}/{namespace}/{externalVNFID}", DeleteHandler).Methods("DELETE")
 	vnfInstanceHandler.HandleFunc("/{cloudRegionID}/{namespace}/{externalVNFID}", GetHandler).Methods("GET")
+	vnfInstanceHandler.HandleFunc("/{cloudRegionID}/{namespace}/{externalVNFID}/drift", DriftHandler).Methods("GET")
+	vnfInstanceHandler.HandleFunc("/{cloudRegionID}/{namespace}/{externalVNFID}/drift", RecreateDriftHandler).Methods("POST")
+	vnfInstanceHandler.HandleFunc("/{cloudRegionID}/{namespace}/{externalVNFID}/scale", ScaleHandler).Methods("POST")
+	vnfInstanceHandler.HandleFunc("/{cloudRegionID}/{namespace}/{externalVNFID}/heal", HealHandler).Methods("POST")
+	vnfInstanceHandler.HandleFunc("/{cloudRegionID}/{namespace}/{externalVNFID}/upgrade", UpgradeHandler).Methods("POST")
+	vnfInstanceHandler.HandleFunc("/{cloudRegionID}/{namespace}/{externalVNFID}/revisions", RevisionsHandler).Methods("GET")
+	vnfInstanceHandler.HandleFunc("/{cloudRegionID}/{namespace}/{externalVNFID}/rollback", RollbackHandler).Methods("POST")
+	vnfInstanceHandler.HandleFunc("/{cloudRegionID}/{namespace}/{externalVNFID}/stop", StopHandler).Methods("POST")
+	vnfInstanceHandler.HandleFunc("/{cloudRegionID}/{namespace}/{externalVNFID}/start", StartHandler).Methods("POST")
+	vnfInstanceHandler.Use(authorize(auth.RoleReadOnly, auth.RoleOperator))
+	vnfInstanceHandler.Use(requireScope)
+	vnfInstanceHandler.Use(tenantScope)
+
+	csarHandler := router.PathPrefix("/v1/csars").Subrouter()
+	csarHandler.HandleFunc("/{csarID}/render", RenderHandler).Methods("POST")
+	csarHandler.Use(authorize(auth.RoleOperator, auth.RoleOperator))
+	csarHandler.Use(requireScope)
+
+	driftHandler := router.PathPrefix("/v1/drift").Subrouter()
+	driftHandler.HandleFunc("/", ListDriftHandler).Methods("GET")
+	driftHandler.Use(authorize(auth.RoleReadOnly, auth.RoleOperator))
+	driftHandler.Use(requireScope)
+
+	tenantHandler := router.PathPrefix("/v1/tenants").Subrouter()
+	tenantHandler.HandleFunc("/", CreateTenantHandler).Methods("POST")
+	tenantHandler.HandleFunc("/", ListTenantsHandler).Methods("GET")
+	tenantHandler.HandleFunc("/{tenant}", GetTenantHandler).Methods("GET")
+	tenantHandler.HandleFunc("/{tenant}", UpdateTenantHandler).Methods("PUT")
+	tenantHandler.HandleFunc("/{tenant}", DeleteTenantHandler).Methods("DELETE")
+	tenantHandler.Use(authorize(auth.RoleAdmin, auth.RoleAdmin))
+	tenantHandler.Use(withoutTenant)
+
+	adminHandler := router.PathPrefix("/v1/admin").Subrouter()
+	adminHandler.HandleFunc("/orphans", ListOrphansHandler).Methods("GET")
+	adminHandler.HandleFunc("/orphans", DeleteOrphansHandler).Methods("DELETE")
+	adminHandler.Use(authorize(auth.RoleAdmin, auth.RoleAdmin))
+	adminHandler.Use(withoutTenant)
 
 	// (TODO): Fix update method
 	// vnfInstanceHandler.HandleFunc("/{vnfInstanceId}", UpdateHandler).Methods("PUT")
 
+	router.Use(authenticate)
+
 	return router
 }
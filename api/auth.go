@@ -0,0 +1,94 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"k8-plugin-multicloud/auth"
+)
+
+// authenticator identifies the callers of the API. It is nil when no
+// authentication is configured, which leaves every route open.
+var authenticator auth.Authenticator
+
+// LoadAuthenticator builds the authenticator configured by the environment
+func LoadAuthenticator() error {
+	var err error
+
+	authenticator, err = auth.NewFromEnv()
+	if err != nil {
+		return err
+	}
+
+	if authenticator == nil {
+		log.Println("No authentication configured, the API is open to every caller")
+	}
+
+	return nil
+}
+
+// authenticate stores the identity of the caller in the request context and
+// rejects the requests without valid credentials
+func authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authenticator == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		identity, err := authenticator.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		if identity == nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Missing credentials", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), identity)))
+	})
+}
+
+// authorize returns a middleware checking the role of the caller: read
+// requests need the read role and the other ones the write role
+func authorize(read auth.Role, write auth.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, ok := auth.FromContext(r.Context())
+			if !ok {
+				// Authentication is disabled
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			required := write
+			if r.Method == "GET" || r.Method == "HEAD" {
+				required = read
+			}
+
+			if !identity.Role.Includes(required) {
+				http.Error(w, "Role "+string(required)+" required", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	pkgerrors "github.com/pkg/errors"
+
+	"k8-plugin-multicloud/db"
+	"k8-plugin-multicloud/reconcile"
+)
+
+// DriftHandler compares a VNF instance with the objects of its cluster
+func DriftHandler(w http.ResponseWriter, r *http.Request) {
+	checkDrift(w, r, false)
+}
+
+// RecreateDriftHandler compares a VNF instance with the objects of its
+// cluster and creates the missing objects again
+func RecreateDriftHandler(w http.ResponseWriter, r *http.Request) {
+	checkDrift(w, r, true)
+}
+
+func checkDrift(w http.ResponseWriter, r *http.Request, recreate bool) {
+	vars := mux.Vars(r)
+
+	cloudRegionID := vars["cloudRegionID"] // cloud1
+	namespace := vars["namespace"]         // default
+	externalVNFID := vars["externalVNFID"] // uuid
+
+	// cloud1-default-uuid
+	internalVNFID := cloudRegionID + "-" + namespace + "-" + externalVNFID
+
+	_, found, err := db.ReadVNFRecord(internalVNFID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if found == false {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	drift, err := reconcile.CheckVNF(internalVNFID, recreate)
+	if err == reconcile.ErrOperationInProgress {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Check VNF drift error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(drift)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Parsing output of VNF drift error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+	}
+}
+
+// ListDriftHandler returns the VNF instances found drifted by the last
+// reconciliation
+func ListDriftHandler(w http.ResponseWriter, r *http.Request) {
+	drifts := reconcile.ListDrifts()
+
+	// Tenants only see the drifts of their own namespaces
+	if name := tenantFromRequest(r); name != "" {
+		tenant, found, err := db.ReadTenant(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if found == false {
+			http.Error(w, "Unknown tenant "+name, http.StatusForbidden)
+			return
+		}
+
+		var owned []reconcile.Drift
+		for _, drift := range drifts {
+			if tenant.OwnsNamespace(drift.CloudRegionID, drift.Namespace) {
+				owned = append(owned, drift)
+			}
+		}
+		drifts = owned
+	}
+
+	resp := ListDriftResponse{
+		Drifts: drifts,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err := json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Parsing output of VNF drift list error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+	}
+}
@@ -19,11 +19,14 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	pkgerrors "github.com/pkg/errors"
 	// "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/client-go/kubernetes"
 
 	"k8-plugin-multicloud/csar"
@@ -55,6 +58,72 @@ func validateBody(body interface{}) error {
 			werr := pkgerrors.Wrap(errors.New("Character \"|\" not allowed in CSAR ID"), "CreateVnfRequest bad request")
 			return werr
 		}
+		if b.StoragePolicy != "" && b.StoragePolicy != csar.StoragePolicyDelete && b.StoragePolicy != csar.StoragePolicyRetain {
+			werr := pkgerrors.Wrap(errors.New("Invalid storage_policy in POST request"), "CreateVnfRequest bad request")
+			return werr
+		}
+	case AdoptVnfRequest:
+		if b.CloudRegionID == "" || b.Namespace == "" {
+			werr := pkgerrors.Wrap(errors.New("Invalid/Missing CloudRegionID or Namespace in POST request"), "AdoptVnfRequest bad request")
+			return werr
+		}
+		if (b.LabelSelector == "") == (len(b.Resources) == 0) {
+			werr := pkgerrors.Wrap(errors.New("Either label_selector or resources must be provided in POST request"), "AdoptVnfRequest bad request")
+			return werr
+		}
+		if _, ok := b.Resources["namespace"]; ok {
+			werr := pkgerrors.Wrap(errors.New("Namespaces can't be adopted"), "AdoptVnfRequest bad request")
+			return werr
+		}
+		if strings.Contains(b.CloudRegionID, "|") || strings.Contains(b.Namespace, "|") {
+			werr := pkgerrors.Wrap(errors.New("Character \"|\" not allowed in CloudRegionID or Namespace"), "AdoptVnfRequest bad request")
+			return werr
+		}
+		if b.StoragePolicy != "" && b.StoragePolicy != csar.StoragePolicyDelete && b.StoragePolicy != csar.StoragePolicyRetain {
+			werr := pkgerrors.Wrap(errors.New("Invalid storage_policy in POST request"), "AdoptVnfRequest bad request")
+			return werr
+		}
+	case RenderCsarRequest:
+		if b.CloudRegionID == "" || b.Namespace == "" {
+			werr := pkgerrors.Wrap(errors.New("Invalid/Missing CloudRegionID or Namespace in POST request"), "RenderCsarRequest bad request")
+			return werr
+		}
+	case ScaleVnfRequest:
+		if (b.Aspect == "") == (len(b.Replicas) == 0) {
+			werr := pkgerrors.Wrap(errors.New("Either replicas or aspect must be provided in POST request"), "ScaleVnfRequest bad request")
+			return werr
+		}
+	case UpgradeVnfRequest:
+		if b.CsarID == "" {
+			werr := pkgerrors.Wrap(errors.New("Invalid/Missing CsarID in POST request"), "UpgradeVnfRequest bad request")
+			return werr
+		}
+		if b.Timeout != "" {
+			if _, err := time.ParseDuration(b.Timeout); err != nil {
+				werr := pkgerrors.Wrap(errors.New("Invalid timeout in POST request"), "UpgradeVnfRequest bad request")
+				return werr
+			}
+		}
+	case RollbackVnfRequest:
+		if b.Revision <= 0 {
+			werr := pkgerrors.Wrap(errors.New("Invalid/Missing revision in POST request"), "RollbackVnfRequest bad request")
+			return werr
+		}
+		if b.Timeout != "" {
+			if _, err := time.ParseDuration(b.Timeout); err != nil {
+				werr := pkgerrors.Wrap(errors.New("Invalid timeout in POST request"), "RollbackVnfRequest bad request")
+				return werr
+			}
+		}
+	case db.Tenant:
+		if !tenantNamePattern.MatchString(b.Name) {
+			werr := pkgerrors.Wrap(errors.New("Invalid/Missing tenant name"), "Tenant bad request")
+			return werr
+		}
+		if _, err := regexp.Compile(b.NamespacePattern); err != nil {
+			werr := pkgerrors.Wrap(errors.New("Invalid namespace_pattern"), "Tenant bad request")
+			return werr
+		}
 	case UpdateVnfRequest:
 		if b.CloudRegionID == "" || b.CsarID == "" {
 			werr := pkgerrors.Wrap(errors.New("Invalid/Missing Data in PUT request"), "UpdateVnfRequest bad request")
@@ -64,6 +133,32 @@ func validateBody(body interface{}) error {
 	return nil
 }
 
+// mergeResources returns the union of two VNF resource maps
+func mergeResources(a map[string][]string, b map[string][]string) map[string][]string {
+	result := make(map[string][]string)
+
+	for _, resources := range []map[string][]string{a, b} {
+		for resourceName, names := range resources {
+			for _, name := range names {
+				if !containsString(result[resourceName], name) {
+					result[resourceName] = append(result[resourceName], name)
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
 // CreateHandler is the POST method creates a new VNF instance resource.
 func CreateHandler(w http.ResponseWriter, r *http.Request) {
 	var resource CreateVnfRequest
@@ -85,6 +180,16 @@ func CreateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Checked again when the namespace is claimed for the tenant
+	tenantName := tenantFromRequest(r)
+	if tenantName != "" {
+		_, _, err = authorizeNamespace(tenantName, resource.CloudRegionID, resource.Namespace)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+
 	// (TODO): Read kubeconfig for specific Cloud Region from local file system
 	// if present or download it from AAI
 	// err := DownloadKubeConfigFromAAI(resource.CloudRegionID, os.Getenv("KUBE_CONFIG_DIR")
@@ -94,6 +199,60 @@ func CreateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if resource.DryRun {
+		dryRunHandler(w, resource, &kubeclient)
+		return
+	}
+
+	profile, err := krd.ReadQuotaProfile(tenantName, resource.CloudRegionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// The VNF is rejected before anything is created when it doesn't fit in
+	// the namespace quota
+	requested, err := csar.VNFRequests(resource.CsarID, resource.CloudRegionID, resource.Namespace, resource.Parameters, resource.Overlay)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Read VNF resource requests error")
+		http.Error(w, werr.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	// Claims retained by a deleted VNF are bound instead of the CSAR ones
+	var reusedClaims map[string]string
+	if resource.ReuseClaimsOf != "" {
+		reusedClaims, err = retainedClaims(resource.CloudRegionID, resource.Namespace, resource.ReuseClaimsOf, &kubeclient)
+		if err != nil {
+			werr := pkgerrors.Wrap(err, "Read retained claims error")
+			http.Error(w, werr.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(reusedClaims) == 0 {
+			http.Error(w, "No claims retained by VNF "+resource.ReuseClaimsOf+" found", http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	// The VNF ID is only known once it is created, a placeholder holds the
+	// namespace meanwhile
+	placeholder := db.CreatingVNFPrefix + string(uuid.NewUUID())
+	claim, ok := reserveNamespace(w, tenantName, resource.CloudRegionID, resource.Namespace, placeholder,
+		requested, profile, &kubeclient)
+	if !ok {
+		return
+	}
+	defer releaseRequests(resource.CloudRegionID, resource.Namespace, placeholder)
+
+	// The namespace registration and claim are released whenever the VNF
+	// isn't created
+	created := false
+	defer func() {
+		if !created {
+			abortCreation(resource, placeholder, tenantName, claim, &kubeclient)
+		}
+	}()
+
 	/*
 		uuid,
 		{
@@ -102,7 +261,8 @@ func CreateHandler(w http.ResponseWriter, r *http.Request) {
 		},
 		nil
 	*/
-	externalVNFID, resourceNameMap, err := csar.CreateVNF(resource.CsarID, resource.CloudRegionID, resource.Namespace, &kubeclient)
+	externalVNFID, resourceNameMap, err := csar.CreateVNF(resource.CsarID, resource.CloudRegionID, resource.Namespace, resource.Parameters, resource.Overlay,
+		reusedClaims, &kubeclient)
 	if err != nil {
 		werr := pkgerrors.Wrap(err, "Read Kubernetes Data information error")
 		http.Error(w, werr.Error(), http.StatusInternalServerError)
@@ -117,24 +277,66 @@ func CreateHandler(w http.ResponseWriter, r *http.Request) {
 
 	// krd.AddNetworkAnnotationsToPod(kubeData, resource.Networks)
 
-	// "{"deployment":<>,"service":<>}"
-	out, err := json.Marshal(resourceNameMap)
+	storagePolicy := resource.StoragePolicy
+	if storagePolicy == "" {
+		storagePolicy = csar.StoragePolicyDelete
+	}
+
+	// key: cloud1-default-uuid
+	// value: "{"csar_id":<>,...,"resources":{"deployment":<>,"service":<>}}"
+	record := db.VNFRecord{
+		CsarID:        resource.CsarID,
+		CloudRegionID: resource.CloudRegionID,
+		Namespace:     resource.Namespace,
+		StoragePolicy: storagePolicy,
+		Tenant:        tenantName,
+		Parameters:    resource.Parameters,
+		Overlay:       resource.Overlay,
+		Resources:     resourceNameMap,
+	}
+
+	// The objects of a VNF which couldn't be recorded are left to the
+	// orphaned resource collector
+	err = db.WriteVNFRecord(internalVNFID, record)
 	if err != nil {
 		werr := pkgerrors.Wrap(err, "Create VNF deployment error")
 		http.Error(w, werr.Error(), http.StatusInternalServerError)
 		return
 	}
-	serializedResourceNameMap := string(out)
-	log.Println(serializedResourceNameMap)
 
-	// key: cloud1-default-uuid
-	// value: "{"deployment":<>,"service":<>}"
-	err = db.DBconn.CreateEntry(internalVNFID, serializedResourceNameMap)
+	err = db.ReplaceNamespaceVNF(resource.CloudRegionID, resource.Namespace, placeholder, internalVNFID)
 	if err != nil {
-		werr := pkgerrors.Wrap(err, "Create VNF deployment error")
+		derr := db.DBconn.DeleteEntry(internalVNFID)
+		if derr != nil {
+			log.Println("Delete VNF record error: " + derr.Error())
+		}
+
+		werr := pkgerrors.Wrap(err, "Record VNF namespace error")
 		http.Error(w, werr.Error(), http.StatusInternalServerError)
 		return
 	}
+	created = true
+
+	if len(reusedClaims) > 0 {
+		// Only the claims matching a CSAR claim were reused
+		var claims []string
+		for _, claim := range reusedClaims {
+			for _, name := range resourceNameMap["pvc"] {
+				if name == claim {
+					claims = append(claims, claim)
+				}
+			}
+		}
+
+		// The VNF is created, failing would make the client create another one.
+		// Claims left retained are only reported again by later reuses.
+		err = db.RemoveNamespaceClaims(resource.CloudRegionID, resource.Namespace, claims)
+		if err != nil {
+			log.Println("Forget reused VNF claims error: " + err.Error())
+		}
+	}
+
+	csar.RecordRevision(internalVNFID, externalVNFID, record, csar.OperationCreate)
 
 	resp := CreateVnfResponse{
 		VNFID:         externalVNFID,
@@ -153,13 +355,69 @@ func CreateHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// abortCreation releases the namespace registration of a VNF whose creation
+// failed and the namespace claimed for its tenant, if any. Errors are only
+// logged as the creation error is reported instead.
+func abortCreation(resource CreateVnfRequest, placeholder string, tenantName string, claim bool,
+	kubeclient *kubernetes.Clientset) {
+
+	err := releaseNamespace(resource.CloudRegionID, resource.Namespace, placeholder, nil, kubeclient)
+	if err != nil {
+		log.Println("Release VNF namespace error: " + err.Error())
+	}
+
+	unlock := db.LockNamespace(resource.CloudRegionID, resource.Namespace)
+	defer unlock()
+
+	releaseClaim(tenantName, resource.CloudRegionID, resource.Namespace, claim)
+}
+
+// dryRunHandler validates the objects of a VNF against the cluster without
+// creating them nor storing anything in the database. The values of the
+// Secrets are redacted from the returned manifests.
+func dryRunHandler(w http.ResponseWriter, resource CreateVnfRequest, kubeclient *kubernetes.Clientset) {
+	result, err := csar.DryRunVNF(resource.CsarID, resource.CloudRegionID, resource.Namespace, resource.Parameters, resource.Overlay, kubeclient)
+	if pkgerrors.Cause(err) == krd.ErrDryRunUnsupported {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Read Kubernetes Data information error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	manifests, err := csar.RedactSecrets(result.Manifests)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := DryRunVnfResponse{
+		CloudRegionID: resource.CloudRegionID,
+		Namespace:     resource.Namespace,
+		Valid:         len(result.Errors) == 0,
+		Manifests:     manifests,
+		Errors:        result.Errors,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Parsing output of VNF dry run error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+	}
+}
+
 // ListHandler the existing VNF instances created in a given Kubernetes cluster
 func ListHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 
 	cloudRegionID := vars["cloudRegionID"]
 	namespace := vars["namespace"]
-	prefix := cloudRegionID + "-" + namespace
+	prefix := cloudRegionID + "-" + namespace + "-"
 
 	internalVNFIDs, err := db.DBconn.ReadAll(prefix)
 	if err != nil {
@@ -173,16 +431,27 @@ func ListHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// TODO: There is an edge case where if namespace is passed but is missing some characters
-	// trailing, it will print the result with those excluding characters. This is because of
-	// the way I am trimming the Prefix. This fix is needed.
-
 	var editedList []string
 
 	for _, id := range internalVNFIDs {
-		if len(id) > 0 {
-			editedList = append(editedList, strings.TrimPrefix(id, prefix)[1:])
+		if len(id) == 0 {
+			continue
+		}
+
+		// The prefix of a namespace also matches the VNFs of the namespaces
+		// it starts, e.g. acme and acme-core
+		record, found, err := db.ReadVNFRecord(id)
+		if err != nil {
+			werr := pkgerrors.Wrap(err, "Get VNF list error")
+			http.Error(w, werr.Error(), http.StatusInternalServerError)
+			return
 		}
+
+		externalVNFID := strings.TrimPrefix(id, prefix)
+		if !found || !inNamespace(record, externalVNFID, cloudRegionID, namespace) {
+			continue
+		}
+		editedList = append(editedList, externalVNFID)
 	}
 
 	if len(editedList) == 0 {
@@ -204,6 +473,18 @@ func ListHandler(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// externalVNFIDFormat matches the VNF IDs generated by the plugin
+var externalVNFIDFormat = regexp.MustCompile("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$")
+
+// inNamespace tells whether a VNF record belongs to a namespace. Records
+// stored before they held their namespace are told apart by their VNF ID.
+func inNamespace(record db.VNFRecord, externalVNFID string, cloudRegionID string, namespace string) bool {
+	if record.Namespace == "" {
+		return externalVNFIDFormat.MatchString(externalVNFID)
+	}
+	return record.CloudRegionID == cloudRegionID && record.Namespace == namespace
+}
+
 // DeleteHandler method terminates an individual VNF instance.
 func DeleteHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -225,42 +506,67 @@ func DeleteHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// key: cloud1-default-uuid
-	// value: "{"deployment":<>,"service":<>}"
-	serializedResourceNameMap, found, err := db.DBconn.ReadEntry(internalVNFID)
+	// value: "{"csar_id":<>,...,"resources":{"deployment":<>,"service":<>}}"
+	// The lock keeps the reconciliation from recreating the objects between
+	// their deletion and the deletion of the record
+	unlock := db.LockKey(internalVNFID)
+	defer unlock()
+
+	record, found, err := db.ReadVNFRecord(internalVNFID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	if found == false {
+	// Objects labelled with the VNF ID are deleted too when discovery is
+	// requested, even if the VNF isn't stored in the database
+	if r.URL.Query().Get("discover") == "true" {
+		discovered, err := csar.DiscoverVNF(externalVNFID, namespace, &kubeclient)
+		if err != nil {
+			werr := pkgerrors.Wrap(err, "Delete VNF error")
+			http.Error(w, werr.Error(), http.StatusInternalServerError)
+			return
+		}
+		record.Resources = mergeResources(record.Resources, discovered)
+	}
+
+	if found == false && len(record.Resources) == 0 {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
-	/*
-		{
-			"deployment": ["cloud1-default-uuid-sisedeploy1", "cloud1-default-uuid-sisedeploy2", ... ]
-			"service": ["cloud1-default-uuid-sisesvc1", "cloud1-default-uuid-sisesvc2", ... ]
-		},
-	*/
-	deserializedResourceNameMap := make(map[string][]string)
-	err = json.Unmarshal([]byte(serializedResourceNameMap), &deserializedResourceNameMap)
+	err = csar.DestroyVNF(record.Resources, namespace, record.StoragePolicy, &kubeclient)
 	if err != nil {
 		werr := pkgerrors.Wrap(err, "Delete VNF error")
 		http.Error(w, werr.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	err = csar.DestroyVNF(deserializedResourceNameMap, namespace, &kubeclient)
+	if found {
+		err = db.DBconn.DeleteEntry(internalVNFID)
+		if err != nil {
+			werr := pkgerrors.Wrap(err, "Delete VNF error")
+			http.Error(w, werr.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = db.DeleteRevisions(internalVNFID)
 	if err != nil {
 		werr := pkgerrors.Wrap(err, "Delete VNF error")
 		http.Error(w, werr.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	err = db.DBconn.DeleteEntry(internalVNFID)
+	var retainedClaims []string
+	if record.StoragePolicy == csar.StoragePolicyRetain {
+		retainedClaims = record.Resources["pvc"]
+	}
+
+	// Retained persistent volume claims would be deleted with the namespace
+	err = releaseNamespace(cloudRegionID, namespace, internalVNFID, retainedClaims, &kubeclient)
 	if err != nil {
-		werr := pkgerrors.Wrap(err, "Delete VNF error")
+		werr := pkgerrors.Wrap(err, "Delete VNF namespace error")
 		http.Error(w, werr.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -350,37 +656,50 @@ func GetHandler(w http.ResponseWriter, r *http.Request) {
 	internalVNFID := cloudRegionID + "-" + namespace + "-" + externalVNFID
 
 	// key: cloud1-default-uuid
-	// value: "{"deployment":<>,"service":<>}"
-	serializedResourceNameMap, found, err := db.DBconn.ReadEntry(internalVNFID)
+	// value: "{"csar_id":<>,...,"resources":{"deployment":<>,"service":<>}}"
+	record, found, err := db.ReadVNFRecord(internalVNFID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	// The components are listed from the objects labelled with the VNF ID
+	// instead of the stored names when discovery is requested
+	if r.URL.Query().Get("discover") == "true" {
+		kubeclient, err := GetVNFClient(os.Getenv("KUBE_CONFIG_DIR") + "/" + cloudRegionID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		record.Resources, err = csar.DiscoverVNF(externalVNFID, namespace, &kubeclient)
+		if err != nil {
+			werr := pkgerrors.Wrap(err, "Get VNF error")
+			http.Error(w, werr.Error(), http.StatusInternalServerError)
+			return
+		}
+		found = found || len(record.Resources) > 0
+	}
+
 	if found == false {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
-	/*
-		{
-			"deployment": ["cloud1-default-uuid-sisedeploy1", "cloud1-default-uuid-sisedeploy2", ... ]
-			"service": ["cloud1-default-uuid-sisesvc1", "cloud1-default-uuid-sisesvc2", ... ]
-		},
-	*/
-	deserializedResourceNameMap := make(map[string][]string)
-	err = json.Unmarshal([]byte(serializedResourceNameMap), &deserializedResourceNameMap)
-	if err != nil {
-		werr := pkgerrors.Wrap(err, "Get VNF error")
-		http.Error(w, werr.Error(), http.StatusInternalServerError)
-		return
+	state := record.State
+	if state == "" {
+		state = db.VNFStateRunning
 	}
 
 	resp := GetVnfResponse{
 		VNFID:         externalVNFID,
 		CloudRegionID: cloudRegionID,
 		Namespace:     namespace,
-		VNFComponents: deserializedResourceNameMap,
+		CsarID:        record.CsarID,
+		StoragePolicy: record.StoragePolicy,
+		State:         state,
+		Replicas:      record.Replicas,
+		VNFComponents: record.Resources,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
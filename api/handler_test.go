@@ -16,14 +16,24 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"k8s.io/client-go/kubernetes"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	pkgerrors "github.com/pkg/errors"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"k8-plugin-multicloud/auth"
 	"k8-plugin-multicloud/csar"
 	"k8-plugin-multicloud/db"
+	"k8-plugin-multicloud/krd"
 )
 
 type mockDB struct {
@@ -56,6 +66,144 @@ func (c *mockDB) ReadAll(key string) ([]string, error) {
 	return returnVal, nil
 }
 
+// mockRecordDB stores a VNF record created from a CSAR
+type mockRecordDB struct {
+	mockDB
+}
+
+func (c *mockRecordDB) ReadEntry(key string) (string, bool, error) {
+	str := "{\"csar_id\":\"csar1\",\"cloud_region_id\":\"cloud1\",\"namespace\":\"default\"," +
+		"\"resources\":{\"deployment\":[\"cloud1-default-uuid-sisedeploy\"],\"service\":[\"cloud1-default-uuid-sisesvc\"]}}"
+	return str, true, nil
+}
+
+// mockRevisionDB stores two revisions of a VNF record created from a CSAR
+type mockRevisionDB struct {
+	mockRecordDB
+}
+
+func (c *mockRevisionDB) ReadEntry(key string) (string, bool, error) {
+	switch key {
+	case "revisions/cloud1-default-uuid/000001":
+		return "{\"revision\":1,\"operation\":\"create\",\"csar_id\":\"csar1\",\"csar_digest\":\"digest1\"," +
+			"\"replicas\":{\"cloud1-default-uuid-sisedeploy\":2}," +
+			"\"resources\":{\"deployment\":[\"cloud1-default-uuid-sisedeploy\"]}}", true, nil
+	case "revisions/cloud1-default-uuid/000002":
+		return "{\"revision\":2,\"operation\":\"upgrade\",\"csar_id\":\"csar2\"," +
+			"\"resources\":{\"deployment\":[\"cloud1-default-uuid-sisedeploy\"]}}", true, nil
+	}
+	if strings.HasPrefix(key, "revisions/") {
+		return "", false, nil
+	}
+	return c.mockRecordDB.ReadEntry(key)
+}
+
+func (c *mockRevisionDB) ReadAll(key string) ([]string, error) {
+	returnVal := []string{"revisions/cloud1-default-uuid/000002", "revisions/cloud1-default-uuid/000001"}
+	return returnVal, nil
+}
+
+// mockStoppedDB stores a stopped VNF record
+type mockStoppedDB struct {
+	mockDB
+}
+
+func (c *mockStoppedDB) ReadEntry(key string) (string, bool, error) {
+	str := "{\"csar_id\":\"csar1\",\"cloud_region_id\":\"cloud1\",\"namespace\":\"default\",\"state\":\"Stopped\"," +
+		"\"stopped_replicas\":{\"cloud1-default-uuid-sisedeploy\":3}," +
+		"\"resources\":{\"deployment\":[\"cloud1-default-uuid-sisedeploy\"],\"service\":[\"cloud1-default-uuid-sisesvc\"]}}"
+	return str, true, nil
+}
+
+// mockTenantDB stores the acme tenant owning the default namespace of cloud1
+type mockTenantDB struct {
+	mockRecordDB
+}
+
+func (c *mockTenantDB) ReadEntry(key string) (string, bool, error) {
+	switch key {
+	case "tenants/acme":
+		str := "{\"name\":\"acme\",\"namespace_pattern\":\"acme-.*\"," +
+			"\"namespaces\":{\"cloud1\":[\"default\"]}}"
+		return str, true, nil
+	}
+	if strings.HasPrefix(key, "tenants/") {
+		return "", false, nil
+	}
+	return c.mockRecordDB.ReadEntry(key)
+}
+
+func (c *mockTenantDB) ReadAll(key string) ([]string, error) {
+	if key == "tenants/" {
+		return []string{"tenants/acme"}, nil
+	}
+	return c.mockRecordDB.ReadAll(key)
+}
+
+// mockMapDB stores its entries in memory
+type mockMapDB struct {
+	mockDB
+	entries map[string]string
+}
+
+func (c *mockMapDB) CreateEntry(key string, value string) error {
+	c.entries[key] = value
+	return nil
+}
+
+func (c *mockMapDB) ReadEntry(key string) (string, bool, error) {
+	value, ok := c.entries[key]
+	return value, ok, nil
+}
+
+func (c *mockMapDB) DeleteEntry(key string) error {
+	delete(c.entries, key)
+	return nil
+}
+
+func (c *mockMapDB) ReadAll(key string) ([]string, error) {
+	var keys []string
+	for entry := range c.entries {
+		if strings.HasPrefix(entry, key) {
+			keys = append(keys, entry)
+		}
+	}
+	return keys, nil
+}
+
+// mockRecordFailureDB stores its entries in memory except the VNF records
+type mockRecordFailureDB struct {
+	mockMapDB
+}
+
+func (c *mockRecordFailureDB) CreateEntry(key string, value string) error {
+	if !strings.Contains(key, "/") {
+		return errors.New("Internal error")
+	}
+	return c.mockMapDB.CreateEntry(key, value)
+}
+
+// mockAuthenticator authenticates the bearer tokens named after a role, the
+// non admin ones belonging to the acme tenant
+type mockAuthenticator struct{}
+
+func (a mockAuthenticator) Authenticate(r *http.Request) (*auth.Identity, error) {
+	switch token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "); token {
+	case "read-only", "operator":
+		return &auth.Identity{Name: token, Role: auth.Role(token), Tenant: "acme"}, nil
+	case "admin":
+		return &auth.Identity{Name: token, Role: auth.RoleAdmin}, nil
+	case "unscoped":
+		return &auth.Identity{Name: token, Role: auth.RoleOperator}, nil
+	case "":
+		return nil, nil
+	}
+	return nil, errors.New("Unknown token")
+}
+
+// discoverVNF keeps the DiscoverVNF function replaced by the tests
+var discoverVNF = csar.DiscoverVNF
+
 func executeRequest(req *http.Request) *httptest.ResponseRecorder {
 	router := NewRouter("")
 	recorder := httptest.NewRecorder()
@@ -110,7 +258,19 @@ func TestVNFInstanceCreation(t *testing.T) {
 			return kubernetes.Clientset{}, nil
 		}
 
-		csar.CreateVNF = func(id string, r string, n string, kubeclient *kubernetes.Clientset) (string, map[string][]string, error) {
+		csar.VNFRequests = func(id string, r string, n string, p map[string]string, o string) (coreV1.ResourceList, error) {
+			return coreV1.ResourceList{}, nil
+		}
+
+		csar.CheckQuota = func(l coreV1.ResourceList, n string, p *krd.QuotaProfile, kubeclient *kubernetes.Clientset) error {
+			return nil
+		}
+
+		csar.EnsureNamespace = func(n string, p *krd.QuotaProfile, kubeclient *kubernetes.Clientset) (bool, error) {
+			return true, nil
+		}
+
+		csar.CreateVNF = func(id string, r string, n string, p map[string]string, o string, c map[string]string, kubeclient *kubernetes.Clientset) (string, map[string][]string, error) {
 			return "externaluuid", data, nil
 		}
 
@@ -124,6 +284,267 @@ func TestVNFInstanceCreation(t *testing.T) {
 			t.Fatalf("TestVNFInstanceCreation returned:\n result=%v\n expected=%v", err, expected.VNFComponents)
 		}
 	})
+	t.Run("Succesful register a VNF in its namespace before creating it", func(t *testing.T) {
+		payload := []byte(`{
+			"cloud_region_id": "region1",
+			"namespace": "test",
+			"csar_id": "UUID-1"
+		}`)
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/", bytes.NewBuffer(payload))
+
+		csar.CreateVNF = func(id string, r string, n string, p map[string]string, o string, c map[string]string, kubeclient *kubernetes.Clientset) (string, map[string][]string, error) {
+			record, _, _ := db.ReadNamespaceRecord("region1", "test")
+			if len(record.VNFs) != 1 || !strings.HasPrefix(record.VNFs[0], "creating-") {
+				t.Fatalf("TestVNFInstanceCreation didn't register the VNF in its namespace (%v)", record.VNFs)
+			}
+			return "externaluuid", map[string][]string{}, nil
+		}
+
+		db.DBconn = &mockMapDB{entries: make(map[string]string)}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusCreated, response.Code)
+
+		record, _, _ := db.ReadNamespaceRecord("region1", "test")
+		expected := []string{"region1-test-externaluuid"}
+		if !reflect.DeepEqual(expected, record.VNFs) {
+			t.Fatalf("TestVNFInstanceCreation returned:\n result=%v\n expected=%v", record.VNFs, expected)
+		}
+	})
+	t.Run("Release the namespace of a VNF whose record couldn't be written", func(t *testing.T) {
+		payload := []byte(`{
+			"cloud_region_id": "region1",
+			"namespace": "test",
+			"csar_id": "UUID-1"
+		}`)
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/", bytes.NewBuffer(payload))
+
+		GetVNFClient = func(configPath string) (kubernetes.Clientset, error) {
+			return kubernetes.Clientset{}, nil
+		}
+
+		csar.VNFRequests = func(id string, r string, n string, p map[string]string, o string) (coreV1.ResourceList, error) {
+			return coreV1.ResourceList{}, nil
+		}
+
+		csar.CheckQuota = func(l coreV1.ResourceList, n string, p *krd.QuotaProfile, kubeclient *kubernetes.Clientset) error {
+			return nil
+		}
+
+		csar.EnsureNamespace = func(n string, p *krd.QuotaProfile, kubeclient *kubernetes.Clientset) (bool, error) {
+			return true, nil
+		}
+
+		csar.CreateVNF = func(id string, r string, n string, p map[string]string, o string, c map[string]string, kubeclient *kubernetes.Clientset) (string, map[string][]string, error) {
+			return "externaluuid", map[string][]string{}, nil
+		}
+
+		db.DBconn = &mockRecordFailureDB{mockMapDB{entries: make(map[string]string)}}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusInternalServerError, response.Code)
+
+		record, _, _ := db.ReadNamespaceRecord("region1", "test")
+		if len(record.VNFs) != 0 {
+			t.Fatalf("TestVNFInstanceCreation kept the VNF registered in its namespace (%v)", record.VNFs)
+		}
+	})
+	t.Run("Succesful create a VNF reusing the claims retained by a deleted VNF", func(t *testing.T) {
+		payload := []byte(`{
+			"cloud_region_id": "region1",
+			"namespace": "test",
+			"csar_id": "UUID-1",
+			"reuse_claims_of": "11111111-2222-3333-4444-555555555555"
+		}`)
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/", bytes.NewBuffer(payload))
+
+		claim := "region1-test-11111111-2222-3333-4444-555555555555-sisedata"
+		csar.ListResources = func(n string, l string, kubeclient *kubernetes.Clientset) (map[string][]string, error) {
+			return map[string][]string{"pvc": []string{claim}}, nil
+		}
+
+		var reused map[string]string
+		csar.CreateVNF = func(id string, r string, n string, p map[string]string, o string, c map[string]string, kubeclient *kubernetes.Clientset) (string, map[string][]string, error) {
+			reused = c
+			return "externaluuid", map[string][]string{"pvc": []string{claim}}, nil
+		}
+
+		db.DBconn = &mockMapDB{entries: map[string]string{
+			"namespaces/region1/test": "{\"cloud_region_id\":\"region1\",\"namespace\":\"test\"," +
+				"\"retained_claims\":[\"" + claim + "\"]}",
+		}}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusCreated, response.Code)
+
+		expected := map[string]string{"sisedata": claim}
+		if !reflect.DeepEqual(expected, reused) {
+			t.Fatalf("TestVNFInstanceCreation returned:\n result=%v\n expected=%v", reused, expected)
+		}
+		record, _, _ := db.ReadNamespaceRecord("region1", "test")
+		if len(record.RetainedClaims) != 0 {
+			t.Fatalf("TestVNFInstanceCreation kept the reused claims retained (%v)", record.RetainedClaims)
+		}
+	})
+	t.Run("Succesful dry run a VNF", func(t *testing.T) {
+		payload := []byte(`{
+			"cloud_region_id": "region1",
+			"namespace": "test",
+			"csar_id": "UUID-1",
+			"dry_run": true
+		}`)
+
+		expected := DryRunVnfResponse{
+			CloudRegionID: "region1",
+			Namespace:     "test",
+			Valid:         false,
+			Manifests:     "kind: Service\n",
+			Errors:        []string{"Service is invalid"},
+		}
+
+		var result DryRunVnfResponse
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/", bytes.NewBuffer(payload))
+
+		GetVNFClient = func(configPath string) (kubernetes.Clientset, error) {
+			return kubernetes.Clientset{}, nil
+		}
+
+		csar.CreateVNF = func(id string, r string, n string, p map[string]string, o string, c map[string]string, kubeclient *kubernetes.Clientset) (string, map[string][]string, error) {
+			t.Fatalf("CreateVNF called during a dry run")
+			return "", nil, nil
+		}
+
+		csar.DryRunVNF = func(id string, r string, n string, p map[string]string, o string, kubeclient *kubernetes.Clientset) (csar.DryRunResult, error) {
+			return csar.DryRunResult{
+				Manifests: "kind: Service\n",
+				Errors:    []string{"Service is invalid"},
+			}, nil
+		}
+
+		db.DBconn = &mockDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusOK, response.Code)
+
+		err := json.NewDecoder(response.Body).Decode(&result)
+		if err != nil {
+			t.Fatalf("TestVNFInstanceCreation returned:\n result=%v\n expected=%v", err, expected)
+		}
+
+		if !reflect.DeepEqual(expected, result) {
+			t.Fatalf("TestVNFInstanceCreation returned:\n result=%v\n expected=%v", result, expected)
+		}
+	})
+	t.Run("Succesful dry run a VNF with a Secret", func(t *testing.T) {
+		payload := []byte(`{
+			"cloud_region_id": "region1",
+			"namespace": "test",
+			"csar_id": "UUID-1",
+			"dry_run": true
+		}`)
+
+		csar.DryRunVNF = func(id string, r string, n string, p map[string]string, o string, kubeclient *kubernetes.Clientset) (csar.DryRunResult, error) {
+			return csar.DryRunResult{
+				Manifests: "kind: Secret\nmetadata:\n  name: region1-test-uuid-sise-secret\n" +
+					"data:\n  password: c2VjcmV0\nstringData:\n  user: admin\n",
+			}, nil
+		}
+
+		db.DBconn = &mockDB{}
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/", bytes.NewBuffer(payload))
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusOK, response.Code)
+
+		var result DryRunVnfResponse
+		err := json.NewDecoder(response.Body).Decode(&result)
+		if err != nil {
+			t.Fatalf("TestVNFInstanceCreation returned an error (%s)", err)
+		}
+
+		expected := "kind: Secret\nmetadata:\n  name: region1-test-uuid-sise-secret\n" +
+			"data:\n  password: REDACTED\nstringData:\n  user: REDACTED\n"
+		if result.Manifests != expected {
+			t.Fatalf("TestVNFInstanceCreation returned:\n result=%v\n expected=%v", result.Manifests, expected)
+		}
+	})
+	t.Run("Unsupported dry run failure", func(t *testing.T) {
+		payload := []byte(`{
+			"cloud_region_id": "region1",
+			"namespace": "test",
+			"csar_id": "UUID-1",
+			"dry_run": true
+		}`)
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/", bytes.NewBuffer(payload))
+
+		csar.DryRunVNF = func(id string, r string, n string, p map[string]string, o string, kubeclient *kubernetes.Clientset) (csar.DryRunResult, error) {
+			return csar.DryRunResult{}, pkgerrors.Wrap(krd.ErrDryRunUnsupported, "Kubernetes v1.10.3")
+		}
+
+		db.DBconn = &mockDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusNotImplemented, response.Code)
+	})
+	t.Run("Quota exceeded failure", func(t *testing.T) {
+		payload := []byte(`{
+			"cloud_region_id": "region1",
+			"namespace": "test",
+			"csar_id": "UUID-1"
+		}`)
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/", bytes.NewBuffer(payload))
+
+		csar.CheckQuota = func(l coreV1.ResourceList, n string, p *krd.QuotaProfile, kubeclient *kubernetes.Clientset) error {
+			return errors.New("Quota of namespace test exceeded: requests.cpu requested 2, remaining 1")
+		}
+
+		csar.CreateVNF = func(id string, r string, n string, p map[string]string, o string, c map[string]string, kubeclient *kubernetes.Clientset) (string, map[string][]string, error) {
+			t.Fatalf("CreateVNF called when the quota is exceeded")
+			return "", nil, nil
+		}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusForbidden, response.Code)
+	})
+	t.Run("Quota checked with the VNFs being created", func(t *testing.T) {
+		payload := []byte(`{
+			"cloud_region_id": "region1",
+			"namespace": "test",
+			"csar_id": "UUID-1"
+		}`)
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/", bytes.NewBuffer(payload))
+
+		pendingRequests.requests["region1/test"] = map[string]coreV1.ResourceList{
+			"creating-other": {coreV1.ResourceRequestsCPU: resource.MustParse("1")},
+		}
+		defer releaseRequests("region1", "test", "creating-other")
+
+		csar.VNFRequests = func(id string, r string, n string, p map[string]string, o string) (coreV1.ResourceList, error) {
+			return coreV1.ResourceList{coreV1.ResourceRequestsCPU: resource.MustParse("500m")}, nil
+		}
+
+		csar.CheckQuota = func(l coreV1.ResourceList, n string, p *krd.QuotaProfile, kubeclient *kubernetes.Clientset) error {
+			cpu := l[coreV1.ResourceRequestsCPU]
+			if cpu.Cmp(resource.MustParse("1500m")) != 0 {
+				t.Fatalf("TestVNFInstanceCreation checked the quota with %s CPU, expected 1500m", cpu.String())
+			}
+			return errors.New("Quota of namespace test exceeded: requests.cpu requested 1500m, remaining 1")
+		}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusForbidden, response.Code)
+
+		csar.VNFRequests = func(id string, r string, n string, p map[string]string, o string) (coreV1.ResourceList, error) {
+			return coreV1.ResourceList{}, nil
+		}
+	})
 	t.Run("Missing body failure", func(t *testing.T) {
 		req, _ := http.NewRequest("POST", "/v1/vnf_instances/", nil)
 		response := executeRequest(req)
@@ -152,6 +573,17 @@ func TestVNFInstanceCreation(t *testing.T) {
 		response := executeRequest(req)
 		checkResponseCode(t, http.StatusUnprocessableEntity, response.Code)
 	})
+	t.Run("Invalid storage policy failure", func(t *testing.T) {
+		payload := []byte(`{
+			"cloud_region_id": "region1",
+			"namespace": "test",
+			"csar_id": "UUID-1",
+			"storage_policy": "archive"
+		}`)
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/", bytes.NewBuffer(payload))
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusUnprocessableEntity, response.Code)
+	})
 }
 
 func TestVNFInstancesRetrieval(t *testing.T) {
@@ -163,7 +595,31 @@ func TestVNFInstancesRetrieval(t *testing.T) {
 
 		req, _ := http.NewRequest("GET", "/v1/vnf_instances/cloud1/default", nil)
 
-		db.DBconn = &mockDB{}
+		db.DBconn = &mockRecordDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusOK, response.Code)
+
+		err := json.NewDecoder(response.Body).Decode(&result)
+		if err != nil {
+			t.Fatalf("TestVNFInstancesRetrieval returned:\n result=%v\n expected=list", err)
+		}
+		if !reflect.DeepEqual(*expected, result) {
+			t.Fatalf("TestVNFInstancesRetrieval returned:\n result=%v\n expected=%v", result, *expected)
+		}
+	})
+	t.Run("Succesful get a list of VNF leaving out the namespaces sharing its prefix", func(t *testing.T) {
+		expected := &ListVnfsResponse{
+			VNFs: []string{"uuid1"},
+		}
+		var result ListVnfsResponse
+
+		req, _ := http.NewRequest("GET", "/v1/vnf_instances/cloud1/acme", nil)
+
+		db.DBconn = &mockMapDB{entries: map[string]string{
+			"cloud1-acme-uuid1":      "{\"cloud_region_id\":\"cloud1\",\"namespace\":\"acme\",\"resources\":{}}",
+			"cloud1-acme-core-uuid2": "{\"cloud_region_id\":\"cloud1\",\"namespace\":\"acme-core\",\"resources\":{}}",
+		}}
 
 		response := executeRequest(req)
 		checkResponseCode(t, http.StatusOK, response.Code)
@@ -192,7 +648,7 @@ func TestVNFInstanceDeletion(t *testing.T) {
 			return kubernetes.Clientset{}, nil
 		}
 
-		csar.DestroyVNF = func(d map[string][]string, n string, kubeclient *kubernetes.Clientset) error {
+		csar.DestroyVNF = func(d map[string][]string, n string, p string, kubeclient *kubernetes.Clientset) error {
 			return nil
 		}
 
@@ -205,16 +661,165 @@ func TestVNFInstanceDeletion(t *testing.T) {
 			t.Fatalf("TestVNFInstanceDeletion returned:\n result=%v\n expected=%v", result, "")
 		}
 	})
-	// t.Run("Malformed delete request", func(t *testing.T) {
-	// 	req, _ := http.NewRequest("DELETE", "/v1/vnf_instances/foo", nil)
-	// 	response := executeRqequest(req)
-	// 	checkResponseCode(t, http.StatusBadRequest, response.Code)
-	// })
-}
+	t.Run("Succesful delete a VNF and its namespace", func(t *testing.T) {
+		os.Setenv("NAMESPACE_CLEANUP_REGIONS", "cloudregion1")
+		defer os.Unsetenv("NAMESPACE_CLEANUP_REGIONS")
 
-// TODO: Update this test when the UpdateVNF endpoint is fixed.
-/*
-func TestVNFInstanceUpdate(t *testing.T) {
+		req, _ := http.NewRequest("DELETE", "/v1/vnf_instances/cloudregion1/testnamespace/1", nil)
+
+		csar.ForeignObjects = func(n string, kubeclient *kubernetes.Clientset) (map[string][]string, error) {
+			return map[string][]string{}, nil
+		}
+
+		deleted := ""
+		csar.DeleteNamespace = func(n string, kubeclient *kubernetes.Clientset) error {
+			deleted = n
+			return nil
+		}
+
+		db.DBconn = &mockDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusAccepted, response.Code)
+
+		if deleted != "testnamespace" {
+			t.Fatalf("TestVNFInstanceDeletion returned:\n result=%v\n expected=%v", deleted, "testnamespace")
+		}
+	})
+	t.Run("Succesful delete a VNF keeping the namespace of its retained claims", func(t *testing.T) {
+		os.Setenv("NAMESPACE_CLEANUP_REGIONS", "cloud1")
+		defer os.Unsetenv("NAMESPACE_CLEANUP_REGIONS")
+
+		req, _ := http.NewRequest("DELETE", "/v1/vnf_instances/cloud1/default/uuid", nil)
+
+		csar.DestroyVNF = func(d map[string][]string, n string, p string, kubeclient *kubernetes.Clientset) error {
+			return nil
+		}
+
+		csar.ListResources = func(n string, l string, kubeclient *kubernetes.Clientset) (map[string][]string, error) {
+			return map[string][]string{"pvc": []string{"cloud1-default-uuid-sisedata"}}, nil
+		}
+
+		deleted := ""
+		csar.DeleteNamespace = func(n string, kubeclient *kubernetes.Clientset) error {
+			deleted = n
+			return nil
+		}
+
+		db.DBconn = &mockMapDB{entries: map[string]string{
+			"cloud1-default-uuid": "{\"csar_id\":\"csar1\",\"cloud_region_id\":\"cloud1\",\"namespace\":\"default\"," +
+				"\"storage_policy\":\"retain\",\"resources\":{\"pvc\":[\"cloud1-default-uuid-sisedata\"]}}",
+			"namespaces/cloud1/default": "{\"cloud_region_id\":\"cloud1\",\"namespace\":\"default\",\"vnfs\":[\"cloud1-default-uuid\"]}",
+		}}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusAccepted, response.Code)
+
+		if deleted != "" {
+			t.Fatalf("TestVNFInstanceDeletion deleted the %s namespace holding retained claims", deleted)
+		}
+		record, _, _ := db.ReadNamespaceRecord("cloud1", "default")
+		expected := []string{"cloud1-default-uuid-sisedata"}
+		if !reflect.DeepEqual(expected, record.RetainedClaims) {
+			t.Fatalf("TestVNFInstanceDeletion returned:\n result=%v\n expected=%v", record.RetainedClaims, expected)
+		}
+	})
+	t.Run("Succesful delete a VNF keeping a namespace holding foreign objects", func(t *testing.T) {
+		os.Setenv("NAMESPACE_CLEANUP_REGIONS", "cloudregion1")
+		defer os.Unsetenv("NAMESPACE_CLEANUP_REGIONS")
+
+		req, _ := http.NewRequest("DELETE", "/v1/vnf_instances/cloudregion1/testnamespace/1", nil)
+
+		csar.ForeignObjects = func(n string, kubeclient *kubernetes.Clientset) (map[string][]string, error) {
+			return map[string][]string{"configmap": []string{"settings"}}, nil
+		}
+
+		deleted := ""
+		csar.DeleteNamespace = func(n string, kubeclient *kubernetes.Clientset) error {
+			deleted = n
+			return nil
+		}
+
+		db.DBconn = &mockDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusAccepted, response.Code)
+
+		if deleted != "" {
+			t.Fatalf("TestVNFInstanceDeletion deleted the %s namespace holding foreign objects", deleted)
+		}
+	})
+	t.Run("Succesful delete a VNF discovering its components", func(t *testing.T) {
+		req, _ := http.NewRequest("DELETE", "/v1/vnf_instances/cloudregion1/testnamespace/1?discover=true", nil)
+
+		GetVNFClient = func(configPath string) (kubernetes.Clientset, error) {
+			return kubernetes.Clientset{}, nil
+		}
+
+		csar.DiscoverVNF = func(id string, n string, kubeclient *kubernetes.Clientset) (map[string][]string, error) {
+			return map[string][]string{
+				"deployment": []string{"cloud1-default-uuid-sisedeploy", "cloud1-default-uuid-siseextra"},
+			}, nil
+		}
+
+		var deleted map[string][]string
+		csar.DestroyVNF = func(d map[string][]string, n string, p string, kubeclient *kubernetes.Clientset) error {
+			deleted = d
+			return nil
+		}
+
+		db.DBconn = &mockDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusAccepted, response.Code)
+
+		expected := map[string][]string{
+			"deployment": []string{"cloud1-default-uuid-sisedeploy", "cloud1-default-uuid-siseextra"},
+			"service":    []string{"cloud1-default-uuid-sisesvc"},
+		}
+		if !reflect.DeepEqual(expected, deleted) {
+			t.Fatalf("TestVNFInstanceDeletion returned:\n result=%v\n expected=%v", deleted, expected)
+		}
+	})
+	t.Run("Succesful delete a VNF without record keeping its retained claims", func(t *testing.T) {
+		req, _ := http.NewRequest("DELETE", "/v1/vnf_instances/cloud1/default/uuid?discover=true", nil)
+
+		csar.DiscoverVNF = discoverVNF
+
+		csar.ListResources = func(n string, l string, kubeclient *kubernetes.Clientset) (map[string][]string, error) {
+			resources := map[string][]string{"deployment": []string{"cloud1-default-uuid-sisedeploy"}}
+			if !strings.Contains(l, krd.RetainedLabel+"!=true") {
+				resources["pvc"] = []string{"cloud1-default-uuid-sisedata"}
+			}
+			return resources, nil
+		}
+
+		var deleted map[string][]string
+		csar.DestroyVNF = func(d map[string][]string, n string, p string, kubeclient *kubernetes.Clientset) error {
+			deleted = d
+			return nil
+		}
+
+		db.DBconn = &mockMapDB{entries: map[string]string{}}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusAccepted, response.Code)
+
+		expected := map[string][]string{"deployment": []string{"cloud1-default-uuid-sisedeploy"}}
+		if !reflect.DeepEqual(expected, deleted) {
+			t.Fatalf("TestVNFInstanceDeletion returned:\n result=%v\n expected=%v", deleted, expected)
+		}
+	})
+	// t.Run("Malformed delete request", func(t *testing.T) {
+	// 	req, _ := http.NewRequest("DELETE", "/v1/vnf_instances/foo", nil)
+	// 	response := executeRqequest(req)
+	// 	checkResponseCode(t, http.StatusBadRequest, response.Code)
+	// })
+}
+
+// TODO: Update this test when the UpdateVNF endpoint is fixed.
+/*
+func TestVNFInstanceUpdate(t *testing.T) {
 	t.Run("Succesful update a VNF", func(t *testing.T) {
 		payload := []byte(`{
 			"cloud_region_id": "region1",
@@ -282,6 +887,7 @@ func TestVNFInstanceRetrieval(t *testing.T) {
 			VNFID:         "1",
 			CloudRegionID: "cloud1",
 			Namespace:     "default",
+			State:         "Running",
 			VNFComponents: data,
 		}
 
@@ -313,4 +919,919 @@ func TestVNFInstanceRetrieval(t *testing.T) {
 
 		checkResponseCode(t, http.StatusOK, response.Code)
 	})
+	t.Run("Succesful get a VNF discovering its components", func(t *testing.T) {
+		data := map[string][]string{
+			"deployment": []string{"cloud1-default-1-sisedeploy"},
+		}
+
+		req, _ := http.NewRequest("GET", "/v1/vnf_instances/cloud1/default/1?discover=true", nil)
+
+		GetVNFClient = func(configPath string) (kubernetes.Clientset, error) {
+			return kubernetes.Clientset{}, nil
+		}
+
+		csar.DiscoverVNF = func(id string, n string, kubeclient *kubernetes.Clientset) (map[string][]string, error) {
+			return data, nil
+		}
+
+		db.DBconn = &mockDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusOK, response.Code)
+
+		var result GetVnfResponse
+
+		err := json.NewDecoder(response.Body).Decode(&result)
+		if err != nil {
+			t.Fatalf("TestVNFInstanceRetrieval returned:\n result=%v\n expected=%v", err, data)
+		}
+
+		if !reflect.DeepEqual(data, result.VNFComponents) {
+			t.Fatalf("TestVNFInstanceRetrieval returned:\n result=%v\n expected=%v", result.VNFComponents, data)
+		}
+	})
+}
+
+func TestVNFDriftRetrieval(t *testing.T) {
+	t.Run("Succesful get the list of drifted VNFs", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/drift/", nil)
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusOK, response.Code)
+
+		var result ListDriftResponse
+
+		err := json.NewDecoder(response.Body).Decode(&result)
+		if err != nil {
+			t.Fatalf("TestVNFDriftRetrieval returned:\n result=%v\n expected=list", err)
+		}
+	})
+}
+
+func TestVNFInstanceAdoption(t *testing.T) {
+	t.Run("Succesful adopt a VNF by label selector", func(t *testing.T) {
+		payload := []byte(`{
+			"cloud_region_id": "region1",
+			"namespace": "test",
+			"label_selector": "app=sise",
+			"relabel": true
+		}`)
+
+		data := map[string][]string{
+			"deployment": []string{"sise-deploy"},
+			"service":    []string{"sise-svc"},
+		}
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/adopt", bytes.NewBuffer(payload))
+
+		GetVNFClient = func(configPath string) (kubernetes.Clientset, error) {
+			return kubernetes.Clientset{}, nil
+		}
+
+		csar.ListResources = func(n string, s string, kubeclient *kubernetes.Clientset) (map[string][]string, error) {
+			if s == krd.ManagedBySelector {
+				return map[string][]string{}, nil
+			}
+			return data, nil
+		}
+
+		relabelled := false
+		csar.LabelVNF = func(d map[string][]string, c string, r string, n string, id string, kubeclient *kubernetes.Clientset) error {
+			relabelled = true
+			return nil
+		}
+
+		db.DBconn = &mockDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusCreated, response.Code)
+
+		var result CreateVnfResponse
+
+		err := json.NewDecoder(response.Body).Decode(&result)
+		if err != nil {
+			t.Fatalf("TestVNFInstanceAdoption returned:\n result=%v\n expected=%v", err, data)
+		}
+
+		if !reflect.DeepEqual(data, result.VNFComponents) || !relabelled {
+			t.Fatalf("TestVNFInstanceAdoption returned:\n result=%v\n expected=%v", result.VNFComponents, data)
+		}
+	})
+	t.Run("Objects owned by another VNF failure", func(t *testing.T) {
+		payload := []byte(`{
+			"cloud_region_id": "region1",
+			"namespace": "test",
+			"label_selector": "app=sise"
+		}`)
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/adopt", bytes.NewBuffer(payload))
+
+		csar.ListResources = func(n string, s string, kubeclient *kubernetes.Clientset) (map[string][]string, error) {
+			return map[string][]string{"deployment": []string{"region1-test-uuid-sisedeploy"}}, nil
+		}
+
+		csar.LabelVNF = func(d map[string][]string, c string, r string, n string, id string, kubeclient *kubernetes.Clientset) error {
+			t.Fatalf("TestVNFInstanceAdoption relabelled objects owned by another VNF")
+			return nil
+		}
+
+		db.DBconn = &mockDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusConflict, response.Code)
+	})
+	t.Run("Missing objects failure", func(t *testing.T) {
+		payload := []byte(`{
+			"cloud_region_id": "region1",
+			"namespace": "test",
+			"resources": {"deployment": ["sise-deploy"]}
+		}`)
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/adopt", bytes.NewBuffer(payload))
+
+		csar.FindMissingResources = func(d map[string][]string, n string, kubeclient *kubernetes.Clientset) (map[string][]string, error) {
+			return d, nil
+		}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusNotFound, response.Code)
+	})
+	t.Run("Missing selector failure", func(t *testing.T) {
+		payload := []byte(`{
+			"cloud_region_id": "region1",
+			"namespace": "test"
+		}`)
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/adopt", bytes.NewBuffer(payload))
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusUnprocessableEntity, response.Code)
+	})
+}
+
+func TestVNFInstanceScale(t *testing.T) {
+	t.Run("Succesful scale a VNF", func(t *testing.T) {
+		payload := []byte(`{
+			"replicas": {"sisedeploy": 3}
+		}`)
+
+		expected := map[string]int32{
+			"cloud1-default-uuid-sisedeploy": 3,
+		}
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/cloud1/default/uuid/scale", bytes.NewBuffer(payload))
+
+		GetVNFClient = func(configPath string) (kubernetes.Clientset, error) {
+			return kubernetes.Clientset{}, nil
+		}
+
+		csar.ReadReplicas = func(d map[string][]string, n string, kubeclient *kubernetes.Clientset) (map[string]int32, error) {
+			return map[string]int32{"cloud1-default-uuid-sisedeploy": 1}, nil
+		}
+
+		var scaled map[string]int32
+		csar.ScaleVNF = func(d map[string][]string, n string, r map[string]int32, kubeclient *kubernetes.Clientset) error {
+			scaled = r
+			return nil
+		}
+
+		db.DBconn = &mockDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusOK, response.Code)
+
+		var result ScaleVnfResponse
+
+		err := json.NewDecoder(response.Body).Decode(&result)
+		if err != nil {
+			t.Fatalf("TestVNFInstanceScale returned:\n result=%v\n expected=%v", err, expected)
+		}
+
+		if !reflect.DeepEqual(expected, result.Replicas) || !reflect.DeepEqual(expected, scaled) {
+			t.Fatalf("TestVNFInstanceScale returned:\n result=%v\n expected=%v", result.Replicas, expected)
+		}
+	})
+	t.Run("Restore the workloads scaled before a scale failure", func(t *testing.T) {
+		payload := []byte(`{
+			"replicas": {"sisedeploy": 3}
+		}`)
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/cloud1/default/uuid/scale", bytes.NewBuffer(payload))
+
+		var calls []map[string]int32
+		csar.ScaleVNF = func(d map[string][]string, n string, r map[string]int32, kubeclient *kubernetes.Clientset) error {
+			calls = append(calls, r)
+			if len(calls) == 1 {
+				return errors.New("Internal error")
+			}
+			return nil
+		}
+
+		db.DBconn = &mockDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusUnprocessableEntity, response.Code)
+
+		expected := map[string]int32{"cloud1-default-uuid-sisedeploy": 1}
+		if len(calls) != 2 || !reflect.DeepEqual(expected, calls[1]) {
+			t.Fatalf("TestVNFInstanceScale returned:\n result=%v\n expected=%v", calls, expected)
+		}
+	})
+	t.Run("Missing replicas and aspect failure", func(t *testing.T) {
+		payload := []byte(`{}`)
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/cloud1/default/uuid/scale", bytes.NewBuffer(payload))
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusUnprocessableEntity, response.Code)
+	})
+}
+
+func TestVNFInstanceHeal(t *testing.T) {
+	t.Run("Succesful heal a VNF", func(t *testing.T) {
+		missing := map[string][]string{
+			"service": []string{"cloud1-default-uuid-sisesvc"},
+		}
+		restarted := []string{"cloud1-default-uuid-sisedeploy-5d8f9-x2x7z"}
+		unhealthy := []string{"cloud1-default-uuid-sisepod"}
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/cloud1/default/uuid/heal", nil)
+
+		GetVNFClient = func(configPath string) (kubernetes.Clientset, error) {
+			return kubernetes.Clientset{}, nil
+		}
+
+		csar.FindMissingResources = func(d map[string][]string, n string, kubeclient *kubernetes.Clientset) (map[string][]string, error) {
+			return missing, nil
+		}
+
+		csar.RestartUnhealthyPods = func(id string, n string, kubeclient *kubernetes.Clientset) ([]string, []string, error) {
+			return restarted, unhealthy, nil
+		}
+
+		db.DBconn = &mockDB{}
+
+		response := executeRequest(req)
+
+		// The mock record has no CSAR to recreate the missing service from
+		checkResponseCode(t, http.StatusUnprocessableEntity, response.Code)
+
+		missing = map[string][]string{}
+		response = executeRequest(req)
+		checkResponseCode(t, http.StatusOK, response.Code)
+
+		var result HealVnfResponse
+
+		err := json.NewDecoder(response.Body).Decode(&result)
+		if err != nil {
+			t.Fatalf("TestVNFInstanceHeal returned:\n result=%v\n expected=%v", err, restarted)
+		}
+
+		if !reflect.DeepEqual(restarted, result.RestartedPods) {
+			t.Fatalf("TestVNFInstanceHeal returned:\n result=%v\n expected=%v", result.RestartedPods, restarted)
+		}
+
+		if !reflect.DeepEqual(unhealthy, result.UnhealthyPods) {
+			t.Fatalf("TestVNFInstanceHeal returned:\n result=%v\n expected=%v", result.UnhealthyPods, unhealthy)
+		}
+	})
+	t.Run("Succesful heal a stopped VNF keeping it stopped", func(t *testing.T) {
+		missing := map[string][]string{
+			"deployment": []string{"cloud1-default-uuid-sisedeploy"},
+		}
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/cloud1/default/uuid/heal", nil)
+
+		csar.FindMissingResources = func(d map[string][]string, n string, kubeclient *kubernetes.Clientset) (map[string][]string, error) {
+			return missing, nil
+		}
+
+		csar.RecreateResources = func(c string, r string, n string, id string, p map[string]string, o string,
+			d map[string][]string, m map[string][]string, kubeclient *kubernetes.Clientset) (map[string][]string, error) {
+			return m, nil
+		}
+
+		var scaled map[string]int32
+		csar.ScaleVNF = func(d map[string][]string, n string, r map[string]int32, kubeclient *kubernetes.Clientset) error {
+			scaled = r
+			return nil
+		}
+
+		db.DBconn = &mockStoppedDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusOK, response.Code)
+
+		expected := map[string]int32{"cloud1-default-uuid-sisedeploy": 0}
+		if !reflect.DeepEqual(expected, scaled) {
+			t.Fatalf("TestVNFInstanceHeal returned:\n result=%v\n expected=%v", scaled, expected)
+		}
+	})
+}
+
+func TestVNFInstanceUpgrade(t *testing.T) {
+	t.Run("Succesful upgrade a VNF", func(t *testing.T) {
+		payload := []byte(`{
+			"csar_id": "csar2",
+			"timeout": "1m"
+		}`)
+
+		data := map[string][]string{
+			"deployment": []string{"cloud1-default-uuid-sisedeploy"},
+			"service":    []string{"cloud1-default-uuid-sisesvc", "cloud1-default-uuid-siseapi"},
+			"pvc":        []string{"cloud1-default-uuid-data"},
+		}
+		unchanged := map[string][]string{
+			"pvc": []string{"cloud1-default-uuid-data"},
+		}
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/cloud1/default/uuid/upgrade", bytes.NewBuffer(payload))
+
+		GetVNFClient = func(configPath string) (kubernetes.Clientset, error) {
+			return kubernetes.Clientset{}, nil
+		}
+
+		csar.UpgradeVNF = func(from csar.VNFRelease, to csar.VNFRelease, r string, n string, id string, d map[string][]string,
+			p string, timeout time.Duration, kubeclient *kubernetes.Clientset) (map[string][]string, map[string][]string, error) {
+			if from.CsarID != "csar1" || to.CsarID != "csar2" || timeout != time.Minute {
+				return nil, nil, errors.New("Unexpected upgrade from " + from.CsarID + " to " + to.CsarID)
+			}
+			return data, unchanged, nil
+		}
+
+		db.DBconn = &mockRecordDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusOK, response.Code)
+
+		var result UpgradeVnfResponse
+
+		err := json.NewDecoder(response.Body).Decode(&result)
+		if err != nil {
+			t.Fatalf("TestVNFInstanceUpgrade returned:\n result=%v\n expected=%v", err, data)
+		}
+
+		if result.CsarID != "csar2" || !reflect.DeepEqual(data, result.VNFComponents) {
+			t.Fatalf("TestVNFInstanceUpgrade returned:\n result=%v\n expected=%v", result.VNFComponents, data)
+		}
+
+		if !reflect.DeepEqual(unchanged, result.Unchanged) {
+			t.Fatalf("TestVNFInstanceUpgrade returned:\n result=%v\n expected=%v", result.Unchanged, unchanged)
+		}
+	})
+	t.Run("Rolled back upgrade failure", func(t *testing.T) {
+		payload := []byte(`{
+			"csar_id": "csar2"
+		}`)
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/cloud1/default/uuid/upgrade", bytes.NewBuffer(payload))
+
+		csar.UpgradeVNF = func(from csar.VNFRelease, to csar.VNFRelease, r string, n string, id string, d map[string][]string,
+			p string, timeout time.Duration, kubeclient *kubernetes.Clientset) (map[string][]string, map[string][]string, error) {
+			return nil, nil, errors.New("Upgrade failed, rolled back to CSAR " + from.CsarID)
+		}
+
+		db.DBconn = &mockRecordDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusUnprocessableEntity, response.Code)
+	})
+	t.Run("Upgrade a stopped VNF failure", func(t *testing.T) {
+		payload := []byte(`{
+			"csar_id": "csar2"
+		}`)
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/cloud1/default/uuid/upgrade", bytes.NewBuffer(payload))
+
+		csar.UpgradeVNF = func(from csar.VNFRelease, to csar.VNFRelease, r string, n string, id string, d map[string][]string,
+			p string, timeout time.Duration, kubeclient *kubernetes.Clientset) (map[string][]string, map[string][]string, error) {
+			t.Fatalf("TestVNFInstanceUpgrade upgraded a stopped VNF")
+			return nil, nil, nil
+		}
+
+		db.DBconn = &mockStoppedDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusConflict, response.Code)
+	})
+	t.Run("Invalid timeout failure", func(t *testing.T) {
+		payload := []byte(`{
+			"csar_id": "csar2",
+			"timeout": "soon"
+		}`)
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/cloud1/default/uuid/upgrade", bytes.NewBuffer(payload))
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusUnprocessableEntity, response.Code)
+	})
+}
+
+func TestVNFInstanceRevisions(t *testing.T) {
+	t.Run("Succesful list the revisions of a VNF", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/vnf_instances/cloud1/default/uuid/revisions", nil)
+
+		db.DBconn = &mockRevisionDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusOK, response.Code)
+
+		var result ListRevisionsResponse
+
+		err := json.NewDecoder(response.Body).Decode(&result)
+		if err != nil {
+			t.Fatalf("TestVNFInstanceRevisions returned:\n result=%v\n expected=%v", err, 2)
+		}
+
+		if len(result.Revisions) != 2 || result.Revisions[0].Number != 1 || result.Revisions[1].CsarID != "csar2" {
+			t.Fatalf("TestVNFInstanceRevisions returned:\n result=%v\n expected=%v", result.Revisions, 2)
+		}
+	})
+	t.Run("Succesful roll back a VNF to a revision", func(t *testing.T) {
+		payload := []byte(`{
+			"revision": 1
+		}`)
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/cloud1/default/uuid/rollback", bytes.NewBuffer(payload))
+
+		GetVNFClient = func(configPath string) (kubernetes.Clientset, error) {
+			return kubernetes.Clientset{}, nil
+		}
+
+		csar.CSARDigest = func(csarID string) (string, error) {
+			return "digest1", nil
+		}
+
+		var release csar.VNFRelease
+		csar.UpgradeVNF = func(from csar.VNFRelease, to csar.VNFRelease, r string, n string, id string, d map[string][]string,
+			p string, timeout time.Duration, kubeclient *kubernetes.Clientset) (map[string][]string, map[string][]string, error) {
+			release = to
+			return d, nil, nil
+		}
+
+		var scaled map[string]int32
+		csar.ScaleVNF = func(d map[string][]string, n string, r map[string]int32, kubeclient *kubernetes.Clientset) error {
+			scaled = r
+			return nil
+		}
+
+		db.DBconn = &mockRevisionDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusOK, response.Code)
+
+		expected := map[string]int32{"cloud1-default-uuid-sisedeploy": 2}
+		if release.CsarID != "csar1" || !reflect.DeepEqual(expected, scaled) {
+			t.Fatalf("TestVNFInstanceRevisions returned:\n result=%v %v\n expected=%v %v", release.CsarID, scaled, "csar1", expected)
+		}
+	})
+	t.Run("Rollback to a revision of a changed CSAR failure", func(t *testing.T) {
+		payload := []byte(`{
+			"revision": 1
+		}`)
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/cloud1/default/uuid/rollback", bytes.NewBuffer(payload))
+
+		csar.CSARDigest = func(csarID string) (string, error) {
+			return "digest2", nil
+		}
+
+		csar.UpgradeVNF = func(from csar.VNFRelease, to csar.VNFRelease, r string, n string, id string, d map[string][]string,
+			p string, timeout time.Duration, kubeclient *kubernetes.Clientset) (map[string][]string, map[string][]string, error) {
+			t.Fatalf("TestVNFInstanceRevisions rolled back to a changed CSAR")
+			return nil, nil, nil
+		}
+
+		db.DBconn = &mockRevisionDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusConflict, response.Code)
+	})
+	t.Run("Revision not found failure", func(t *testing.T) {
+		payload := []byte(`{
+			"revision": 3
+		}`)
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/cloud1/default/uuid/rollback", bytes.NewBuffer(payload))
+
+		db.DBconn = &mockRevisionDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusNotFound, response.Code)
+	})
+	t.Run("Rollback a stopped VNF failure", func(t *testing.T) {
+		payload := []byte(`{
+			"revision": 1
+		}`)
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/cloud1/default/uuid/rollback", bytes.NewBuffer(payload))
+
+		csar.ScaleVNF = func(d map[string][]string, n string, r map[string]int32, kubeclient *kubernetes.Clientset) error {
+			t.Fatalf("TestVNFInstanceRevisions scaled a stopped VNF")
+			return nil
+		}
+
+		db.DBconn = &mockStoppedDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusConflict, response.Code)
+	})
+}
+
+func TestVNFInstanceStopStart(t *testing.T) {
+	t.Run("Succesful stop a VNF", func(t *testing.T) {
+		replicas := map[string]int32{"cloud1-default-uuid-sisedeploy": 3}
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/cloud1/default/uuid/stop", nil)
+
+		GetVNFClient = func(configPath string) (kubernetes.Clientset, error) {
+			return kubernetes.Clientset{}, nil
+		}
+
+		csar.ReadReplicas = func(d map[string][]string, n string, kubeclient *kubernetes.Clientset) (map[string]int32, error) {
+			return replicas, nil
+		}
+
+		var scaled map[string]int32
+		csar.ScaleVNF = func(d map[string][]string, n string, r map[string]int32, kubeclient *kubernetes.Clientset) error {
+			scaled = r
+			return nil
+		}
+
+		db.DBconn = &mockRecordDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusOK, response.Code)
+
+		var result VnfStateResponse
+
+		err := json.NewDecoder(response.Body).Decode(&result)
+		if err != nil {
+			t.Fatalf("TestVNFInstanceStopStart returned:\n result=%v\n expected=%v", err, replicas)
+		}
+
+		expected := map[string]int32{"cloud1-default-uuid-sisedeploy": 0}
+		if result.State != db.VNFStateStopped || !reflect.DeepEqual(replicas, result.StoppedReplicas) ||
+			!reflect.DeepEqual(expected, scaled) {
+			t.Fatalf("TestVNFInstanceStopStart returned:\n result=%v\n expected=%v", result, replicas)
+		}
+	})
+	t.Run("Succesful start a stopped VNF", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/cloud1/default/uuid/start", nil)
+
+		var scaled map[string]int32
+		csar.ScaleVNF = func(d map[string][]string, n string, r map[string]int32, kubeclient *kubernetes.Clientset) error {
+			scaled = r
+			return nil
+		}
+
+		db.DBconn = &mockStoppedDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusOK, response.Code)
+
+		expected := map[string]int32{"cloud1-default-uuid-sisedeploy": 3}
+		if !reflect.DeepEqual(expected, scaled) {
+			t.Fatalf("TestVNFInstanceStopStart returned:\n result=%v\n expected=%v", scaled, expected)
+		}
+	})
+	t.Run("Start a running VNF failure", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/cloud1/default/uuid/start", nil)
+
+		db.DBconn = &mockRecordDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusConflict, response.Code)
+	})
+}
+
+func TestTenants(t *testing.T) {
+	t.Run("Succesful create a tenant", func(t *testing.T) {
+		payload := []byte(`{
+			"name": "globex",
+			"namespace_pattern": "globex-.*"
+		}`)
+
+		req, _ := http.NewRequest("POST", "/v1/tenants/", bytes.NewBuffer(payload))
+
+		db.DBconn = &mockTenantDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusCreated, response.Code)
+	})
+	t.Run("Existing tenant failure", func(t *testing.T) {
+		payload := []byte(`{
+			"name": "acme"
+		}`)
+
+		req, _ := http.NewRequest("POST", "/v1/tenants/", bytes.NewBuffer(payload))
+
+		db.DBconn = &mockTenantDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusConflict, response.Code)
+	})
+	t.Run("Invalid tenant name failure", func(t *testing.T) {
+		payload := []byte(`{
+			"name": "Acme/Corp"
+		}`)
+
+		req, _ := http.NewRequest("POST", "/v1/tenants/", bytes.NewBuffer(payload))
+
+		db.DBconn = &mockTenantDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusUnprocessableEntity, response.Code)
+	})
+	t.Run("Namespace owned by another tenant failure", func(t *testing.T) {
+		payload := []byte(`{
+			"name": "globex",
+			"namespaces": {"cloud1": ["default"]}
+		}`)
+
+		req, _ := http.NewRequest("POST", "/v1/tenants/", bytes.NewBuffer(payload))
+
+		db.DBconn = &mockTenantDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusConflict, response.Code)
+	})
+	t.Run("Succesful list the tenants", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/tenants/", nil)
+
+		db.DBconn = &mockTenantDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusOK, response.Code)
+
+		var result ListTenantsResponse
+
+		err := json.NewDecoder(response.Body).Decode(&result)
+		if err != nil {
+			t.Fatalf("TestTenants returned:\n result=%v\n expected=%v", err, []string{"acme"})
+		}
+
+		if !reflect.DeepEqual([]string{"acme"}, result.Tenants) {
+			t.Fatalf("TestTenants returned:\n result=%v\n expected=%v", result.Tenants, []string{"acme"})
+		}
+	})
+	t.Run("Succesful get a tenant", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/tenants/acme", nil)
+
+		db.DBconn = &mockTenantDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusOK, response.Code)
+
+		var result db.Tenant
+
+		err := json.NewDecoder(response.Body).Decode(&result)
+		if err != nil {
+			t.Fatalf("TestTenants returned:\n result=%v\n expected=%v", err, "acme")
+		}
+
+		if result.Name != "acme" || !result.OwnsNamespace("cloud1", "default") {
+			t.Fatalf("TestTenants returned:\n result=%v\n expected=%v", result, "acme")
+		}
+	})
+	t.Run("Tenant owning namespaces deletion failure", func(t *testing.T) {
+		req, _ := http.NewRequest("DELETE", "/v1/tenants/acme", nil)
+
+		db.DBconn = &mockTenantDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusConflict, response.Code)
+	})
+	t.Run("Tenant managing tenants failure", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/tenants/", nil)
+		req.Header.Set(TenantHeader, "acme")
+
+		db.DBconn = &mockTenantDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusForbidden, response.Code)
+	})
+	t.Run("Succesful get a VNF of an owned namespace", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/vnf_instances/cloud1/default/uuid", nil)
+		req.Header.Set(TenantHeader, "acme")
+
+		db.DBconn = &mockTenantDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusOK, response.Code)
+	})
+	t.Run("VNF of another namespace not found", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/vnf_instances/cloud1/other/uuid", nil)
+		req.Header.Set(TenantHeader, "acme")
+
+		db.DBconn = &mockTenantDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusNotFound, response.Code)
+	})
+	t.Run("Unknown tenant failure", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/vnf_instances/cloud1/default/uuid", nil)
+		req.Header.Set(TenantHeader, "globex")
+
+		db.DBconn = &mockTenantDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusForbidden, response.Code)
+	})
+	t.Run("Namespace outside of the tenant pattern failure", func(t *testing.T) {
+		payload := []byte(`{
+			"cloud_region_id": "cloud1",
+			"namespace": "test",
+			"csar_id": "UUID-1",
+			"oof_parameters": [{
+				"key1": "value1",
+				"key2": "value2",
+				"key3": {}
+			}],
+			"network_parameters": {
+				"oam_ip_address": {
+					"connection_point": "string",
+					"ip_address": "string",
+					"workload_name": "string"
+				}
+			}
+		}`)
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/", bytes.NewBuffer(payload))
+		req.Header.Set(TenantHeader, "acme")
+
+		db.DBconn = &mockTenantDB{}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusForbidden, response.Code)
+	})
+	t.Run("Release the namespace claimed for a VNF whose creation failed", func(t *testing.T) {
+		payload := []byte(`{
+			"cloud_region_id": "cloud1",
+			"namespace": "acme-core",
+			"csar_id": "UUID-1"
+		}`)
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/", bytes.NewBuffer(payload))
+		req.Header.Set(TenantHeader, "acme")
+
+		GetVNFClient = func(configPath string) (kubernetes.Clientset, error) {
+			return kubernetes.Clientset{}, nil
+		}
+
+		csar.VNFRequests = func(id string, r string, n string, p map[string]string, o string) (coreV1.ResourceList, error) {
+			return coreV1.ResourceList{}, nil
+		}
+
+		csar.CheckQuota = func(l coreV1.ResourceList, n string, p *krd.QuotaProfile, kubeclient *kubernetes.Clientset) error {
+			return nil
+		}
+
+		csar.EnsureNamespace = func(n string, p *krd.QuotaProfile, kubeclient *kubernetes.Clientset) (bool, error) {
+			return true, nil
+		}
+
+		csar.CreateVNF = func(id string, r string, n string, p map[string]string, o string, c map[string]string, kubeclient *kubernetes.Clientset) (string, map[string][]string, error) {
+			tenant, _, _ := db.ReadTenant("acme")
+			if !tenant.OwnsNamespace("cloud1", "acme-core") {
+				t.Fatalf("TestTenants didn't claim the namespace before creating the VNF")
+			}
+			return "", nil, errors.New("Internal error")
+		}
+
+		db.DBconn = &mockMapDB{entries: map[string]string{
+			"tenants/acme": "{\"name\":\"acme\",\"namespace_pattern\":\"acme-.*\",\"namespaces\":{\"cloud1\":[\"default\"]}}",
+		}}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusInternalServerError, response.Code)
+
+		tenant, _, _ := db.ReadTenant("acme")
+		if tenant.OwnsNamespace("cloud1", "acme-core") {
+			t.Fatalf("TestTenants kept the namespace claimed (%v)", tenant.Namespaces)
+		}
+	})
+	t.Run("Release the namespace claimed for a VNF whose record couldn't be written", func(t *testing.T) {
+		payload := []byte(`{
+			"cloud_region_id": "cloud1",
+			"namespace": "acme-core",
+			"csar_id": "UUID-1"
+		}`)
+
+		req, _ := http.NewRequest("POST", "/v1/vnf_instances/", bytes.NewBuffer(payload))
+		req.Header.Set(TenantHeader, "acme")
+
+		csar.CreateVNF = func(id string, r string, n string, p map[string]string, o string, c map[string]string, kubeclient *kubernetes.Clientset) (string, map[string][]string, error) {
+			return "externaluuid", map[string][]string{}, nil
+		}
+
+		db.DBconn = &mockRecordFailureDB{mockMapDB{entries: map[string]string{
+			"tenants/acme": "{\"name\":\"acme\",\"namespace_pattern\":\"acme-.*\",\"namespaces\":{\"cloud1\":[\"default\"]}}",
+		}}}
+
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusInternalServerError, response.Code)
+
+		tenant, _, _ := db.ReadTenant("acme")
+		if tenant.OwnsNamespace("cloud1", "acme-core") {
+			t.Fatalf("TestTenants kept the namespace claimed (%v)", tenant.Namespaces)
+		}
+	})
+}
+
+func TestAuthorization(t *testing.T) {
+	authenticator = mockAuthenticator{}
+	defer func() {
+		authenticator = nil
+	}()
+
+	testCases := []struct {
+		label    string
+		method   string
+		url      string
+		token    string
+		expected int
+	}{
+		{"Missing credentials failure", "GET", "/v1/vnf_instances/cloud1/default/uuid", "", http.StatusUnauthorized},
+		{"Invalid credentials failure", "GET", "/v1/vnf_instances/cloud1/default/uuid", "other", http.StatusUnauthorized},
+		{"Succesful read with the read-only role", "GET", "/v1/vnf_instances/cloud1/default/uuid", "read-only", http.StatusOK},
+		{"Write with the read-only role failure", "DELETE", "/v1/vnf_instances/cloud1/default/uuid", "read-only", http.StatusForbidden},
+		{"Recreate with the read-only role failure", "POST", "/v1/vnf_instances/cloud1/default/uuid/drift", "read-only", http.StatusForbidden},
+		{"Tenants with the operator role failure", "GET", "/v1/tenants/", "operator", http.StatusForbidden},
+		{"Succesful list the tenants with the admin role", "GET", "/v1/tenants/", "admin", http.StatusOK},
+		{"Namespace of another tenant not found", "GET", "/v1/vnf_instances/cloud1/other/uuid", "operator", http.StatusNotFound},
+		{"Tenant header of an authenticated caller ignored", "GET", "/v1/vnf_instances/cloud1/other/uuid", "admin", http.StatusOK},
+		{"Caller without a tenant failure", "GET", "/v1/vnf_instances/cloud1/default/uuid", "unscoped", http.StatusForbidden},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.label, func(t *testing.T) {
+			req, _ := http.NewRequest(testCase.method, testCase.url, nil)
+			if testCase.token != "" {
+				req.Header.Set("Authorization", "Bearer "+testCase.token)
+			}
+			req.Header.Set(TenantHeader, "acme")
+
+			db.DBconn = &mockTenantDB{}
+
+			response := executeRequest(req)
+			checkResponseCode(t, testCase.expected, response.Code)
+		})
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/healthz", nil)
+
+	db.DBconn = &mockDB{}
+
+	recorder := httptest.NewRecorder()
+	HealthHandler(recorder, req)
+	checkResponseCode(t, http.StatusOK, recorder.Code)
+}
+
+func TestCSARRender(t *testing.T) {
+	t.Run("Succesful render a CSAR", func(t *testing.T) {
+		payload := []byte(`{
+			"cloud_region_id": "region1",
+			"namespace": "test",
+			"vnf_id": "uuid",
+			"parameters": {"replicas": "2"}
+		}`)
+
+		rendered := "kind: Deployment\nmetadata:\n  name: region1-test-uuid-sise-deploy\n---\n" +
+			"kind: Secret\nmetadata:\n  name: region1-test-uuid-sise-secret\ndata:\n  password: c2VjcmV0\n"
+		expected := "kind: Deployment\nmetadata:\n  name: region1-test-uuid-sise-deploy\n---\n" +
+			"kind: Secret\nmetadata:\n  name: region1-test-uuid-sise-secret\ndata:\n  password: REDACTED\n"
+
+		os.Setenv("CSAR_DIR", os.TempDir())
+		err := os.MkdirAll(os.TempDir()+"/UUID-1", 0755)
+		if err != nil {
+			t.Fatalf("TestCSARRender returned an error (%s)", err)
+		}
+		defer os.RemoveAll(os.TempDir() + "/UUID-1")
+
+		csar.RenderVNF = func(d string, id string, r string, n string, v string, p map[string]string, o string) (string, error) {
+			if id != "UUID-1" || v != "uuid" || p["replicas"] != "2" {
+				t.Fatalf("TestCSARRender received unexpected parameters %s %s %v", id, v, p)
+			}
+			return rendered, nil
+		}
+
+		req, _ := http.NewRequest("POST", "/v1/csars/UUID-1/render", bytes.NewBuffer(payload))
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusOK, response.Code)
+
+		if response.Body.String() != expected {
+			t.Fatalf("TestCSARRender returned:\n result=%v\n expected=%v", response.Body.String(), expected)
+		}
+	})
+	t.Run("CSAR not found", func(t *testing.T) {
+		payload := []byte(`{
+			"cloud_region_id": "region1",
+			"namespace": "test"
+		}`)
+
+		os.Setenv("CSAR_DIR", os.TempDir())
+
+		req, _ := http.NewRequest("POST", "/v1/csars/missing-csar/render", bytes.NewBuffer(payload))
+		response := executeRequest(req)
+		checkResponseCode(t, http.StatusNotFound, response.Code)
+	})
 }
@@ -0,0 +1,113 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+
+	"github.com/gorilla/mux"
+	pkgerrors "github.com/pkg/errors"
+
+	"k8-plugin-multicloud/csar"
+	"k8-plugin-multicloud/db"
+)
+
+// HealHandler recreates the missing objects of a VNF instance from its
+// original CSAR and restarts its crash-looping pods
+func HealHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	cloudRegionID := vars["cloudRegionID"] // cloud1
+	namespace := vars["namespace"]         // default
+	externalVNFID := vars["externalVNFID"] // uuid
+
+	// cloud1-default-uuid
+	internalVNFID := cloudRegionID + "-" + namespace + "-" + externalVNFID
+
+	unlock := db.LockKey(internalVNFID)
+	defer unlock()
+
+	record, found, err := db.ReadVNFRecord(internalVNFID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if found == false {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	kubeclient, err := GetVNFClient(os.Getenv("KUBE_CONFIG_DIR") + "/" + cloudRegionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := HealVnfResponse{
+		VNFID: externalVNFID,
+	}
+
+	resp.Missing, err = csar.FindMissingResources(record.Resources, namespace, &kubeclient)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Find missing VNF components error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if len(resp.Missing) > 0 {
+		if record.CsarID == "" {
+			http.Error(w, "Missing components of a VNF not created from a CSAR can't be recreated",
+				http.StatusUnprocessableEntity)
+			return
+		}
+
+		resp.Recreated, err = csar.RecreateResources(record.CsarID, cloudRegionID, namespace, externalVNFID,
+			record.Parameters, record.Overlay, record.Resources, resp.Missing, &kubeclient)
+		if err != nil {
+			werr := pkgerrors.Wrap(err, "Recreate VNF components error")
+			http.Error(w, werr.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+
+		err = csar.RestoreReplicas(resp.Recreated, namespace, record.Replicas, record.State == db.VNFStateStopped, &kubeclient)
+		if err != nil {
+			werr := pkgerrors.Wrap(err, "Restore VNF replicas error")
+			http.Error(w, werr.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	resp.RestartedPods, resp.UnhealthyPods, err = csar.RestartUnhealthyPods(externalVNFID, namespace, &kubeclient)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Restart VNF pods error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if len(resp.Recreated) > 0 || len(resp.RestartedPods) > 0 {
+		csar.RecordRevision(internalVNFID, externalVNFID, record, csar.OperationHeal)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Parsing output of VNF heal error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	pkgerrors "github.com/pkg/errors"
+
+	"k8-plugin-multicloud/db"
+)
+
+// HealthResponse contains the status of the plugin
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+// HealthHandler reports whether the plugin can serve requests, which needs
+// its database
+func HealthHandler(w http.ResponseWriter, r *http.Request) {
+	resp := HealthResponse{
+		Status: "ok",
+	}
+	status := http.StatusOK
+
+	err := db.DBconn.CheckDatabase()
+	if err != nil {
+		resp.Status = pkgerrors.Wrap(err, "Database error").Error()
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Parsing output of health error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+	}
+}
@@ -13,6 +13,12 @@ limitations under the License.
 
 package api
 
+import (
+	"k8-plugin-multicloud/db"
+	"k8-plugin-multicloud/gc"
+	"k8-plugin-multicloud/reconcile"
+)
+
 // CreateVnfRequest contains the VNF creation request parameters
 type CreateVnfRequest struct {
 	CloudRegionID string                   `json:"cloud_region_id"`
@@ -22,6 +28,11 @@ type CreateVnfRequest struct {
 	Namespace     string                   `json:"namespace"`
 	Name          string                   `json:"vnf_instance_name"`
 	Description   string                   `json:"vnf_instance_description"`
+	StoragePolicy string                   `json:"storage_policy"`
+	DryRun        bool                     `json:"dry_run"`
+	Parameters    map[string]string        `json:"parameters"`
+	Overlay       string                   `json:"overlay"`
+	ReuseClaimsOf string                   `json:"reuse_claims_of"`
 }
 
 // CreateVnfResponse contains the VNF creation response parameters
@@ -32,6 +43,100 @@ type CreateVnfResponse struct {
 	VNFComponents map[string][]string `json:"vnf_components"`
 }
 
+// DryRunVnfResponse contains the manifests rendered by a VNF creation dry run
+// and the validation errors returned by the cluster
+type DryRunVnfResponse struct {
+	CloudRegionID string   `json:"cloud_region_id"`
+	Namespace     string   `json:"namespace"`
+	Valid         bool     `json:"valid"`
+	Manifests     string   `json:"manifests"`
+	Errors        []string `json:"errors"`
+}
+
+// RenderCsarRequest contains the parameters used to render the manifests of
+// a CSAR. A VNF ID is generated when none is given.
+type RenderCsarRequest struct {
+	CloudRegionID string            `json:"cloud_region_id"`
+	Namespace     string            `json:"namespace"`
+	VNFID         string            `json:"vnf_id"`
+	Parameters    map[string]string `json:"parameters"`
+	Overlay       string            `json:"overlay"`
+}
+
+// AdoptVnfRequest contains the parameters used to bring existing Kubernetes
+// objects under management as a VNF instance. The objects are selected either
+// by a label selector or by name, grouped by plugin.
+type AdoptVnfRequest struct {
+	CloudRegionID string              `json:"cloud_region_id"`
+	Namespace     string              `json:"namespace"`
+	CsarID        string              `json:"csar_id"`
+	LabelSelector string              `json:"label_selector"`
+	Resources     map[string][]string `json:"resources"`
+	Relabel       bool                `json:"relabel"`
+	StoragePolicy string              `json:"storage_policy"`
+}
+
+// ScaleVnfRequest contains either the replicas of the VNF workloads, keyed by
+// name, or the name of a scaling aspect declared in the CSAR
+type ScaleVnfRequest struct {
+	Replicas map[string]int32 `json:"replicas"`
+	Aspect   string           `json:"aspect"`
+}
+
+// ScaleVnfResponse contains the desired replicas of the scaled VNF workloads
+type ScaleVnfResponse struct {
+	VNFID    string           `json:"vnf_id"`
+	Replicas map[string]int32 `json:"replicas"`
+}
+
+// HealVnfResponse contains the VNF components found missing, the ones created
+// again, the pods restarted by a heal and the unhealthy ones it couldn't
+// restart
+type HealVnfResponse struct {
+	VNFID         string              `json:"vnf_id"`
+	Missing       map[string][]string `json:"missing_components"`
+	Recreated     map[string][]string `json:"recreated_components,omitempty"`
+	RestartedPods []string            `json:"restarted_pods,omitempty"`
+	UnhealthyPods []string            `json:"unhealthy_pods,omitempty"`
+}
+
+// UpgradeVnfRequest contains the CSAR a VNF is upgraded to. The parameters
+// and overlay of the VNF are kept when omitted.
+type UpgradeVnfRequest struct {
+	CsarID     string            `json:"csar_id"`
+	Parameters map[string]string `json:"parameters"`
+	Overlay    string            `json:"overlay"`
+	Timeout    string            `json:"timeout"`
+}
+
+// UpgradeVnfResponse contains the components of an upgraded VNF, and the
+// ones found in both CSARs which can't be updated
+type UpgradeVnfResponse struct {
+	VNFID         string              `json:"vnf_id"`
+	CsarID        string              `json:"csar_id"`
+	VNFComponents map[string][]string `json:"vnf_components"`
+	Unchanged     map[string][]string `json:"unchanged_components,omitempty"`
+}
+
+// RollbackVnfRequest contains the number of the revision a VNF is rolled
+// back to
+type RollbackVnfRequest struct {
+	Revision int    `json:"revision"`
+	Timeout  string `json:"timeout"`
+}
+
+// ListRevisionsResponse contains the revisions of a VNF from the oldest to
+// the newest
+type ListRevisionsResponse struct {
+	VNFID     string        `json:"vnf_id"`
+	Revisions []db.Revision `json:"revisions"`
+}
+
+// ListTenantsResponse contains the names of the tenants
+type ListTenantsResponse struct {
+	Tenants []string `json:"tenants"`
+}
+
 // ListVnfsResponse contains the list of VNFs response parameters
 type ListVnfsResponse struct {
 	VNFs []string `json:"vnf_id_list"`
@@ -72,9 +177,33 @@ type GetVnfResponse struct {
 	VNFID         string              `json:"vnf_id"`
 	CloudRegionID string              `json:"cloud_region_id"`
 	Namespace     string              `json:"namespace"`
+	CsarID        string              `json:"csar_id,omitempty"`
+	StoragePolicy string              `json:"storage_policy,omitempty"`
+	State         string              `json:"state"`
+	Replicas      map[string]int32    `json:"replicas,omitempty"`
 	VNFComponents map[string][]string `json:"vnf_components"`
 }
 
+// VnfStateResponse contains the state of a stopped or started VNF and the
+// replicas its workloads are started with
+type VnfStateResponse struct {
+	VNFID           string           `json:"vnf_id"`
+	State           string           `json:"state"`
+	StoppedReplicas map[string]int32 `json:"stopped_replicas,omitempty"`
+}
+
+// ListDriftResponse contains the VNFs whose objects are missing from their
+// clusters, as found by the last reconciliation
+type ListDriftResponse struct {
+	Drifts []reconcile.Drift `json:"drifts"`
+}
+
+// OrphansResponse contains the objects created by the plugin which don't
+// belong to a stored VNF
+type OrphansResponse struct {
+	Orphans []gc.Orphan `json:"orphans"`
+}
+
 // GeneralResponse is a generic response
 type GeneralResponse struct {
 	Response string `json:"response"`
@@ -0,0 +1,236 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	pkgerrors "github.com/pkg/errors"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"k8-plugin-multicloud/csar"
+	"k8-plugin-multicloud/db"
+	"k8-plugin-multicloud/krd"
+)
+
+// vnfIDPrefix matches the VNF ID starting the name of an object once the
+// cloud region and the namespace are trimmed
+var vnfIDPrefix = regexp.MustCompile("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}-")
+
+// retainedClaims maps the names declared in the CSAR to the persistent volume
+// claims retained in a namespace by a deleted VNF. Claims reused before keep
+// the VNF ID of the VNF which created them in their name.
+func retainedClaims(cloudRegionID string, namespace string, externalVNFID string,
+	kubeclient *kubernetes.Clientset) (map[string]string, error) {
+
+	claims, err := csar.ListResources(namespace, krd.RetainedSelector+","+krd.VNFSelector(externalVNFID), kubeclient)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := cloudRegionID + "-" + namespace + "-"
+	result := make(map[string]string)
+	for _, claim := range claims["pvc"] {
+		originalName := vnfIDPrefix.ReplaceAllString(strings.TrimPrefix(claim, prefix), "")
+		result[originalName] = claim
+	}
+
+	return result, nil
+}
+
+// pendingRequests holds the resources requested by the VNFs being created, by
+// namespace and placeholder, as the ResourceQuotas only count the objects of a
+// VNF once they are created
+var pendingRequests = struct {
+	sync.Mutex
+	requests map[string]map[string]coreV1.ResourceList
+}{
+	requests: make(map[string]map[string]coreV1.ResourceList),
+}
+
+// reserveNamespace claims the namespace of a new VNF for its tenant when no
+// tenant owns it yet, checks the VNF fits in the quota of the namespace along
+// with the VNFs being created in it, and registers it in the namespace. All
+// of it happens under the namespace lock, so concurrent creations can't claim
+// the namespace for different tenants nor exceed the quota together. The
+// requests of the VNF are counted until releaseRequests is called. It returns
+// whether the namespace was claimed, and writes the error to w when the VNF
+// can't be registered.
+func reserveNamespace(w http.ResponseWriter, tenantName string, cloudRegionID string, namespace string, internalVNFID string,
+	requested coreV1.ResourceList, profile *krd.QuotaProfile, kubeclient *kubernetes.Clientset) (bool, bool) {
+
+	unlock := db.LockNamespace(cloudRegionID, namespace)
+	defer unlock()
+
+	claim, ok := claimNamespace(w, tenantName, cloudRegionID, namespace)
+	if !ok {
+		return false, false
+	}
+
+	err := csar.CheckQuota(withPendingRequests(cloudRegionID, namespace, requested), namespace, profile, kubeclient)
+	if err != nil {
+		releaseClaim(tenantName, cloudRegionID, namespace, claim)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return false, false
+	}
+
+	err = acquireNamespace(cloudRegionID, namespace, internalVNFID, profile, kubeclient)
+	if err != nil {
+		releaseClaim(tenantName, cloudRegionID, namespace, claim)
+		werr := pkgerrors.Wrap(err, "Create VNF namespace error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+		return false, false
+	}
+
+	pendingRequests.Lock()
+	defer pendingRequests.Unlock()
+
+	key := cloudRegionID + "/" + namespace
+	if pendingRequests.requests[key] == nil {
+		pendingRequests.requests[key] = make(map[string]coreV1.ResourceList)
+	}
+	pendingRequests.requests[key][internalVNFID] = requested
+
+	return claim, true
+}
+
+// withPendingRequests returns the resources requested by a new VNF added to
+// those of the VNFs being created in its namespace
+func withPendingRequests(cloudRegionID string, namespace string, requested coreV1.ResourceList) coreV1.ResourceList {
+	pendingRequests.Lock()
+	defer pendingRequests.Unlock()
+
+	total := requested.DeepCopy()
+	for _, pending := range pendingRequests.requests[cloudRegionID+"/"+namespace] {
+		for name, quantity := range pending {
+			sum := total[name]
+			sum.Add(quantity)
+			total[name] = sum
+		}
+	}
+
+	return total
+}
+
+// releaseRequests stops counting the requests of a VNF whose creation ended
+func releaseRequests(cloudRegionID string, namespace string, internalVNFID string) {
+	pendingRequests.Lock()
+	defer pendingRequests.Unlock()
+
+	key := cloudRegionID + "/" + namespace
+	delete(pendingRequests.requests[key], internalVNFID)
+	if len(pendingRequests.requests[key]) == 0 {
+		delete(pendingRequests.requests, key)
+	}
+}
+
+// acquireNamespace creates the namespace of a VNF when it is missing and
+// registers the VNF in the record of the namespaces created by the plugin,
+// before any of its objects is created, so that the deletion of the last
+// other VNF of the namespace keeps it. The caller holds the LockNamespace
+// lock.
+func acquireNamespace(cloudRegionID string, namespace string, internalVNFID string, profile *krd.QuotaProfile,
+	kubeclient *kubernetes.Clientset) error {
+
+	created, err := csar.EnsureNamespace(namespace, profile, kubeclient)
+	if err != nil {
+		return err
+	}
+
+	if created {
+		return db.WriteNamespaceRecord(db.NamespaceRecord{
+			CloudRegionID: cloudRegionID,
+			Namespace:     namespace,
+			VNFs:          []string{internalVNFID},
+		})
+	}
+
+	return db.AddNamespaceVNF(cloudRegionID, namespace, internalVNFID)
+}
+
+// releaseNamespace forgets a VNF terminated in a namespace created by the
+// plugin, recording the persistent volume claims it retained. The namespace is
+// deleted along with its last VNF when enabled for the cloud region, unless
+// retained claims or objects not created by the plugin are left in it.
+func releaseNamespace(cloudRegionID string, namespace string, internalVNFID string, retainedClaims []string,
+	kubeclient *kubernetes.Clientset) error {
+
+	unlock := db.LockNamespace(cloudRegionID, namespace)
+	defer unlock()
+
+	record, found, err := db.RemoveNamespaceVNF(cloudRegionID, namespace, internalVNFID, retainedClaims)
+	if err != nil || found == false {
+		return err
+	}
+
+	if len(record.VNFs) > 0 || !csar.NamespaceCleanupEnabled(cloudRegionID) {
+		return nil
+	}
+
+	if len(record.RetainedClaims) > 0 {
+		// Forget the retained claims deleted since
+		retained, err := csar.ListResources(namespace, krd.RetainedSelector, kubeclient)
+		if err != nil {
+			return err
+		}
+
+		record.RetainedClaims = intersect(record.RetainedClaims, retained["pvc"])
+		err = db.WriteNamespaceRecord(record)
+		if err != nil {
+			return err
+		}
+
+		if len(record.RetainedClaims) > 0 {
+			log.Printf("Keeping namespace %s of %s holding retained claims %v", namespace, cloudRegionID,
+				record.RetainedClaims)
+			return nil
+		}
+	}
+
+	foreign, err := csar.ForeignObjects(namespace, kubeclient)
+	if err != nil {
+		return err
+	}
+
+	if len(foreign) > 0 {
+		log.Printf("Keeping namespace %s of %s holding foreign objects %v", namespace, cloudRegionID, foreign)
+		return nil
+	}
+
+	err = csar.DeleteNamespace(namespace, kubeclient)
+	if err != nil {
+		return err
+	}
+
+	return db.DeleteNamespaceRecord(cloudRegionID, namespace)
+}
+
+// intersect returns the names of a list also found in another
+func intersect(names []string, others []string) []string {
+	var result []string
+	for _, name := range names {
+		for _, other := range others {
+			if name == other {
+				result = append(result, name)
+				break
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+
+	"github.com/gorilla/mux"
+	pkgerrors "github.com/pkg/errors"
+
+	"k8-plugin-multicloud/csar"
+)
+
+// RenderHandler returns the manifests a VNF would be created with from a
+// CSAR as multi-document YAML, without contacting the cluster. The values of
+// the Secrets are redacted.
+func RenderHandler(w http.ResponseWriter, r *http.Request) {
+	var resource RenderCsarRequest
+
+	if r.Body == nil {
+		http.Error(w, "Body empty", http.StatusBadRequest)
+		return
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&resource)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	err = validateBody(resource)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	csarID := mux.Vars(r)["csarID"]
+	csarDirPath := os.Getenv("CSAR_DIR") + "/" + csarID
+	if _, err := os.Stat(csarDirPath); os.IsNotExist(err) {
+		http.Error(w, "CSAR "+csarID+" not found", http.StatusNotFound)
+		return
+	}
+
+	manifests, err := csar.RenderVNF(csarDirPath, csarID, resource.CloudRegionID, resource.Namespace,
+		resource.VNFID, resource.Parameters, resource.Overlay)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Render CSAR error")
+		http.Error(w, werr.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	manifests, err = csar.RedactSecrets(manifests)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-yaml")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(manifests))
+}
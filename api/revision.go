@@ -0,0 +1,220 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gorilla/mux"
+	pkgerrors "github.com/pkg/errors"
+
+	"k8-plugin-multicloud/csar"
+	"k8-plugin-multicloud/db"
+)
+
+// RevisionsHandler lists the revisions of a VNF instance
+func RevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	cloudRegionID := vars["cloudRegionID"] // cloud1
+	namespace := vars["namespace"]         // default
+	externalVNFID := vars["externalVNFID"] // uuid
+
+	// cloud1-default-uuid
+	internalVNFID := cloudRegionID + "-" + namespace + "-" + externalVNFID
+
+	_, found, err := db.ReadVNFRecord(internalVNFID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if found == false {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	revisions, err := db.ListRevisions(internalVNFID)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Get VNF revisions error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := ListRevisionsResponse{
+		VNFID:     externalVNFID,
+		Revisions: revisions,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Parsing output of VNF revisions error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+	}
+}
+
+// RollbackHandler re-applies a revision of a VNF instance: its objects are
+// upgraded to the CSAR, parameters and overlay of the revision and its
+// workloads scaled back to the replicas of the revision. Revisions whose CSAR
+// changed since they were recorded are refused.
+func RollbackHandler(w http.ResponseWriter, r *http.Request) {
+	var resource RollbackVnfRequest
+
+	if r.Body == nil {
+		http.Error(w, "Body empty", http.StatusBadRequest)
+		return
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&resource)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	err = validateBody(resource)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	cloudRegionID := vars["cloudRegionID"] // cloud1
+	namespace := vars["namespace"]         // default
+	externalVNFID := vars["externalVNFID"] // uuid
+
+	// cloud1-default-uuid
+	internalVNFID := cloudRegionID + "-" + namespace + "-" + externalVNFID
+
+	unlock := db.LockKey(internalVNFID)
+	defer unlock()
+
+	record, found, err := db.ReadVNFRecord(internalVNFID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if found == false {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// The revision would scale the workloads of a stopped VNF back up
+	if record.State == db.VNFStateStopped {
+		http.Error(w, "Stopped VNFs can't be rolled back", http.StatusConflict)
+		return
+	}
+
+	revision, found, err := db.ReadRevision(internalVNFID, resource.Revision)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if found == false {
+		http.Error(w, "Revision not found", http.StatusNotFound)
+		return
+	}
+
+	if record.CsarID == "" || revision.CsarID == "" {
+		http.Error(w, "Only revisions of VNFs created from a CSAR can be re-applied", http.StatusUnprocessableEntity)
+		return
+	}
+
+	// The revision is rendered again from its CSAR, which must be the one
+	// its manifests were rendered from
+	digest, err := csar.CSARDigest(revision.CsarID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	if revision.CsarDigest == "" || digest != revision.CsarDigest {
+		http.Error(w, "CSAR "+revision.CsarID+" changed since the revision was recorded", http.StatusConflict)
+		return
+	}
+
+	timeout := defaultUpgradeTimeout
+	if resource.Timeout != "" {
+		timeout, _ = time.ParseDuration(resource.Timeout)
+	}
+
+	kubeclient, err := GetVNFClient(os.Getenv("KUBE_CONFIG_DIR") + "/" + cloudRegionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	to := csar.VNFRelease{
+		CsarID:     revision.CsarID,
+		Parameters: revision.Parameters,
+		Overlay:    revision.Overlay,
+	}
+
+	unchanged, err := applyRelease(&record, to, externalVNFID, timeout, &kubeclient)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Rollback VNF error")
+		http.Error(w, werr.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	replicas := make(map[string]int32)
+	for name, count := range revision.Replicas {
+		if containsString(record.Resources["deployment"], name) || containsString(record.Resources["statefulset"], name) {
+			replicas[name] = count
+		}
+	}
+
+	if len(replicas) > 0 {
+		err = csar.ScaleVNF(record.Resources, namespace, replicas, &kubeclient)
+		if err != nil {
+			log.Println("Scale VNF " + internalVNFID + " error: " + err.Error())
+		} else {
+			record.Replicas = replicas
+		}
+	}
+
+	err = db.WriteVNFRecord(internalVNFID, record)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Update VNF record error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	csar.RecordRevision(internalVNFID, externalVNFID, record, csar.OperationRollback)
+
+	resp := UpgradeVnfResponse{
+		VNFID:         externalVNFID,
+		CsarID:        record.CsarID,
+		VNFComponents: record.Resources,
+		Unchanged:     unchanged,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Parsing output of VNF rollback error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+	}
+}
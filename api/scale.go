@@ -0,0 +1,164 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+
+	"github.com/gorilla/mux"
+	pkgerrors "github.com/pkg/errors"
+
+	"k8-plugin-multicloud/csar"
+	"k8-plugin-multicloud/db"
+)
+
+// ScaleHandler sets the replicas of the deployments and statefulsets of a VNF
+// instance, either from a replica map or from a scaling aspect of its CSAR,
+// and stores them as the desired state of the VNF
+func ScaleHandler(w http.ResponseWriter, r *http.Request) {
+	var resource ScaleVnfRequest
+
+	if r.Body == nil {
+		http.Error(w, "Body empty", http.StatusBadRequest)
+		return
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&resource)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	err = validateBody(resource)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	cloudRegionID := vars["cloudRegionID"] // cloud1
+	namespace := vars["namespace"]         // default
+	externalVNFID := vars["externalVNFID"] // uuid
+
+	// cloud1-default-uuid
+	internalVNFID := cloudRegionID + "-" + namespace + "-" + externalVNFID
+
+	unlock := db.LockKey(internalVNFID)
+	defer unlock()
+
+	record, found, err := db.ReadVNFRecord(internalVNFID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if found == false {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if record.State == db.VNFStateStopped {
+		http.Error(w, "Stopped VNFs can't be scaled", http.StatusConflict)
+		return
+	}
+
+	replicas := resource.Replicas
+	if resource.Aspect != "" {
+		if record.CsarID == "" {
+			http.Error(w, "VNF wasn't created from a CSAR", http.StatusUnprocessableEntity)
+			return
+		}
+
+		replicas, err = csar.ReadScalingAspect(record.CsarID, record.CloudRegionID, resource.Aspect)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	// Workloads may be named as in the CSAR or with their VNF name
+	desired := make(map[string]int32)
+	for name, count := range replicas {
+		if count < 0 {
+			http.Error(w, "Invalid replicas for "+name, http.StatusUnprocessableEntity)
+			return
+		}
+		if !containsString(record.Resources["deployment"], name) && !containsString(record.Resources["statefulset"], name) {
+			name = internalVNFID + "-" + name
+		}
+		desired[name] = count
+	}
+
+	kubeclient, err := GetVNFClient(os.Getenv("KUBE_CONFIG_DIR") + "/" + cloudRegionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	previous, err := csar.ReadReplicas(record.Resources, namespace, &kubeclient)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Scale VNF error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = csar.ScaleVNF(record.Resources, namespace, desired, &kubeclient)
+	if err != nil {
+		// Restore the workloads already scaled so they match the record
+		restored := make(map[string]int32)
+		for name := range desired {
+			if count, ok := previous[name]; ok {
+				restored[name] = count
+			}
+		}
+		csar.ScaleVNF(record.Resources, namespace, restored, &kubeclient)
+
+		werr := pkgerrors.Wrap(err, "Scale VNF error")
+		http.Error(w, werr.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	if record.Replicas == nil {
+		record.Replicas = make(map[string]int32)
+	}
+	for name, count := range desired {
+		record.Replicas[name] = count
+	}
+
+	err = db.WriteVNFRecord(internalVNFID, record)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Update VNF record error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	csar.RecordRevision(internalVNFID, externalVNFID, record, csar.OperationScale)
+
+	resp := ScaleVnfResponse{
+		VNFID:    externalVNFID,
+		Replicas: record.Replicas,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Parsing output of VNF scale error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+
+	"github.com/gorilla/mux"
+	pkgerrors "github.com/pkg/errors"
+
+	"k8-plugin-multicloud/csar"
+	"k8-plugin-multicloud/db"
+)
+
+// StopHandler scales the deployments and statefulsets of a VNF instance to
+// zero, remembering their replicas in the VNF record
+func StopHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	cloudRegionID := vars["cloudRegionID"] // cloud1
+	namespace := vars["namespace"]         // default
+	externalVNFID := vars["externalVNFID"] // uuid
+
+	// cloud1-default-uuid
+	internalVNFID := cloudRegionID + "-" + namespace + "-" + externalVNFID
+
+	unlock := db.LockKey(internalVNFID)
+	defer unlock()
+
+	record, found, err := db.ReadVNFRecord(internalVNFID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if found == false {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if record.State == db.VNFStateStopped {
+		http.Error(w, "VNF is already stopped", http.StatusConflict)
+		return
+	}
+
+	kubeclient, err := GetVNFClient(os.Getenv("KUBE_CONFIG_DIR") + "/" + cloudRegionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	replicas, err := csar.ReadReplicas(record.Resources, namespace, &kubeclient)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Stop VNF error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	stopped := make(map[string]int32)
+	for name := range replicas {
+		stopped[name] = 0
+	}
+
+	err = csar.ScaleVNF(record.Resources, namespace, stopped, &kubeclient)
+	if err != nil {
+		// Restore the workloads already scaled down
+		csar.ScaleVNF(record.Resources, namespace, replicas, &kubeclient)
+
+		werr := pkgerrors.Wrap(err, "Stop VNF error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	record.State = db.VNFStateStopped
+	record.StoppedReplicas = replicas
+
+	writeStateResponse(w, internalVNFID, externalVNFID, record, csar.OperationStop)
+}
+
+// StartHandler scales the deployments and statefulsets of a stopped VNF
+// instance back to the replicas they had when it was stopped
+func StartHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	cloudRegionID := vars["cloudRegionID"] // cloud1
+	namespace := vars["namespace"]         // default
+	externalVNFID := vars["externalVNFID"] // uuid
+
+	// cloud1-default-uuid
+	internalVNFID := cloudRegionID + "-" + namespace + "-" + externalVNFID
+
+	unlock := db.LockKey(internalVNFID)
+	defer unlock()
+
+	record, found, err := db.ReadVNFRecord(internalVNFID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if found == false {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if record.State != db.VNFStateStopped {
+		http.Error(w, "VNF isn't stopped", http.StatusConflict)
+		return
+	}
+
+	kubeclient, err := GetVNFClient(os.Getenv("KUBE_CONFIG_DIR") + "/" + cloudRegionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Workloads removed while the VNF was stopped are skipped
+	replicas := make(map[string]int32)
+	for name, count := range record.StoppedReplicas {
+		if containsString(record.Resources["deployment"], name) || containsString(record.Resources["statefulset"], name) {
+			replicas[name] = count
+		}
+	}
+
+	err = csar.ScaleVNF(record.Resources, namespace, replicas, &kubeclient)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Start VNF error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	record.State = db.VNFStateRunning
+	record.StoppedReplicas = nil
+
+	writeStateResponse(w, internalVNFID, externalVNFID, record, csar.OperationStart)
+}
+
+// writeStateResponse stores the record of a stopped or started VNF and
+// writes its state
+func writeStateResponse(w http.ResponseWriter, internalVNFID string, externalVNFID string, record db.VNFRecord, operation string) {
+	err := db.WriteVNFRecord(internalVNFID, record)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Update VNF record error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	csar.RecordRevision(internalVNFID, externalVNFID, record, operation)
+
+	resp := VnfStateResponse{
+		VNFID:           externalVNFID,
+		State:           record.State,
+		StoppedReplicas: record.StoppedReplicas,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Parsing output of VNF state error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,437 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+
+	"github.com/gorilla/mux"
+	pkgerrors "github.com/pkg/errors"
+
+	"k8-plugin-multicloud/auth"
+	"k8-plugin-multicloud/db"
+)
+
+// TenantHeader names the tenant a request is made for when authentication is
+// disabled. Requests without it aren't scoped to a tenant. Authenticated
+// callers are scoped to the tenant of their identity only.
+const TenantHeader = "X-Tenant-ID"
+
+// tenantNamePattern validates the name of a tenant, which is part of its DB key
+var tenantNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// tenantFromRequest returns the tenant a request is made for, if any. The
+// header can't be trusted once callers are authenticated.
+func tenantFromRequest(r *http.Request) string {
+	identity, ok := auth.FromContext(r.Context())
+	if ok {
+		return identity.Tenant
+	}
+	return r.Header.Get(TenantHeader)
+}
+
+// requireScope rejects the authenticated callers without a tenant unless
+// they are admins, as requests without a tenant reach every namespace
+func requireScope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := auth.FromContext(r.Context())
+		if ok && identity.Tenant == "" && !identity.Role.Includes(auth.RoleAdmin) {
+			http.Error(w, "Callers without a tenant need the "+string(auth.RoleAdmin)+" role", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// tenantScope hides the VNF instances of the namespaces a tenant doesn't own
+// from its requests
+func tenantScope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := tenantFromRequest(r)
+		vars := mux.Vars(r)
+		if name == "" || vars["namespace"] == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		tenant, found, err := db.ReadTenant(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if found == false {
+			http.Error(w, "Unknown tenant "+name, http.StatusForbidden)
+			return
+		}
+
+		if !tenant.OwnsNamespace(vars["cloudRegionID"], vars["namespace"]) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withoutTenant rejects the requests made for a tenant, for the routes
+// managing every tenant
+func withoutTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tenantFromRequest(r) != "" {
+			http.Error(w, "Not allowed for tenants", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorizeNamespace checks a tenant may deploy into a namespace of a cloud
+// region, either because it owns it or because the tenant pattern matches a
+// namespace no other tenant owns. The tenant and whether the namespace has
+// to be claimed are returned.
+func authorizeNamespace(name string, cloudRegionID string, namespace string) (db.Tenant, bool, error) {
+	tenant, found, err := db.ReadTenant(name)
+	if err != nil {
+		return tenant, false, err
+	}
+
+	if found == false {
+		return tenant, false, pkgerrors.New("Unknown tenant " + name)
+	}
+
+	if tenant.OwnsNamespace(cloudRegionID, namespace) {
+		return tenant, false, nil
+	}
+
+	if !tenant.MatchesNamespace(namespace) {
+		return tenant, false, pkgerrors.New("Namespace " + namespace + " not allowed for tenant " + name)
+	}
+
+	_, owned, err := db.FindNamespaceOwner(cloudRegionID, namespace)
+	if err != nil {
+		return tenant, false, err
+	}
+
+	if owned {
+		return tenant, false, pkgerrors.New("Namespace " + namespace + " belongs to another tenant")
+	}
+
+	return tenant, true, nil
+}
+
+// claimNamespace checks again a tenant may deploy into a namespace of a cloud
+// region and adds the namespace to the tenant when no tenant owns it yet. The
+// caller holds the LockNamespace lock, so no other tenant can claim the
+// namespace meanwhile. Nothing is checked for requests without a tenant. It
+// returns whether the namespace was claimed, and writes the error to w when
+// the tenant may not deploy into it.
+func claimNamespace(w http.ResponseWriter, name string, cloudRegionID string, namespace string) (bool, bool) {
+	if name == "" {
+		return false, true
+	}
+
+	unlock := db.LockTenant(name)
+	defer unlock()
+
+	tenant, claim, err := authorizeNamespace(name, cloudRegionID, namespace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return false, false
+	}
+
+	if !claim {
+		return false, true
+	}
+
+	if tenant.Namespaces == nil {
+		tenant.Namespaces = make(map[string][]string)
+	}
+	tenant.Namespaces[cloudRegionID] = append(tenant.Namespaces[cloudRegionID], namespace)
+
+	err = db.WriteTenant(tenant)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Claim VNF namespace error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+		return false, false
+	}
+
+	return true, true
+}
+
+// unclaimNamespace removes a namespace of a cloud region from a tenant, when
+// the VNF it was claimed for couldn't be created. The caller holds the
+// LockNamespace lock.
+func unclaimNamespace(name string, cloudRegionID string, namespace string) error {
+	unlock := db.LockTenant(name)
+	defer unlock()
+
+	tenant, found, err := db.ReadTenant(name)
+	if err != nil || found == false {
+		return err
+	}
+
+	namespaces := make([]string, 0, len(tenant.Namespaces[cloudRegionID]))
+	for _, owned := range tenant.Namespaces[cloudRegionID] {
+		if owned != namespace {
+			namespaces = append(namespaces, owned)
+		}
+	}
+	tenant.Namespaces[cloudRegionID] = namespaces
+
+	return db.WriteTenant(tenant)
+}
+
+// releaseClaim unclaims the namespace claimed for a VNF which couldn't be
+// created, if any. Errors are only logged as the creation error is reported
+// instead. The caller holds the LockNamespace lock.
+func releaseClaim(name string, cloudRegionID string, namespace string, claim bool) {
+	if !claim {
+		return
+	}
+
+	err := unclaimNamespace(name, cloudRegionID, namespace)
+	if err != nil {
+		log.Println("Release VNF namespace claim error: " + err.Error())
+	}
+}
+
+// lockTenant takes the locks of the namespaces of a tenant, in a fixed order,
+// and then the lock of the tenant. It returns the function releasing them.
+func lockTenant(tenant db.Tenant) func() {
+	var keys [][2]string
+	for cloudRegionID, namespaces := range tenant.Namespaces {
+		for _, namespace := range namespaces {
+			keys = append(keys, [2]string{cloudRegionID, namespace})
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+
+	var unlocks []func()
+	for i, key := range keys {
+		if i > 0 && key == keys[i-1] {
+			continue
+		}
+		unlocks = append(unlocks, db.LockNamespace(key[0], key[1]))
+	}
+	unlocks = append(unlocks, db.LockTenant(tenant.Name))
+
+	return func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+}
+
+// CreateTenantHandler stores a new tenant
+func CreateTenantHandler(w http.ResponseWriter, r *http.Request) {
+	var tenant db.Tenant
+
+	if r.Body == nil {
+		http.Error(w, "Body empty", http.StatusBadRequest)
+		return
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&tenant)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	err = validateBody(tenant)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	_, found, err := db.ReadTenant(tenant.Name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if found {
+		http.Error(w, "Tenant "+tenant.Name+" already exists", http.StatusConflict)
+		return
+	}
+
+	writeTenant(w, tenant, http.StatusCreated)
+}
+
+// UpdateTenantHandler replaces the namespaces and pattern of a tenant
+func UpdateTenantHandler(w http.ResponseWriter, r *http.Request) {
+	var tenant db.Tenant
+
+	if r.Body == nil {
+		http.Error(w, "Body empty", http.StatusBadRequest)
+		return
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&tenant)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	tenant.Name = mux.Vars(r)["tenant"]
+
+	err = validateBody(tenant)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	current, found, err := db.ReadTenant(tenant.Name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if found == false {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	tenant.CreatedAt = current.CreatedAt
+
+	writeTenant(w, tenant, http.StatusOK)
+}
+
+// writeTenant stores a tenant, unless one of its namespaces belongs to
+// another tenant, and writes it. Its namespaces are locked meanwhile, so no
+// other tenant can claim them.
+func writeTenant(w http.ResponseWriter, tenant db.Tenant, status int) {
+	unlock := lockTenant(tenant)
+	defer unlock()
+
+	for cloudRegionID, namespaces := range tenant.Namespaces {
+		for _, namespace := range namespaces {
+			owner, found, err := db.FindNamespaceOwner(cloudRegionID, namespace)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if found && owner.Name != tenant.Name {
+				http.Error(w, "Namespace "+namespace+" of "+cloudRegionID+" belongs to tenant "+owner.Name,
+					http.StatusConflict)
+				return
+			}
+		}
+	}
+
+	err := db.WriteTenant(tenant)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Write tenant error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err = json.NewEncoder(w).Encode(tenant)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Parsing output of tenant error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+	}
+}
+
+// ListTenantsHandler returns the names of the tenants
+func ListTenantsHandler(w http.ResponseWriter, r *http.Request) {
+	names, err := db.ListTenants()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := ListTenantsResponse{
+		Tenants: names,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Parsing output of tenant list error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+	}
+}
+
+// GetTenantHandler returns a tenant
+func GetTenantHandler(w http.ResponseWriter, r *http.Request) {
+	tenant, found, err := db.ReadTenant(mux.Vars(r)["tenant"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if found == false {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(tenant)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Parsing output of tenant error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+	}
+}
+
+// DeleteTenantHandler deletes a tenant which no longer owns any namespace
+func DeleteTenantHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["tenant"]
+
+	unlock := db.LockTenant(name)
+	defer unlock()
+
+	tenant, found, err := db.ReadTenant(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if found == false {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	for _, namespaces := range tenant.Namespaces {
+		if len(namespaces) > 0 {
+			http.Error(w, "Tenant "+name+" still owns namespaces", http.StatusConflict)
+			return
+		}
+	}
+
+	err = db.DeleteTenant(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gorilla/mux"
+	pkgerrors "github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+
+	"k8-plugin-multicloud/csar"
+	"k8-plugin-multicloud/db"
+)
+
+// defaultUpgradeTimeout is the time given to the workloads of an upgraded VNF
+// to roll out before it is rolled back
+const defaultUpgradeTimeout = 5 * time.Minute
+
+// UpgradeHandler moves a VNF instance to a new CSAR, rolling it back to its
+// current CSAR when the new workloads don't roll out in time
+func UpgradeHandler(w http.ResponseWriter, r *http.Request) {
+	var resource UpgradeVnfRequest
+
+	if r.Body == nil {
+		http.Error(w, "Body empty", http.StatusBadRequest)
+		return
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&resource)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	err = validateBody(resource)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	cloudRegionID := vars["cloudRegionID"] // cloud1
+	namespace := vars["namespace"]         // default
+	externalVNFID := vars["externalVNFID"] // uuid
+
+	// cloud1-default-uuid
+	internalVNFID := cloudRegionID + "-" + namespace + "-" + externalVNFID
+
+	unlock := db.LockKey(internalVNFID)
+	defer unlock()
+
+	record, found, err := db.ReadVNFRecord(internalVNFID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if found == false {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if record.CsarID == "" {
+		http.Error(w, "VNF wasn't created from a CSAR", http.StatusUnprocessableEntity)
+		return
+	}
+
+	// The new workloads would start with the replicas of the CSAR
+	if record.State == db.VNFStateStopped {
+		http.Error(w, "Stopped VNFs can't be upgraded", http.StatusConflict)
+		return
+	}
+
+	to := csar.VNFRelease{
+		CsarID:     resource.CsarID,
+		Parameters: record.Parameters,
+		Overlay:    record.Overlay,
+	}
+	if resource.Parameters != nil {
+		to.Parameters = resource.Parameters
+	}
+	if resource.Overlay != "" {
+		to.Overlay = resource.Overlay
+	}
+
+	timeout := defaultUpgradeTimeout
+	if resource.Timeout != "" {
+		timeout, _ = time.ParseDuration(resource.Timeout)
+	}
+
+	kubeclient, err := GetVNFClient(os.Getenv("KUBE_CONFIG_DIR") + "/" + cloudRegionID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	unchanged, err := applyRelease(&record, to, externalVNFID, timeout, &kubeclient)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Upgrade VNF error")
+		http.Error(w, werr.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	err = db.WriteVNFRecord(internalVNFID, record)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Update VNF record error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	csar.RecordRevision(internalVNFID, externalVNFID, record, csar.OperationUpgrade)
+
+	resp := UpgradeVnfResponse{
+		VNFID:         externalVNFID,
+		CsarID:        record.CsarID,
+		VNFComponents: record.Resources,
+		Unchanged:     unchanged,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		werr := pkgerrors.Wrap(err, "Parsing output of VNF upgrade error")
+		http.Error(w, werr.Error(), http.StatusInternalServerError)
+	}
+}
+
+// applyRelease upgrades the objects of a VNF to a release and updates its
+// record accordingly. The objects the release couldn't update are returned.
+// An error is returned when the VNF was rolled back and its record left
+// unchanged.
+func applyRelease(record *db.VNFRecord, to csar.VNFRelease, externalVNFID string, timeout time.Duration,
+	kubeclient *kubernetes.Clientset) (map[string][]string, error) {
+
+	from := csar.VNFRelease{
+		CsarID:     record.CsarID,
+		Parameters: record.Parameters,
+		Overlay:    record.Overlay,
+	}
+
+	resources, unchanged, err := csar.UpgradeVNF(from, to, record.CloudRegionID, record.Namespace, externalVNFID,
+		record.Resources, record.StoragePolicy, timeout, kubeclient)
+	if resources == nil && err != nil {
+		return nil, err
+	}
+
+	// The VNF runs the new CSAR even if some of the removed objects couldn't
+	// be deleted, so they are kept in the record to be deleted with the VNF
+	if err != nil {
+		log.Println("Upgrade VNF " + externalVNFID + " error: " + err.Error())
+		resources = mergeResources(resources, record.Resources)
+	}
+
+	record.CsarID = to.CsarID
+	record.Parameters = to.Parameters
+	record.Overlay = to.Overlay
+	record.Resources = resources
+	for name := range record.Replicas {
+		if !containsString(resources["deployment"], name) && !containsString(resources["statefulset"], name) {
+			delete(record.Replicas, name)
+		}
+	}
+
+	return unchanged, nil
+}
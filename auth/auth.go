@@ -0,0 +1,172 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	pkgerrors "github.com/pkg/errors"
+)
+
+// Role grants access to a set of routes. Every role includes the ones before
+// it: read-only, operator, admin.
+type Role string
+
+// Roles of the API callers
+const (
+	RoleReadOnly Role = "read-only"
+	RoleOperator Role = "operator"
+	RoleAdmin    Role = "admin"
+)
+
+var roleLevels = map[Role]int{
+	RoleReadOnly: 1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// Valid tells whether the role is known
+func (r Role) Valid() bool {
+	_, ok := roleLevels[r]
+	return ok
+}
+
+// Includes tells whether the role grants the access of another role
+func (r Role) Includes(required Role) bool {
+	return roleLevels[r] >= roleLevels[required]
+}
+
+// Identity is an authenticated caller of the API. Callers with a tenant are
+// scoped to it.
+type Identity struct {
+	Name   string `json:"name"`
+	Role   Role   `json:"role"`
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// Authenticator finds the identity of a request. A nil identity without error
+// means the request has no credentials this authenticator understands.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Identity, error)
+}
+
+// Chain tries its authenticators in order and returns the first identity
+type Chain []Authenticator
+
+// Authenticate implements Authenticator
+func (c Chain) Authenticate(r *http.Request) (*Identity, error) {
+	for _, authenticator := range c {
+		identity, err := authenticator.Authenticate(r)
+		if err != nil || identity != nil {
+			return identity, err
+		}
+	}
+	return nil, nil
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying an identity
+func NewContext(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, identity)
+}
+
+// FromContext returns the identity of a context, if any
+func FromContext(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(contextKey{}).(*Identity)
+	return identity, ok && identity != nil
+}
+
+// bearerToken returns the token of the Authorization header, if any
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(header[7:])
+}
+
+// readYAML parses a YAML or JSON configuration file
+func readYAML(path string, out interface{}) error {
+	rawBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Read "+path+" error")
+	}
+
+	err = yaml.Unmarshal(rawBytes, out)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Parse "+path+" error")
+	}
+
+	return nil
+}
+
+// checkIdentity validates an identity read from a configuration file
+func checkIdentity(identity Identity, source string) error {
+	if identity.Name == "" {
+		return pkgerrors.New("Missing name in " + source)
+	}
+	if !identity.Role.Valid() {
+		return pkgerrors.New("Invalid role " + string(identity.Role) + " of " + identity.Name + " in " + source)
+	}
+	return nil
+}
+
+// NewFromEnv builds the authenticators configured by the environment:
+// AUTH_CLIENT_CERTS_FILE for client certificates, AUTH_TOKENS_FILE for static
+// bearer tokens and AUTH_JWKS_FILE for JWTs. A nil authenticator is returned
+// when none of them is set, which leaves the API unauthenticated.
+func NewFromEnv() (Authenticator, error) {
+	var chain Chain
+
+	if path := os.Getenv("AUTH_CLIENT_CERTS_FILE"); path != "" {
+		authenticator, err := NewCertAuthenticator(path)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, authenticator)
+	}
+
+	if path := os.Getenv("AUTH_TOKENS_FILE"); path != "" {
+		authenticator, err := NewTokenAuthenticator(path)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, authenticator)
+	}
+
+	if path := os.Getenv("AUTH_JWKS_FILE"); path != "" {
+		authenticator, err := NewJWTAuthenticator(path, JWTOptions{
+			Issuer:      os.Getenv("AUTH_JWT_ISSUER"),
+			Audience:    os.Getenv("AUTH_JWT_AUDIENCE"),
+			RoleClaim:   os.Getenv("AUTH_JWT_ROLE_CLAIM"),
+			TenantClaim: os.Getenv("AUTH_JWT_TENANT_CLAIM"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, authenticator)
+	}
+
+	if len(chain) == 0 {
+		return nil, nil
+	}
+
+	return chain, nil
+}
@@ -0,0 +1,268 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, content string) string {
+	file, err := ioutil.TempFile("", "auth")
+	if err != nil {
+		t.Fatalf("TempFile returned an error (%s)", err)
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(content)
+	if err != nil {
+		t.Fatalf("WriteString returned an error (%s)", err)
+	}
+
+	return file.Name()
+}
+
+func encodeSegment(t *testing.T, value interface{}) string {
+	rawBytes, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("Marshal returned an error (%s)", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(rawBytes)
+}
+
+func bearerRequest(token string) *http.Request {
+	req, _ := http.NewRequest("GET", "/v1/vnf_instances/cloud1/default", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+func TestTokenAuthenticator(t *testing.T) {
+	path := writeFile(t, `
+- token: s3cr3t
+  name: ci
+  role: operator
+  tenant: acme
+`)
+	defer os.Remove(path)
+
+	authenticator, err := NewTokenAuthenticator(path)
+	if err != nil {
+		t.Fatalf("NewTokenAuthenticator returned an error (%s)", err)
+	}
+
+	t.Run("Successfully authenticate a static token", func(t *testing.T) {
+		identity, err := authenticator.Authenticate(bearerRequest("s3cr3t"))
+		if err != nil || identity == nil {
+			t.Fatalf("Authenticate returned:\n result=%v %v\n expected=%v", identity, err, "ci")
+		}
+		if identity.Name != "ci" || identity.Role != RoleOperator || identity.Tenant != "acme" {
+			t.Fatalf("Authenticate returned:\n result=%v\n expected=%v", identity, "ci")
+		}
+	})
+	t.Run("Unknown token", func(t *testing.T) {
+		identity, err := authenticator.Authenticate(bearerRequest("other"))
+		if err != nil || identity != nil {
+			t.Fatalf("Authenticate returned:\n result=%v %v\n expected=%v", identity, err, nil)
+		}
+	})
+	t.Run("Invalid role failure", func(t *testing.T) {
+		path := writeFile(t, `[{"token": "s3cr3t", "name": "ci", "role": "root"}]`)
+		defer os.Remove(path)
+
+		_, err := NewTokenAuthenticator(path)
+		if err == nil {
+			t.Fatalf("NewTokenAuthenticator didn't return an error for an invalid role")
+		}
+	})
+}
+
+func TestRoleIncludes(t *testing.T) {
+	if !RoleAdmin.Includes(RoleOperator) || !RoleOperator.Includes(RoleReadOnly) {
+		t.Fatalf("Higher roles don't include the lower ones")
+	}
+	if RoleReadOnly.Includes(RoleOperator) || Role("").Includes(RoleReadOnly) {
+		t.Fatalf("Lower roles include the higher ones")
+	}
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey returned an error (%s)", err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey returned an error (%s)", err)
+	}
+
+	encode := func(i *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(i.Bytes())
+	}
+	jwks := map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa1", "n": encode(rsaKey.N), "e": encode(big.NewInt(int64(rsaKey.E)))},
+			{"kty": "EC", "kid": "ec1", "crv": "P-256", "x": encode(ecKey.X), "y": encode(ecKey.Y)},
+		},
+	}
+	rawBytes, _ := json.Marshal(jwks)
+	path := writeFile(t, string(rawBytes))
+	defer os.Remove(path)
+
+	authenticator, err := NewJWTAuthenticator(path, JWTOptions{Issuer: "idp", Audience: "k8plugin"})
+	if err != nil {
+		t.Fatalf("NewJWTAuthenticator returned an error (%s)", err)
+	}
+
+	sign := func(alg string, kid string, claims map[string]interface{}) string {
+		signed := encodeSegment(t, map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." +
+			encodeSegment(t, claims)
+		digest := sha256.Sum256([]byte(signed))
+
+		var signature []byte
+		switch alg {
+		case "RS256":
+			signature, err = rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+		case "ES256":
+			var r, s *big.Int
+			r, s, err = ecdsa.Sign(rand.Reader, ecKey, digest[:])
+			signature = make([]byte, 64)
+			copy(signature[32-len(r.Bytes()):32], r.Bytes())
+			copy(signature[64-len(s.Bytes()):], s.Bytes())
+		}
+		if err != nil {
+			t.Fatalf("Sign returned an error (%s)", err)
+		}
+
+		return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+	}
+
+	claims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"sub":    "alice",
+			"iss":    "idp",
+			"aud":    []string{"k8plugin"},
+			"exp":    time.Now().Add(time.Hour).Unix(),
+			"role":   []string{"read-only", "operator"},
+			"tenant": "acme",
+		}
+	}
+
+	t.Run("Successfully authenticate RS256 and ES256 JWTs", func(t *testing.T) {
+		for _, alg := range []string{"RS256", "ES256"} {
+			kid := map[string]string{"RS256": "rsa1", "ES256": "ec1"}[alg]
+			identity, err := authenticator.Authenticate(bearerRequest(sign(alg, kid, claims())))
+			if err != nil || identity == nil {
+				t.Fatalf("Authenticate returned:\n result=%v %v\n expected=%v", identity, err, "alice")
+			}
+			expected := Identity{Name: "alice", Role: RoleOperator, Tenant: "acme"}
+			if *identity != expected {
+				t.Fatalf("Authenticate returned:\n result=%v\n expected=%v", *identity, expected)
+			}
+		}
+	})
+	t.Run("Token which isn't a JWT", func(t *testing.T) {
+		identity, err := authenticator.Authenticate(bearerRequest("s3cr3t"))
+		if err != nil || identity != nil {
+			t.Fatalf("Authenticate returned:\n result=%v %v\n expected=%v", identity, err, nil)
+		}
+	})
+	t.Run("Invalid JWTs failure", func(t *testing.T) {
+		expired := claims()
+		expired["exp"] = time.Now().Add(-time.Hour).Unix()
+
+		otherAudience := claims()
+		otherAudience["aud"] = "other"
+
+		noRole := claims()
+		delete(noRole, "role")
+
+		valid := sign("RS256", "rsa1", claims())
+		tampered := valid[:len(valid)-4] + "AAAA"
+
+		testCases := map[string]string{
+			"expired":        sign("RS256", "rsa1", expired),
+			"other audience": sign("RS256", "rsa1", otherAudience),
+			"no role":        sign("RS256", "rsa1", noRole),
+			"tampered":       tampered,
+			"wrong key":      sign("RS256", "ec1", claims()),
+			"none algorithm": encodeSegment(t, map[string]string{"alg": "none"}) + "." + encodeSegment(t, claims()) + ".",
+		}
+
+		for label, token := range testCases {
+			identity, err := authenticator.Authenticate(bearerRequest(token))
+			if err == nil {
+				t.Fatalf("Authenticate didn't return an error for the %s JWT: %v", label, identity)
+			}
+		}
+	})
+}
+
+func TestCertAuthenticator(t *testing.T) {
+	path := writeFile(t, `
+- common_name: ci.example.com
+  name: ci
+  role: operator
+`)
+	defer os.Remove(path)
+
+	certs, err := NewCertAuthenticator(path)
+	if err != nil {
+		t.Fatalf("NewCertAuthenticator returned an error (%s)", err)
+	}
+
+	tokensPath := writeFile(t, `[{"token": "s3cr3t", "name": "admin", "role": "admin"}]`)
+	defer os.Remove(tokensPath)
+
+	tokens, err := NewTokenAuthenticator(tokensPath)
+	if err != nil {
+		t.Fatalf("NewTokenAuthenticator returned an error (%s)", err)
+	}
+
+	certRequest := func(commonName string, token string) *http.Request {
+		req := bearerRequest(token)
+		req.TLS = &tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: commonName}}}},
+		}
+		return req
+	}
+
+	t.Run("Successfully authenticate a client certificate", func(t *testing.T) {
+		identity, err := Chain{certs, tokens}.Authenticate(certRequest("ci.example.com", ""))
+		if err != nil || identity == nil || identity.Name != "ci" {
+			t.Fatalf("Authenticate returned:\n result=%v %v\n expected=%v", identity, err, "ci")
+		}
+	})
+	t.Run("Successfully authenticate a token along with an unknown certificate", func(t *testing.T) {
+		identity, err := Chain{certs, tokens}.Authenticate(certRequest("other.example.com", "s3cr3t"))
+		if err != nil || identity == nil || identity.Name != "admin" {
+			t.Fatalf("Authenticate returned:\n result=%v %v\n expected=%v", identity, err, "admin")
+		}
+	})
+}
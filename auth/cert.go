@@ -0,0 +1,75 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"net/http"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// ClientCert maps the common name of a client certificate of
+// AUTH_CLIENT_CERTS_FILE to an identity
+type ClientCert struct {
+	CommonName string `json:"common_name"`
+	Identity
+}
+
+// CertAuthenticator authenticates the client certificates verified by the
+// TLS server against its client CA
+type CertAuthenticator struct {
+	certs map[string]Identity
+}
+
+// NewCertAuthenticator reads the list of client certificates from a file
+func NewCertAuthenticator(path string) (*CertAuthenticator, error) {
+	var certs []ClientCert
+
+	err := readYAML(path, &certs)
+	if err != nil {
+		return nil, err
+	}
+
+	authenticator := &CertAuthenticator{certs: make(map[string]Identity)}
+	for _, cert := range certs {
+		if cert.CommonName == "" {
+			return nil, pkgerrors.New("Missing common_name of " + cert.Name + " in " + path)
+		}
+		err = checkIdentity(cert.Identity, path)
+		if err != nil {
+			return nil, err
+		}
+		authenticator.certs[cert.CommonName] = cert.Identity
+	}
+
+	return authenticator, nil
+}
+
+// Authenticate implements Authenticator. Only the certificates with a
+// verified chain are considered. Certificates whose common name isn't listed
+// are ignored, so that the next authenticators can still identify the caller,
+// e.g. by a bearer token.
+func (a *CertAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, nil
+	}
+
+	commonName := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	identity, ok := a.certs[commonName]
+	if !ok {
+		return nil, nil
+	}
+
+	return &identity, nil
+}
@@ -0,0 +1,345 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	// Register the hashes of the signing algorithms
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// clockSkew is tolerated on the expiry and not before times of a JWT
+const clockSkew = time.Minute
+
+// JWTOptions are the claims checked on a JWT besides its signature and expiry
+type JWTOptions struct {
+	// Issuer and Audience must match the iss and aud claims when set
+	Issuer   string
+	Audience string
+
+	// RoleClaim and TenantClaim name the claims holding the role and tenant
+	// of the caller, "role" and "tenant" by default
+	RoleClaim   string
+	TenantClaim string
+}
+
+// jwtAlgorithms maps the supported signing algorithms to their key type and
+// hash. HMAC and "none" are deliberately left out.
+var jwtAlgorithms = map[string]struct {
+	keyType string
+	hash    crypto.Hash
+}{
+	"RS256": {"RSA", crypto.SHA256},
+	"RS384": {"RSA", crypto.SHA384},
+	"RS512": {"RSA", crypto.SHA512},
+	"PS256": {"RSA", crypto.SHA256},
+	"PS384": {"RSA", crypto.SHA384},
+	"PS512": {"RSA", crypto.SHA512},
+	"ES256": {"EC", crypto.SHA256},
+	"ES384": {"EC", crypto.SHA384},
+	"ES512": {"EC", crypto.SHA512},
+}
+
+var curves = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+	"P-521": elliptic.P521(),
+}
+
+// jsonWebKey is a key of a JWKS file, as defined by RFC 7517
+type jsonWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+}
+
+// verificationKey is a public key read from a JWKS file
+type verificationKey struct {
+	id        string
+	keyType   string
+	algorithm string
+	key       crypto.PublicKey
+}
+
+// JWTAuthenticator authenticates the bearer JWTs signed by a key of a local
+// JWKS file
+type JWTAuthenticator struct {
+	keys    []verificationKey
+	options JWTOptions
+}
+
+// NewJWTAuthenticator reads the public keys of a JWKS file
+func NewJWTAuthenticator(path string, options JWTOptions) (*JWTAuthenticator, error) {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+
+	err := readYAML(path, &jwks)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.RoleClaim == "" {
+		options.RoleClaim = "role"
+	}
+	if options.TenantClaim == "" {
+		options.TenantClaim = "tenant"
+	}
+
+	authenticator := &JWTAuthenticator{options: options}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := parseJSONWebKey(jwk)
+		if err != nil {
+			return nil, pkgerrors.Wrap(err, "Parse key "+jwk.KeyID+" of "+path+" error")
+		}
+
+		authenticator.keys = append(authenticator.keys, verificationKey{
+			id:        jwk.KeyID,
+			keyType:   jwk.KeyType,
+			algorithm: jwk.Algorithm,
+			key:       key,
+		})
+	}
+
+	if len(authenticator.keys) == 0 {
+		return nil, pkgerrors.New("No signing key in " + path)
+	}
+
+	return authenticator, nil
+}
+
+// parseJSONWebKey returns the RSA or EC public key of a JWK
+func parseJSONWebKey(jwk jsonWebKey) (crypto.PublicKey, error) {
+	switch jwk.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, pkgerrors.New("Invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curve, ok := curves[jwk.Curve]
+		if !ok {
+			return nil, pkgerrors.New("Unsupported curve " + jwk.Curve)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, pkgerrors.New("Point not on curve " + jwk.Curve)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, pkgerrors.New("Unsupported key type " + jwk.KeyType)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	rawBytes, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(rawBytes) == 0 {
+		return nil, pkgerrors.New("Invalid key parameter")
+	}
+	return new(big.Int).SetBytes(rawBytes), nil
+}
+
+// Authenticate implements Authenticator. Bearer tokens which aren't JWTs are
+// left to the next authenticators.
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	token := bearerToken(r)
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, nil
+	}
+
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	err := decodeSegment(parts[0], &header)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Invalid JWT header")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, pkgerrors.New("Invalid JWT signature")
+	}
+
+	err = a.verify(header.Algorithm, header.KeyID, parts[0]+"."+parts[1], signature)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	err = decodeSegment(parts[1], &claims)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Invalid JWT claims")
+	}
+
+	return a.identity(claims, time.Now())
+}
+
+func decodeSegment(segment string, out interface{}) error {
+	rawBytes, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(rawBytes, out)
+}
+
+// verify checks the signature of a JWT with the keys allowed for its
+// algorithm and key ID
+func (a *JWTAuthenticator) verify(algorithm string, keyID string, signed string, signature []byte) error {
+	spec, ok := jwtAlgorithms[algorithm]
+	if !ok {
+		return pkgerrors.New("Unsupported JWT algorithm " + algorithm)
+	}
+
+	hasher := spec.hash.New()
+	hasher.Write([]byte(signed))
+	digest := hasher.Sum(nil)
+
+	for _, key := range a.keys {
+		if key.keyType != spec.keyType || (keyID != "" && key.id != keyID) ||
+			(key.algorithm != "" && key.algorithm != algorithm) {
+			continue
+		}
+
+		switch publicKey := key.key.(type) {
+		case *rsa.PublicKey:
+			if strings.HasPrefix(algorithm, "PS") {
+				err := rsa.VerifyPSS(publicKey, spec.hash, digest, signature, nil)
+				if err == nil {
+					return nil
+				}
+			} else if rsa.VerifyPKCS1v15(publicKey, spec.hash, digest, signature) == nil {
+				return nil
+			}
+		case *ecdsa.PublicKey:
+			size := (publicKey.Curve.Params().BitSize + 7) / 8
+			if len(signature) != 2*size {
+				continue
+			}
+			r := new(big.Int).SetBytes(signature[:size])
+			s := new(big.Int).SetBytes(signature[size:])
+			if ecdsa.Verify(publicKey, digest, r, s) {
+				return nil
+			}
+		}
+	}
+
+	return pkgerrors.New("Invalid JWT signature")
+}
+
+// identity checks the registered claims of a JWT and returns the identity
+// of its subject
+func (a *JWTAuthenticator) identity(claims map[string]interface{}, now time.Time) (*Identity, error) {
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, pkgerrors.New("JWT without expiry")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(clockSkew)) {
+		return nil, pkgerrors.New("JWT expired")
+	}
+
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(clockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return nil, pkgerrors.New("JWT not valid yet")
+	}
+
+	if a.options.Issuer != "" && claims["iss"] != a.options.Issuer {
+		return nil, pkgerrors.New("Invalid JWT issuer")
+	}
+
+	if a.options.Audience != "" && !containsString(claims["aud"], a.options.Audience) {
+		return nil, pkgerrors.New("Invalid JWT audience")
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, pkgerrors.New("JWT without subject")
+	}
+
+	// The highest of the roles is kept when the claim holds several
+	var role Role
+	switch value := claims[a.options.RoleClaim].(type) {
+	case string:
+		role = Role(value)
+	case []interface{}:
+		for _, item := range value {
+			if name, ok := item.(string); ok && Role(name).Valid() && !role.Includes(Role(name)) {
+				role = Role(name)
+			}
+		}
+	}
+	if !role.Valid() {
+		return nil, pkgerrors.New("JWT without a valid " + a.options.RoleClaim + " claim")
+	}
+
+	tenant, _ := claims[a.options.TenantClaim].(string)
+
+	return &Identity{
+		Name:   subject,
+		Role:   role,
+		Tenant: tenant,
+	}, nil
+}
+
+// containsString tells whether a string or list claim holds a value
+func containsString(claim interface{}, value string) bool {
+	switch claim := claim.(type) {
+	case string:
+		return claim == value
+	case []interface{}:
+		for _, item := range claim {
+			if item == value {
+				return true
+			}
+		}
+	}
+	return false
+}
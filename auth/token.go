@@ -0,0 +1,74 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// StaticToken is a bearer token of AUTH_TOKENS_FILE and the identity it
+// authenticates
+type StaticToken struct {
+	Token string `json:"token"`
+	Identity
+}
+
+// TokenAuthenticator authenticates the bearer tokens of a static list
+type TokenAuthenticator struct {
+	tokens []StaticToken
+}
+
+// NewTokenAuthenticator reads the list of static tokens from a file
+func NewTokenAuthenticator(path string) (*TokenAuthenticator, error) {
+	var tokens []StaticToken
+
+	err := readYAML(path, &tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, token := range tokens {
+		if token.Token == "" {
+			return nil, pkgerrors.New("Missing token of " + token.Name + " in " + path)
+		}
+		err = checkIdentity(token.Identity, path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &TokenAuthenticator{tokens: tokens}, nil
+}
+
+// Authenticate implements Authenticator. Unknown tokens are left to the
+// next authenticators.
+func (a *TokenAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return nil, nil
+	}
+
+	// Every token is compared so that the timing doesn't tell which matched
+	var identity *Identity
+	for i := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(a.tokens[i].Token), []byte(token)) == 1 {
+			identity = &a.tokens[i].Identity
+		}
+	}
+
+	return identity, nil
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8-plugin-multicloud/api"
+	"k8-plugin-multicloud/csar"
+)
+
+const csarUsage = `Usage: k8plugin csar <command> [arguments]
+
+Commands:
+  validate <dir|archive>            check a CSAR without contacting any cluster
+  render [flags] <dir|archive>      print the manifests a VNF would be created with
+
+Render flags:
+  -cloud-region, -namespace, -vnf-id, -csar-id, -overlay, -set name=value (repeatable)
+`
+
+// runCSARCommand runs a csar subcommand and returns the process exit code
+func runCSARCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, csarUsage)
+		return 2
+	}
+
+	// Keep the standard output for the command results
+	log.SetOutput(ioutil.Discard)
+
+	switch args[0] {
+	case "validate":
+		return validateCSAR(args[1:])
+	case "render":
+		return renderCSAR(args[1:])
+	default:
+		fmt.Fprint(os.Stderr, csarUsage)
+		return 2
+	}
+}
+
+// openCSAR returns the directory of a CSAR, extracting it first when it is
+// an archive, and a function removing the extracted files
+func openCSAR(path string) (string, func(), error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", nil, err
+	}
+	if info.IsDir() {
+		return path, func() {}, nil
+	}
+
+	dir, err := csar.ExtractArchive(path)
+	if err != nil {
+		return "", nil, err
+	}
+	return dir, func() { os.RemoveAll(dir) }, nil
+}
+
+func validateCSAR(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprint(os.Stderr, csarUsage)
+		return 2
+	}
+
+	dir, cleanup, err := openCSAR(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer cleanup()
+
+	report := csar.ValidateCSAR(dir)
+	report.Path = args[0]
+
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	fmt.Println(string(out))
+
+	if !report.Valid {
+		return 1
+	}
+	return 0
+}
+
+// parameterFlags collects the repeated -set name=value flags
+type parameterFlags map[string]string
+
+func (p parameterFlags) String() string {
+	var values []string
+	for name, value := range p {
+		values = append(values, name+"="+value)
+	}
+	return strings.Join(values, ",")
+}
+
+func (p parameterFlags) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("invalid parameter %q, expected name=value", value)
+	}
+	p[parts[0]] = parts[1]
+	return nil
+}
+
+func renderCSAR(args []string) int {
+	parameters := make(parameterFlags)
+
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	cloudRegionID := flags.String("cloud-region", "cloud1", "cloud region the VNF is rendered for")
+	namespace := flags.String("namespace", "default", "namespace the VNF is rendered for")
+	externalVNFID := flags.String("vnf-id", "", "VNF ID used in the object names, generated when empty")
+	csarID := flags.String("csar-id", "", "CSAR ID recorded in the annotations, defaults to the CSAR file name")
+	overlay := flags.String("overlay", "", "kustomization overlay, defaults to the one of the cloud region")
+	flags.Var(parameters, "set", "parameter value as name=value")
+
+	err := flags.Parse(args)
+	if err != nil || flags.NArg() != 1 {
+		fmt.Fprint(os.Stderr, csarUsage)
+		return 2
+	}
+
+	path := flags.Arg(0)
+	if *csarID == "" {
+		*csarID = filepath.Base(path)
+	}
+
+	err = api.LoadPlugins()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	dir, cleanup, err := openCSAR(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer cleanup()
+
+	manifests, err := csar.RenderVNF(dir, *csarID, *cloudRegionID, *namespace, *externalVNFID, parameters, *overlay)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Print(manifests)
+	return 0
+}
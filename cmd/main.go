@@ -19,16 +19,30 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/gorilla/handlers"
 	"k8s.io/client-go/util/homedir"
 
 	"k8-plugin-multicloud/api"
+	"k8-plugin-multicloud/gc"
+	"k8-plugin-multicloud/reconcile"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "csar" {
+		os.Exit(runCSARCommand(os.Args[2:]))
+	}
+
 	var kubeconfig string
 
+	// Every flag defaults to its environment variable
+	listenAddress := flag.String("listen", envOrDefault("LISTEN_ADDRESS", ":8081"), "address the API is served on")
+	healthAddress := flag.String("health-listen", os.Getenv("HEALTH_LISTEN_ADDRESS"), "(optional) plain HTTP address of the /healthz check")
+	certFile := flag.String("tls-cert", os.Getenv("TLS_CERT_FILE"), "(optional) TLS certificate file, reloaded when it changes")
+	keyFile := flag.String("tls-key", os.Getenv("TLS_KEY_FILE"), "(optional) TLS private key file")
+	clientCAFile := flag.String("tls-client-ca", os.Getenv("TLS_CLIENT_CA_FILE"), "(optional) CA file verifying the TLS client certificates")
+
 	home := homedir.HomeDir()
 	if home != "" {
 		kubeconfig = *flag.String("kubeconfig", filepath.Join(home, ".kube", "config"), "(optional) absolute path to the kubeconfig file")
@@ -40,8 +54,76 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// RECONCILE_INTERVAL enables the periodic drift detection of the stored
+	// VNFs, e.g. "5m". RECONCILE_RECREATE=true recreates the missing objects.
+	if value, ok := os.LookupEnv("RECONCILE_INTERVAL"); ok {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			log.Fatal("Invalid RECONCILE_INTERVAL value: " + value)
+		}
+		reconcile.Start(interval, os.Getenv("RECONCILE_RECREATE") == "true")
+	}
+
+	// GC_GRACE_PERIOD is how long orphaned resources are reported before
+	// being deleted, periodically only when set
+	gcGracePeriod, deleteOrphans := os.LookupEnv("GC_GRACE_PERIOD")
+	if deleteOrphans {
+		gracePeriod, err := time.ParseDuration(gcGracePeriod)
+		if err != nil || gracePeriod <= 0 {
+			log.Fatal("Invalid GC_GRACE_PERIOD value: " + gcGracePeriod)
+		}
+		gc.GracePeriod = gracePeriod
+	}
+
+	// GC_INTERVAL enables the periodic scan of orphaned resources
+	if value, ok := os.LookupEnv("GC_INTERVAL"); ok {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			log.Fatal("Invalid GC_INTERVAL value: " + value)
+		}
+		gc.Start(interval, deleteOrphans)
+	}
+
+	if *healthAddress != "" {
+		healthRouter := http.NewServeMux()
+		healthRouter.HandleFunc("/healthz", api.HealthHandler)
+		go func() {
+			log.Println("Starting health check on " + *healthAddress)
+			log.Fatal(http.ListenAndServe(*healthAddress, healthRouter))
+		}()
+	}
+
 	router := api.NewRouter(kubeconfig)
 	loggedRouter := handlers.LoggingHandler(os.Stdout, router)
-	log.Println("Starting Kubernetes Multicloud API")
-	log.Fatal(http.ListenAndServe(":8081", loggedRouter)) // Remove hardcode.
+	server := &http.Server{
+		Addr:    *listenAddress,
+		Handler: loggedRouter,
+	}
+
+	useTLS, err := checkTLSFlags(*certFile, *keyFile, *clientCAFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if !useTLS {
+		log.Println("Starting Kubernetes Multicloud API on " + *listenAddress)
+		log.Fatal(server.ListenAndServe())
+	}
+
+	server.TLSConfig, err = newTLSConfig(*certFile, *keyFile, *clientCAFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Println("Starting Kubernetes Multicloud API with TLS on " + *listenAddress)
+	log.Fatal(server.ListenAndServeTLS("", ""))
+}
+
+// envOrDefault returns the value of an environment variable, or a default
+// value when it isn't set
+func envOrDefault(name string, value string) string {
+	if env, ok := os.LookupEnv(name); ok {
+		return env
+	}
+	return value
 }
@@ -0,0 +1,194 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// tlsReloadInterval is how often the TLS files are checked for changes
+const tlsReloadInterval = 30 * time.Second
+
+// tlsReloader serves the TLS certificate and client CA of the API server and
+// loads them again whenever their files change, so that rotated certificates
+// are used without a restart
+type tlsReloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+
+	// configuration served to the clients, swapped as a whole on reload
+	config atomic.Value
+
+	// only used by the watch goroutine once started
+	modTime time.Time
+}
+
+func newTLSReloader(certFile string, keyFile string, clientCAFile string) (*tlsReloader, error) {
+	reloader := &tlsReloader{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
+	}
+
+	modTime, err := reloader.lastModified()
+	if err != nil {
+		return nil, err
+	}
+
+	err = reloader.load(modTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return reloader, nil
+}
+
+// lastModified returns the latest modification time of the TLS files
+func (r *tlsReloader) lastModified() (time.Time, error) {
+	var modTime time.Time
+
+	for _, path := range []string{r.certFile, r.keyFile, r.clientCAFile} {
+		if path == "" {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return modTime, pkgerrors.Wrap(err, "Read TLS file error")
+		}
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+
+	return modTime, nil
+}
+
+// load reads the TLS files and swaps the configuration served to the clients.
+// Client certificates are verified against the client CA when one is given,
+// but aren't required so that callers can use bearer tokens too.
+func (r *tlsReloader) load(modTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Load TLS certificate error")
+	}
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+
+	if r.clientCAFile != "" {
+		rawBytes, err := ioutil.ReadFile(r.clientCAFile)
+		if err != nil {
+			return pkgerrors.Wrap(err, "Read TLS client CA error")
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(rawBytes) {
+			return pkgerrors.New("No certificate in TLS client CA " + r.clientCAFile)
+		}
+
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	r.config.Store(config)
+	r.modTime = modTime
+	return nil
+}
+
+// watch checks the TLS files every interval and loads them again when they
+// changed. The previous configuration is kept while the new files can't be
+// loaded, e.g. when only some of them have been replaced yet.
+func (r *tlsReloader) watch(interval time.Duration) {
+	for range time.Tick(interval) {
+		err := r.reload()
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// reload loads the TLS files again when they changed since the last load
+func (r *tlsReloader) reload() error {
+	modTime, err := r.lastModified()
+	if err != nil || modTime.Equal(r.modTime) {
+		return err
+	}
+
+	err = r.load(modTime)
+	if err != nil {
+		return err
+	}
+
+	log.Println("Reloaded TLS certificate " + r.certFile)
+	return nil
+}
+
+// GetConfigForClient is used as tls.Config.GetConfigForClient
+func (r *tlsReloader) GetConfigForClient(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+	return r.config.Load().(*tls.Config), nil
+}
+
+// GetCertificate is used as tls.Config.GetCertificate
+func (r *tlsReloader) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return &r.config.Load().(*tls.Config).Certificates[0], nil
+}
+
+// checkTLSFlags reports whether the API is served with TLS. A certificate
+// and a key are both required for it, and a client CA can only be used along
+// with them.
+func checkTLSFlags(certFile string, keyFile string, clientCAFile string) (bool, error) {
+	if certFile == "" && keyFile == "" {
+		if clientCAFile != "" {
+			return false, pkgerrors.New("A TLS client CA requires a TLS certificate and key")
+		}
+		return false, nil
+	}
+
+	if certFile == "" || keyFile == "" {
+		return false, pkgerrors.New("Both a TLS certificate and key are required")
+	}
+
+	return true, nil
+}
+
+// newTLSConfig returns the TLS configuration of the API server, whose
+// certificate and client CA are reloaded in the background
+func newTLSConfig(certFile string, keyFile string, clientCAFile string) (*tls.Config, error) {
+	reloader, err := newTLSReloader(certFile, keyFile, clientCAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	go reloader.watch(tlsReloadInterval)
+
+	// The server requires a certificate in its own configuration too
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		GetCertificate:     reloader.GetCertificate,
+		GetConfigForClient: reloader.GetConfigForClient,
+	}, nil
+}
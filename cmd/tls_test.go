@@ -0,0 +1,216 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCertificate writes a self-signed certificate and its key with a
+// modification time, and returns the certificate
+func writeCertificate(t *testing.T, certFile string, keyFile string, commonName string, modTime time.Time) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("writeCertificate returned an error (%s)", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("writeCertificate returned an error (%s)", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("writeCertificate returned an error (%s)", err)
+	}
+
+	files := map[string][]byte{
+		certFile: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyFile:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+	for path, content := range files {
+		writeFile(t, path, content, modTime)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("writeCertificate returned an error (%s)", err)
+	}
+	return cert
+}
+
+// writeFile writes a file with a modification time, as files rewritten in a
+// row can get the same one
+func writeFile(t *testing.T, path string, content []byte, modTime time.Time) {
+	err := ioutil.WriteFile(path, content, 0600)
+	if err != nil {
+		t.Fatalf("writeFile returned an error (%s)", err)
+	}
+	err = os.Chtimes(path, modTime, modTime)
+	if err != nil {
+		t.Fatalf("writeFile returned an error (%s)", err)
+	}
+}
+
+// servedCommonName returns the common name of the certificate served by a
+// reloader
+func servedCommonName(t *testing.T, reloader *tlsReloader) string {
+	cert, err := reloader.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatalf("GetCertificate returned an error (%s)", err)
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("GetCertificate returned an invalid certificate (%s)", err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestTLSReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatalf("TestTLSReloader returned an error (%s)", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	clientCAFile := filepath.Join(dir, "ca.crt")
+	modTime := time.Now().Add(-time.Hour)
+
+	writeCertificate(t, certFile, keyFile, "first", modTime)
+	ca := writeCertificate(t, filepath.Join(dir, "ca.pem"), filepath.Join(dir, "ca.key"), "client-ca", modTime)
+	writeFile(t, clientCAFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}), modTime)
+
+	reloader, err := newTLSReloader(certFile, keyFile, clientCAFile)
+	if err != nil {
+		t.Fatalf("TestTLSReloader returned an error (%s)", err)
+	}
+
+	t.Run("Serve the certificate and verify the client certificates", func(t *testing.T) {
+		if name := servedCommonName(t, reloader); name != "first" {
+			t.Fatalf("TestTLSReloader served the %s certificate", name)
+		}
+
+		config, err := reloader.GetConfigForClient(&tls.ClientHelloInfo{})
+		if err != nil {
+			t.Fatalf("TestTLSReloader returned an error (%s)", err)
+		}
+		if config.ClientAuth != tls.VerifyClientCertIfGiven || config.ClientCAs == nil {
+			t.Fatalf("TestTLSReloader doesn't verify the client certificates (%v)", config.ClientAuth)
+		}
+		if len(config.ClientCAs.Subjects()) != 1 {
+			t.Fatalf("TestTLSReloader returned unexpected client CAs (%d)", len(config.ClientCAs.Subjects()))
+		}
+	})
+
+	t.Run("Keep the certificate while the files are unchanged", func(t *testing.T) {
+		err := reloader.reload()
+		if err != nil {
+			t.Fatalf("TestTLSReloader returned an error (%s)", err)
+		}
+		if name := servedCommonName(t, reloader); name != "first" {
+			t.Fatalf("TestTLSReloader served the %s certificate", name)
+		}
+	})
+
+	t.Run("Serve a rotated certificate", func(t *testing.T) {
+		modTime = modTime.Add(time.Minute)
+		writeCertificate(t, certFile, keyFile, "second", modTime)
+
+		err := reloader.reload()
+		if err != nil {
+			t.Fatalf("TestTLSReloader returned an error (%s)", err)
+		}
+		if name := servedCommonName(t, reloader); name != "second" {
+			t.Fatalf("TestTLSReloader served the %s certificate", name)
+		}
+	})
+
+	t.Run("Keep the certificate when its replacement is invalid", func(t *testing.T) {
+		modTime = modTime.Add(time.Minute)
+		writeFile(t, certFile, []byte("not a certificate"), modTime)
+
+		err := reloader.reload()
+		if err == nil {
+			t.Fatalf("TestTLSReloader didn't return an error for an invalid certificate")
+		}
+		if name := servedCommonName(t, reloader); name != "second" {
+			t.Fatalf("TestTLSReloader served the %s certificate", name)
+		}
+	})
+
+	t.Run("Keep the certificate when the client CA is invalid", func(t *testing.T) {
+		modTime = modTime.Add(time.Minute)
+		writeCertificate(t, certFile, keyFile, "third", modTime)
+		writeFile(t, clientCAFile, []byte("not a certificate"), modTime)
+
+		err := reloader.reload()
+		if err == nil {
+			t.Fatalf("TestTLSReloader didn't return an error for an invalid client CA")
+		}
+		if name := servedCommonName(t, reloader); name != "second" {
+			t.Fatalf("TestTLSReloader served the %s certificate", name)
+		}
+	})
+}
+
+func TestCheckTLSFlags(t *testing.T) {
+	testCases := []struct {
+		label        string
+		certFile     string
+		keyFile      string
+		clientCAFile string
+		useTLS       bool
+		valid        bool
+	}{
+		{"Plain HTTP", "", "", "", false, true},
+		{"TLS", "tls.crt", "tls.key", "", true, true},
+		{"TLS with a client CA", "tls.crt", "tls.key", "ca.crt", true, true},
+		{"Certificate without key", "tls.crt", "", "", false, false},
+		{"Key without certificate", "", "tls.key", "", false, false},
+		{"Client CA without certificate and key", "", "", "ca.crt", false, false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.label, func(t *testing.T) {
+			useTLS, err := checkTLSFlags(testCase.certFile, testCase.keyFile, testCase.clientCAFile)
+			if (err == nil) != testCase.valid || useTLS != testCase.useTLS {
+				t.Fatalf("TestCheckTLSFlags returned unexpected result (%v, %v)", useTLS, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csar
+
+import (
+	"log"
+
+	pkgerrors "github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+
+	"k8-plugin-multicloud/krd"
+)
+
+// LabelVNF stamps the VNF ownership metadata on existing objects, so objects
+// adopted by a VNF can be discovered like the ones it created
+var LabelVNF = func(data map[string][]string, csarID string, cloudRegionID string, namespace string, externalVNFID string,
+	kubeclient *kubernetes.Clientset) error {
+
+	kubedata := &krd.GenericKubeResourceData{
+		Namespace:     namespace,
+		InternalVNFID: cloudRegionID + "-" + namespace + "-" + externalVNFID,
+		ExternalVNFID: externalVNFID,
+		CloudRegionID: cloudRegionID,
+		CsarID:        csarID,
+	}
+
+	for resourceName, resourceList := range data {
+		typePlugin, ok := krd.LoadedPlugins[resourceName]
+		if !ok {
+			return pkgerrors.New("No plugin for resource " + resourceName + " found")
+		}
+
+		symLabelResourceFunc, err := typePlugin.Lookup("LabelResource")
+		if err != nil {
+			return pkgerrors.Wrap(err, "Error fetching "+resourceName+" plugin")
+		}
+
+		for _, name := range resourceList {
+			log.Println("Labelling resource: " + name)
+
+			err = symLabelResourceFunc.(func(string, string, *krd.GenericKubeResourceData, *kubernetes.Clientset) error)(
+				name, namespace, kubedata, kubeclient)
+			if err != nil {
+				return pkgerrors.Wrap(err, "Error labelling "+name)
+			}
+		}
+	}
+
+	return nil
+}
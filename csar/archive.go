@@ -0,0 +1,164 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csar
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// ExtractArchive unpacks a CSAR packaged as a .zip, .csar, .tar, .tar.gz or
+// .tgz file into a temporary directory, which the caller must remove
+func ExtractArchive(archivePath string) (string, error) {
+	dir, err := ioutil.TempDir("", "csar")
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Create CSAR directory error")
+	}
+
+	switch {
+	case strings.HasSuffix(archivePath, ".zip"), strings.HasSuffix(archivePath, ".csar"):
+		err = extractZip(archivePath, dir)
+	case strings.HasSuffix(archivePath, ".tar.gz"), strings.HasSuffix(archivePath, ".tgz"):
+		err = extractTar(archivePath, dir, true)
+	case strings.HasSuffix(archivePath, ".tar"):
+		err = extractTar(archivePath, dir, false)
+	default:
+		err = pkgerrors.New("Unsupported CSAR archive format: " + archivePath)
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+
+	return dir, nil
+}
+
+// archiveTarget returns the path an archive entry is extracted to, rejecting
+// entries outside of the destination directory
+func archiveTarget(dir string, name string) (string, error) {
+	target := filepath.Join(dir, name)
+	if target != dir && !strings.HasPrefix(target, dir+string(os.PathSeparator)) {
+		return "", pkgerrors.New("Invalid path in CSAR archive: " + name)
+	}
+	return target, nil
+}
+
+func writeArchiveFile(target string, r io.Reader) error {
+	err := os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Extract CSAR archive error")
+	}
+
+	out, err := os.Create(target)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Extract CSAR archive error")
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, r)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Extract CSAR archive error")
+	}
+	return nil
+}
+
+func extractZip(archivePath string, dir string) error {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Open CSAR archive error")
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		target, err := archiveTarget(dir, file.Name)
+		if err != nil {
+			return err
+		}
+
+		if file.FileInfo().IsDir() {
+			err = os.MkdirAll(target, 0755)
+			if err != nil {
+				return pkgerrors.Wrap(err, "Extract CSAR archive error")
+			}
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return pkgerrors.Wrap(err, "Extract CSAR archive error")
+		}
+		err = writeArchiveFile(target, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func extractTar(archivePath string, dir string, compressed bool) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Open CSAR archive error")
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if compressed {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return pkgerrors.Wrap(err, "Open CSAR archive error")
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	reader := tar.NewReader(r)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return pkgerrors.Wrap(err, "Extract CSAR archive error")
+		}
+
+		target, err := archiveTarget(dir, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0755)
+			if err != nil {
+				return pkgerrors.Wrap(err, "Extract CSAR archive error")
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			err = writeArchiveFile(target, reader)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csar
+
+import (
+	"log"
+
+	pkgerrors "github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+
+	"k8-plugin-multicloud/krd"
+)
+
+// FindMissingResources returns the objects of a VNF which no longer exist in
+// the cluster, using the GetResource function of every plugin
+var FindMissingResources = func(data map[string][]string, namespace string, kubeclient *kubernetes.Clientset) (map[string][]string, error) {
+	missing := make(map[string][]string)
+
+	for resourceName, resourceList := range data {
+		typePlugin, ok := krd.LoadedPlugins[resourceName]
+		if !ok {
+			return nil, pkgerrors.New("No plugin for resource " + resourceName + " found")
+		}
+
+		symGetResourceFunc, err := typePlugin.Lookup("GetResource")
+		if err != nil {
+			return nil, pkgerrors.Wrap(err, "Error fetching "+resourceName+" plugin")
+		}
+
+		for _, name := range resourceList {
+			result, err := symGetResourceFunc.(func(string, string, *kubernetes.Clientset) (string, error))(
+				name, namespace, kubeclient)
+			if err != nil {
+				return nil, pkgerrors.Wrap(err, "Error getting "+name)
+			}
+
+			if result == "" {
+				missing[resourceName] = append(missing[resourceName], name)
+			}
+		}
+	}
+
+	return missing, nil
+}
+
+// RecreateResources creates again the missing objects of a VNF from the CSAR
+// parameters and overlay it was created with and returns the names of the recreated
+// objects
+var RecreateResources = func(csarID string, cloudRegionID string, namespace string, externalVNFID string,
+	parameters map[string]string, overlay string, data map[string][]string, missing map[string][]string,
+	kubeclient *kubernetes.Clientset) (map[string][]string, error) {
+
+	vnf := newVNFInstance(csarID, cloudRegionID, namespace, externalVNFID)
+
+	err := vnf.verify()
+	if err != nil {
+		return nil, err
+	}
+	defer vnf.release()
+
+	for resourceName, resourceList := range data {
+		for _, name := range resourceList {
+			vnf.addResource(resourceName, name)
+		}
+	}
+
+	seqFile, err := readCSARMetadata(vnf.csarDirPath)
+	if err != nil {
+		return nil, err
+	}
+	vnf.parameters = seqFile.parameterValues(parameters)
+	vnf.overlay = overlay
+
+	resources, err := vnf.resources(seqFile)
+	if err != nil {
+		return nil, err
+	}
+
+	recreated := make(map[string][]string)
+
+	for _, resource := range resources {
+		name, err := vnf.internalName(resource)
+		if err != nil {
+			return recreated, err
+		}
+
+		if !containsString(missing[resource.resourceName], name) {
+			continue
+		}
+
+		log.Println("Recreating resource: " + name)
+
+		genericKubeData, err := vnf.kubeData(resource)
+		if err != nil {
+			return recreated, err
+		}
+
+		internalResourceName, err := createResource(resource.resourceName, genericKubeData, kubeclient)
+		if err != nil {
+			return recreated, err
+		}
+
+		recreated[resource.resourceName] = append(recreated[resource.resourceName], internalResourceName)
+	}
+
+	return recreated, nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
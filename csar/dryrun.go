@@ -0,0 +1,138 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csar
+
+import (
+	"strings"
+
+	"github.com/ghodss/yaml"
+	pkgerrors "github.com/pkg/errors"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"k8-plugin-multicloud/krd"
+)
+
+// DryRunResult contains the manifests rendered for a VNF and the errors
+// returned while validating them against the cluster
+type DryRunResult struct {
+	ExternalVNFID string
+	Manifests     string
+	Errors        []string
+}
+
+// DryRunVNF runs the CreateVNF pipeline submitting every object with a
+// server side dry run. Errors of a single object are reported in the result
+// and don't stop the processing of the rest of the CSAR.
+var DryRunVNF = func(csarID string, cloudRegionID string, namespace string, parameters map[string]string, overlay string,
+	kubeclient *kubernetes.Clientset) (DryRunResult, error) {
+	var result DryRunResult
+
+	// Checked first as the objects would really be created otherwise
+	err := krd.CheckDryRunSupport(kubeclient)
+	if err != nil {
+		return result, err
+	}
+
+	result.ExternalVNFID = string(uuid.NewUUID())
+	vnf := newVNFInstance(csarID, cloudRegionID, namespace, result.ExternalVNFID)
+
+	err = vnf.verify()
+	if err != nil {
+		return result, err
+	}
+	defer vnf.release()
+
+	namespacePlugin, ok := krd.LoadedPlugins["namespace"]
+	if !ok {
+		return result, pkgerrors.New("No plugin for namespace resource found")
+	}
+
+	symGetNamespaceFunc, err := namespacePlugin.Lookup("GetResource")
+	if err != nil {
+		return result, pkgerrors.Wrap(err, "Error fetching namespace plugin")
+	}
+
+	present, err := symGetNamespaceFunc.(func(string, *kubernetes.Clientset) (bool, error))(
+		namespace, kubeclient)
+	if err != nil || present == false {
+		result.Errors = append(result.Errors, "Namespace "+namespace+" is not available, objects are validated against a missing namespace")
+	}
+
+	seqFile, err := readCSARMetadata(vnf.csarDirPath)
+	if err != nil {
+		return result, err
+	}
+	vnf.parameters = seqFile.parameterValues(parameters)
+	vnf.overlay = overlay
+
+	resources, err := vnf.resources(seqFile)
+	if err != nil {
+		return result, err
+	}
+
+	var manifests []string
+	for _, resource := range resources {
+		genericKubeData, err := vnf.kubeData(resource)
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			continue
+		}
+		genericKubeData.DryRun = true
+
+		internalResourceName, err := createResource(resource.resourceName, genericKubeData, kubeclient)
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		}
+
+		if genericKubeData.Rendered != nil {
+			manifest, err := renderObject(genericKubeData.Rendered)
+			if err != nil {
+				result.Errors = append(result.Errors, err.Error())
+			} else {
+				manifests = append(manifests, manifest)
+			}
+		}
+
+		if internalResourceName != "" {
+			vnf.addResource(resource.resourceName, internalResourceName)
+		}
+	}
+
+	result.Manifests = strings.Join(manifests, "---\n")
+
+	return result, nil
+}
+
+// renderObject serializes an object built by a plugin as a YAML manifest
+func renderObject(obj runtime.Object) (string, error) {
+	obj = obj.DeepCopyObject()
+
+	// Typed clients drop the kind of the objects they decode
+	kinds, _, err := scheme.Scheme.ObjectKinds(obj)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Render object error")
+	}
+	obj.GetObjectKind().SetGroupVersionKind(kinds[0])
+
+	out, err := yaml.Marshal(obj)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Render object error")
+	}
+
+	return string(out), nil
+}
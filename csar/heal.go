@@ -0,0 +1,82 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csar
+
+import (
+	"log"
+
+	pkgerrors "github.com/pkg/errors"
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"k8-plugin-multicloud/krd"
+)
+
+// crashLoopReason is the waiting reason of a container restarted repeatedly
+const crashLoopReason = "CrashLoopBackOff"
+
+// RestartUnhealthyPods deletes the failed or crash-looping pods of a VNF so
+// their controller creates them again, and returns the names of the deleted
+// pods. Pods without a controller are left as they are and returned apart.
+var RestartUnhealthyPods = func(externalVNFID string, namespace string, kubeclient *kubernetes.Clientset) ([]string, []string, error) {
+	pods, err := kubeclient.CoreV1().Pods(namespace).List(metaV1.ListOptions{
+		LabelSelector: krd.VNFSelector(externalVNFID),
+	})
+	if err != nil {
+		return nil, nil, pkgerrors.Wrap(err, "List VNF pods error")
+	}
+
+	var restarted []string
+	var unhealthy []string
+	for _, pod := range pods.Items {
+		if !isUnhealthy(pod) {
+			continue
+		}
+
+		if metaV1.GetControllerOf(&pod) == nil {
+			log.Println("Unhealthy pod " + pod.Name + " has no controller, not restarting it")
+			unhealthy = append(unhealthy, pod.Name)
+			continue
+		}
+
+		log.Println("Restarting pod: " + pod.Name)
+
+		err = kubeclient.CoreV1().Pods(namespace).Delete(pod.Name, &metaV1.DeleteOptions{})
+		if err != nil {
+			return restarted, unhealthy, pkgerrors.Wrap(err, "Delete pod "+pod.Name+" error")
+		}
+
+		restarted = append(restarted, pod.Name)
+	}
+
+	return restarted, unhealthy, nil
+}
+
+// isUnhealthy returns whether a pod has failed or any of its containers is
+// crash-looping
+func isUnhealthy(pod coreV1.Pod) bool {
+	if pod.Status.Phase == coreV1.PodFailed {
+		return true
+	}
+
+	statuses := append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		if status.State.Waiting != nil && status.State.Waiting.Reason == crashLoopReason {
+			return true
+		}
+	}
+
+	return false
+}
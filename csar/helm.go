@@ -0,0 +1,105 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csar
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/engine"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// ChartResource is a Helm chart, packaged or as a directory, rendered with an
+// optional values file into the objects of a VNF
+type ChartResource struct {
+	Chart  string `yaml:"chart"`
+	Values string `yaml:"values"`
+}
+
+// chartResources renders a Helm chart locally, without Tiller, and returns
+// its objects in creation order
+func (v *vnfInstance) chartResources(c ChartResource) ([]csarResource, error) {
+	chartPath := v.csarDirPath + "/" + c.Chart
+	if _, err := os.Stat(chartPath); os.IsNotExist(err) {
+		return nil, pkgerrors.New("Chart " + chartPath + " does not exists")
+	}
+
+	chrt, err := chartutil.Load(chartPath)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Load chart "+c.Chart+" error")
+	}
+
+	config := &chart.Config{}
+	if c.Values != "" {
+		rawBytes, err := ioutil.ReadFile(v.csarDirPath + "/" + c.Values)
+		if err != nil {
+			return nil, pkgerrors.Wrap(err, "Read values file "+c.Values+" error")
+		}
+		config.Raw = string(rawBytes)
+	}
+
+	err = chartutil.ProcessRequirementsEnabled(chrt, config)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Process chart "+c.Chart+" requirements error")
+	}
+	err = chartutil.ProcessRequirementsImportValues(chrt)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Process chart "+c.Chart+" requirements error")
+	}
+
+	// Object names are prefixed with the internal VNF ID by the plugins, so
+	// the chart name is enough as release name
+	options := chartutil.ReleaseOptions{
+		Name:      chrt.Metadata.Name,
+		Namespace: v.namespace,
+		IsInstall: true,
+	}
+	values, err := chartutil.ToRenderValues(chrt, config, options)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Render chart "+c.Chart+" values error")
+	}
+
+	rendered, err := engine.New().Render(chrt, values)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Render chart "+c.Chart+" error")
+	}
+
+	templates := make([]string, 0, len(rendered))
+	for name := range rendered {
+		base := path.Base(name)
+		if strings.HasPrefix(base, "_") || base == "NOTES.txt" {
+			continue
+		}
+		templates = append(templates, name)
+	}
+	sort.Strings(templates)
+
+	var resources []csarResource
+	for _, name := range templates {
+		documents, err := splitManifests(name, rendered[name])
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, documents...)
+	}
+	sortByKind(resources)
+
+	return resources, nil
+}
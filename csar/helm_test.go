@@ -0,0 +1,54 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csar
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChartResources(t *testing.T) {
+	vnf := newVNFInstance("mock_yamls", "cloudregion1", "test", "uuid")
+	vnf.csarDirPath = "mock_yamls"
+
+	resources, err := vnf.chartResources(ChartResource{
+		Chart:  "charts/sise",
+		Values: "sise-values.yaml",
+	})
+	if err != nil {
+		t.Fatalf("TestChartResources returned an error (%s)", err)
+	}
+
+	if len(resources) != 2 {
+		t.Fatalf("TestChartResources returned unexpected resources (%v)", resources)
+	}
+
+	// Services are created before the workloads selecting them
+	if resources[0].resourceName != "service" || resources[1].resourceName != "deployment" {
+		t.Fatalf("TestChartResources returned resources in unexpected order (%s, %s)",
+			resources[0].resourceName, resources[1].resourceName)
+	}
+
+	if !strings.Contains(string(resources[0].manifest), "port: 8080") {
+		t.Fatalf("TestChartResources didn't apply the values file (%s)", resources[0].manifest)
+	}
+
+	name, err := vnf.internalName(resources[1])
+	if err != nil {
+		t.Fatalf("TestChartResources returned an error (%s)", err)
+	}
+	if name != "cloudregion1-test-uuid-sise-sise" {
+		t.Fatalf("TestChartResources returned unexpected name (%s)", name)
+	}
+}
@@ -0,0 +1,351 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csar
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// Files carrying the integrity data of a CSAR. The digest manifest has the
+// sha256sum format and the signature is a detached RSA or ECDSA signature of
+// the manifest, as produced by
+// "openssl dgst -sha256 -sign key.pem -out checksums.sha256.sig checksums.sha256"
+const (
+	DigestManifestFile = "checksums.sha256"
+	SignatureFile      = "checksums.sha256.sig"
+)
+
+// VerifyCSAR checks the files of a CSAR against its digest manifest and the
+// manifest signature against the trusted keys in CSAR_TRUSTED_KEYS_DIR.
+// Unsigned CSARs are rejected in the cloud regions listed in
+// CSAR_SIGNATURE_REQUIRED_REGIONS, "*" meaning every region.
+//
+// The CSARs with a digest manifest are copied to a private directory which is
+// verified instead, so their files can't change between their verification
+// and their use. The directory to read the CSAR from is returned, the caller
+// removes it when it isn't csarDirPath.
+var VerifyCSAR = func(csarDirPath string, cloudRegionID string) (string, error) {
+	_, err := os.Stat(csarDirPath + "/" + DigestManifestFile)
+	if os.IsNotExist(err) {
+		if signatureRequired(cloudRegionID) {
+			return "", pkgerrors.New("CSAR is not signed, signatures are required in cloud region " + cloudRegionID)
+		}
+		return csarDirPath, nil
+	}
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Read digest manifest error")
+	}
+
+	dir, err := ioutil.TempDir("", "csar")
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Copy CSAR error")
+	}
+
+	err = copyDir(csarDirPath, dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", pkgerrors.Wrap(err, "Copy CSAR error")
+	}
+
+	err = verifyFiles(dir, cloudRegionID)
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+
+	return dir, nil
+}
+
+// verifyFiles checks the files of a CSAR carrying a digest manifest
+func verifyFiles(csarDirPath string, cloudRegionID string) error {
+	manifest, err := ioutil.ReadFile(csarDirPath + "/" + DigestManifestFile)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Read digest manifest error")
+	}
+
+	err = verifyDigests(csarDirPath, manifest)
+	if err != nil {
+		return err
+	}
+
+	signature, err := ioutil.ReadFile(csarDirPath + "/" + SignatureFile)
+	if os.IsNotExist(err) {
+		if signatureRequired(cloudRegionID) {
+			return pkgerrors.New("CSAR is not signed, signatures are required in cloud region " + cloudRegionID)
+		}
+		return nil
+	}
+	if err != nil {
+		return pkgerrors.Wrap(err, "Read signature error")
+	}
+
+	keys, err := loadTrustedKeys(os.Getenv("CSAR_TRUSTED_KEYS_DIR"))
+	if err != nil {
+		return err
+	}
+
+	return verifySignature(manifest, signature, keys)
+}
+
+// copyDir copies the files of a directory tree into an existing directory
+func copyDir(src string, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, name), 0700)
+		}
+
+		return copyFile(path, filepath.Join(dst, name))
+	})
+}
+
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// signatureRequired tells whether unsigned CSARs are rejected in a cloud region
+func signatureRequired(cloudRegionID string) bool {
+	return regionListed("CSAR_SIGNATURE_REQUIRED_REGIONS", cloudRegionID)
+}
+
+// regionListed tells whether a cloud region is in the comma separated list of
+// an environment variable, "*" meaning every region
+func regionListed(variable string, cloudRegionID string) bool {
+	for _, region := range strings.Split(os.Getenv(variable), ",") {
+		region = strings.TrimSpace(region)
+		if region == "*" || (region != "" && region == cloudRegionID) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyDigests checks every file of the CSAR is listed in the manifest with
+// its SHA-256 digest
+func verifyDigests(csarDirPath string, manifest []byte) error {
+	digests := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(manifest))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return pkgerrors.New("Invalid digest manifest line: " + line)
+		}
+
+		// sha256sum marks files read in binary mode with a "*"
+		name := filepath.Clean(strings.TrimPrefix(fields[1], "*"))
+		if filepath.IsAbs(name) || strings.HasPrefix(name, "..") {
+			return pkgerrors.New("Invalid path in digest manifest: " + fields[1])
+		}
+		digests[filepath.ToSlash(name)] = strings.ToLower(fields[0])
+	}
+	err := scanner.Err()
+	if err != nil {
+		return pkgerrors.Wrap(err, "Read digest manifest error")
+	}
+
+	for name, expected := range digests {
+		digest, err := fileDigest(csarDirPath + "/" + name)
+		if err != nil {
+			return pkgerrors.Wrap(err, "Digest "+name+" error")
+		}
+		if digest != expected {
+			return pkgerrors.New("Digest mismatch for " + name)
+		}
+	}
+
+	// Files added after signing would be deployed without being verified
+	return filepath.Walk(csarDirPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		name, err := filepath.Rel(csarDirPath, path)
+		if err != nil {
+			return err
+		}
+		name = filepath.ToSlash(name)
+		if name == DigestManifestFile || name == SignatureFile {
+			return nil
+		}
+
+		if _, ok := digests[name]; !ok {
+			return pkgerrors.New("File " + name + " is not listed in the digest manifest")
+		}
+		return nil
+	})
+}
+
+// CSARDigest returns the SHA-256 digest of the names and contents of the
+// files of a CSAR, which changes whenever the CSAR is modified or uploaded
+// again with other contents
+var CSARDigest = func(csarID string) (string, error) {
+	csarDirPath := os.Getenv("CSAR_DIR") + "/" + csarID
+
+	hash := sha256.New()
+	err := filepath.Walk(csarDirPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		name, err := filepath.Rel(csarDirPath, path)
+		if err != nil {
+			return err
+		}
+
+		digest, err := fileDigest(path)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.WriteString(hash, digest+"  "+filepath.ToSlash(name)+"\n")
+		return err
+	})
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Digest CSAR "+csarID+" error")
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func fileDigest(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// loadTrustedKeys reads the PEM encoded public keys of a directory
+func loadTrustedKeys(dir string) ([]crypto.PublicKey, error) {
+	if dir == "" {
+		return nil, pkgerrors.New("CSAR is signed but CSAR_TRUSTED_KEYS_DIR is not set")
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Read trusted keys error")
+	}
+
+	var keys []crypto.PublicKey
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		rawBytes, err := ioutil.ReadFile(dir + "/" + file.Name())
+		if err != nil {
+			return nil, pkgerrors.Wrap(err, "Read trusted key "+file.Name()+" error")
+		}
+
+		for {
+			var block *pem.Block
+			block, rawBytes = pem.Decode(rawBytes)
+			if block == nil {
+				break
+			}
+			if block.Type != "PUBLIC KEY" {
+				continue
+			}
+
+			key, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, pkgerrors.Wrap(err, "Parse trusted key "+file.Name()+" error")
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// verifySignature checks the signature of the digest manifest was made by
+// one of the trusted keys
+func verifySignature(manifest []byte, signature []byte, keys []crypto.PublicKey) error {
+	hash := sha256.Sum256(manifest)
+
+	for _, key := range keys {
+		switch k := key.(type) {
+		case *rsa.PublicKey:
+			if rsa.VerifyPKCS1v15(k, crypto.SHA256, hash[:], signature) == nil {
+				return nil
+			}
+		case *ecdsa.PublicKey:
+			var sig struct {
+				R, S *big.Int
+			}
+			_, err := asn1.Unmarshal(signature, &sig)
+			if err == nil && ecdsa.Verify(k, hash[:], sig.R, sig.S) {
+				return nil
+			}
+		}
+	}
+
+	return pkgerrors.New("CSAR signature doesn't match any trusted key")
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csar
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+)
+
+// writeSignedCSAR creates a CSAR with a digest manifest signed by a new key
+// and returns its directory and the trusted keys directory
+func writeSignedCSAR(t *testing.T) (string, string) {
+	csarDir, err := ioutil.TempDir("", "csar")
+	if err != nil {
+		t.Fatalf("TestVerifyCSAR returned an error (%s)", err)
+	}
+	keysDir, err := ioutil.TempDir("", "keys")
+	if err != nil {
+		t.Fatalf("TestVerifyCSAR returned an error (%s)", err)
+	}
+
+	content := []byte("resources:\n")
+	err = ioutil.WriteFile(csarDir+"/metadata.yaml", content, 0644)
+	if err != nil {
+		t.Fatalf("TestVerifyCSAR returned an error (%s)", err)
+	}
+
+	digest := sha256.Sum256(content)
+	manifest := []byte(hex.EncodeToString(digest[:]) + "  metadata.yaml\n")
+	err = ioutil.WriteFile(csarDir+"/"+DigestManifestFile, manifest, 0644)
+	if err != nil {
+		t.Fatalf("TestVerifyCSAR returned an error (%s)", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("TestVerifyCSAR returned an error (%s)", err)
+	}
+
+	manifestDigest := sha256.Sum256(manifest)
+	r, s, err := ecdsa.Sign(rand.Reader, key, manifestDigest[:])
+	if err != nil {
+		t.Fatalf("TestVerifyCSAR returned an error (%s)", err)
+	}
+	signature, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	if err != nil {
+		t.Fatalf("TestVerifyCSAR returned an error (%s)", err)
+	}
+	err = ioutil.WriteFile(csarDir+"/"+SignatureFile, signature, 0644)
+	if err != nil {
+		t.Fatalf("TestVerifyCSAR returned an error (%s)", err)
+	}
+
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("TestVerifyCSAR returned an error (%s)", err)
+	}
+	err = ioutil.WriteFile(keysDir+"/vendor.pem", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey}), 0644)
+	if err != nil {
+		t.Fatalf("TestVerifyCSAR returned an error (%s)", err)
+	}
+
+	return csarDir, keysDir
+}
+
+func TestVerifyCSAR(t *testing.T) {
+	defer os.Unsetenv("CSAR_TRUSTED_KEYS_DIR")
+	defer os.Unsetenv("CSAR_SIGNATURE_REQUIRED_REGIONS")
+
+	t.Run("Successfully verify a signed CSAR", func(t *testing.T) {
+		csarDir, keysDir := writeSignedCSAR(t)
+		defer os.RemoveAll(csarDir)
+		defer os.RemoveAll(keysDir)
+
+		os.Setenv("CSAR_TRUSTED_KEYS_DIR", keysDir)
+		os.Setenv("CSAR_SIGNATURE_REQUIRED_REGIONS", "*")
+
+		dir, err := VerifyCSAR(csarDir, "cloudregion1")
+		if err != nil {
+			t.Fatalf("TestVerifyCSAR returned an error (%s)", err)
+		}
+		defer os.RemoveAll(dir)
+
+		// The verified copy doesn't follow the changes of the CSAR
+		ioutil.WriteFile(csarDir+"/metadata.yaml", []byte("resources: []\n"), 0644)
+
+		original, err := ioutil.ReadFile(dir + "/metadata.yaml")
+		if dir == csarDir || err != nil || string(original) == "resources: []\n" {
+			t.Fatalf("TestVerifyCSAR didn't return a private copy of the CSAR (%s)", dir)
+		}
+	})
+
+	t.Run("Modified file", func(t *testing.T) {
+		csarDir, keysDir := writeSignedCSAR(t)
+		defer os.RemoveAll(csarDir)
+		defer os.RemoveAll(keysDir)
+
+		os.Setenv("CSAR_TRUSTED_KEYS_DIR", keysDir)
+		ioutil.WriteFile(csarDir+"/metadata.yaml", []byte("resources: []\n"), 0644)
+
+		_, err := VerifyCSAR(csarDir, "cloudregion1")
+		if err == nil {
+			t.Fatalf("TestVerifyCSAR didn't detect the modified file")
+		}
+	})
+
+	t.Run("File not listed in the manifest", func(t *testing.T) {
+		csarDir, keysDir := writeSignedCSAR(t)
+		defer os.RemoveAll(csarDir)
+		defer os.RemoveAll(keysDir)
+
+		os.Setenv("CSAR_TRUSTED_KEYS_DIR", keysDir)
+		ioutil.WriteFile(csarDir+"/deployment.yaml", []byte("kind: Deployment\n"), 0644)
+
+		_, err := VerifyCSAR(csarDir, "cloudregion1")
+		if err == nil {
+			t.Fatalf("TestVerifyCSAR didn't detect the added file")
+		}
+	})
+
+	t.Run("Untrusted key", func(t *testing.T) {
+		csarDir, keysDir := writeSignedCSAR(t)
+		defer os.RemoveAll(csarDir)
+		defer os.RemoveAll(keysDir)
+
+		_, otherKeysDir := writeSignedCSAR(t)
+		defer os.RemoveAll(otherKeysDir)
+
+		os.Setenv("CSAR_TRUSTED_KEYS_DIR", otherKeysDir)
+
+		_, err := VerifyCSAR(csarDir, "cloudregion1")
+		if err == nil {
+			t.Fatalf("TestVerifyCSAR accepted a signature of an untrusted key")
+		}
+	})
+
+	t.Run("Unsigned CSAR", func(t *testing.T) {
+		os.Setenv("CSAR_SIGNATURE_REQUIRED_REGIONS", "cloudregion2")
+
+		dir, err := VerifyCSAR("mock_yamls", "cloudregion1")
+		if err != nil || dir != "mock_yamls" {
+			t.Fatalf("TestVerifyCSAR rejected an unsigned CSAR (%s)", err)
+		}
+
+		_, err = VerifyCSAR("mock_yamls", "cloudregion2")
+		if err == nil {
+			t.Fatalf("TestVerifyCSAR accepted an unsigned CSAR in a region requiring signatures")
+		}
+	})
+}
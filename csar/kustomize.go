@@ -0,0 +1,105 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csar
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// KustomizeResource is a kustomization base with named overlays, each one a
+// directory with a kustomization.yaml file
+type KustomizeResource struct {
+	Base     string            `yaml:"base"`
+	Overlays map[string]string `yaml:"overlays"`
+
+	// DefaultOverlays selects the overlay used in a cloud region when the
+	// request doesn't name one. The base is used otherwise.
+	DefaultOverlays map[string]string `yaml:"default_overlays"`
+}
+
+// selectOverlay returns the kustomization directory to build for a request
+func (k KustomizeResource) selectOverlay(overlay string, cloudRegionID string) (string, error) {
+	if overlay == "" {
+		overlay = k.DefaultOverlays[cloudRegionID]
+	}
+
+	if overlay == "" {
+		if k.Base == "" {
+			return "", pkgerrors.New("No overlay selected and no kustomize base defined")
+		}
+		return k.Base, nil
+	}
+
+	dir, ok := k.Overlays[overlay]
+	if !ok {
+		return "", pkgerrors.New("Overlay " + overlay + " not found")
+	}
+	return dir, nil
+}
+
+// kustomizeResources builds the selected kustomization overlay with the
+// kustomize binary and returns its objects in creation order
+func (v *vnfInstance) kustomizeResources(k KustomizeResource) ([]csarResource, error) {
+	dir, err := k.selectOverlay(v.overlay, v.cloudRegionID)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := buildKustomization(v.csarDirPath + "/" + dir)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Build kustomization "+dir+" error")
+	}
+
+	resources, err := splitManifests(dir, content)
+	if err != nil {
+		return nil, err
+	}
+	sortByKind(resources)
+
+	return resources, nil
+}
+
+// kustomizeCommand returns the kustomize binary, set with KUSTOMIZE_BIN or
+// looked up in the PATH. The binary is used instead of the kustomize library,
+// which needs a newer apimachinery than the client-go release of the plugin.
+func kustomizeCommand() string {
+	if bin := os.Getenv("KUSTOMIZE_BIN"); bin != "" {
+		return bin
+	}
+	return "kustomize"
+}
+
+// buildKustomization runs kustomize build on a directory
+var buildKustomization = func(path string) (string, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return "", pkgerrors.New("Kustomization " + path + " does not exists")
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(kustomizeCommand(), "build", path)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return "", pkgerrors.Wrap(err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
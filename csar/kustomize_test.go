@@ -0,0 +1,128 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csar
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestKustomizeResources(t *testing.T) {
+	oldBuildKustomization := buildKustomization
+
+	defer func() {
+		buildKustomization = oldBuildKustomization
+	}()
+
+	var built string
+	buildKustomization = func(path string) (string, error) {
+		built = path
+		return "apiVersion: v1\nkind: Service\nmetadata:\n  name: sise-svc\n---\n" +
+			"apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: sise-deploy\n---\n" +
+			"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: sise-config\n", nil
+	}
+
+	kustomization := KustomizeResource{
+		Base: "kustomize/base",
+		Overlays: map[string]string{
+			"lab": "kustomize/overlays/lab",
+		},
+		DefaultOverlays: map[string]string{
+			"cloudlab": "lab",
+		},
+	}
+
+	testCases := []struct {
+		label         string
+		cloudRegionID string
+		overlay       string
+		expected      string
+	}{
+		{"Base when no overlay is selected", "cloudregion1", "", "mock_yamls/kustomize/base"},
+		{"Overlay requested", "cloudregion1", "lab", "mock_yamls/kustomize/overlays/lab"},
+		{"Default overlay of the cloud region", "cloudlab", "", "mock_yamls/kustomize/overlays/lab"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.label, func(t *testing.T) {
+			vnf := newVNFInstance("mock_yamls", testCase.cloudRegionID, "test", "uuid")
+			vnf.csarDirPath = "mock_yamls"
+			vnf.overlay = testCase.overlay
+
+			resources, err := vnf.kustomizeResources(kustomization)
+			if err != nil {
+				t.Fatalf("TestKustomizeResources returned an error (%s)", err)
+			}
+
+			if built != testCase.expected {
+				t.Fatalf("TestKustomizeResources built %s instead of %s", built, testCase.expected)
+			}
+
+			var names []string
+			for _, resource := range resources {
+				names = append(names, resource.resourceName)
+			}
+			if strings.Join(names, ",") != "configmap,service,deployment" {
+				t.Fatalf("TestKustomizeResources returned unexpected resources (%v)", names)
+			}
+		})
+	}
+
+	t.Run("Unknown overlay", func(t *testing.T) {
+		vnf := newVNFInstance("mock_yamls", "cloudregion1", "test", "uuid")
+		vnf.csarDirPath = "mock_yamls"
+		vnf.overlay = "production"
+
+		_, err := vnf.kustomizeResources(kustomization)
+		if err == nil {
+			t.Fatalf("TestKustomizeResources didn't return an error for an unknown overlay")
+		}
+	})
+}
+
+func TestBuildKustomization(t *testing.T) {
+	if _, err := exec.LookPath(kustomizeCommand()); err != nil {
+		t.Skip("kustomize binary not found")
+	}
+
+	testCases := []struct {
+		label    string
+		path     string
+		replicas string
+	}{
+		{"Base", "mock_yamls/kustomize/base", "replicas: 1"},
+		{"Overlay", "mock_yamls/kustomize/overlays/lab", "replicas: 3"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.label, func(t *testing.T) {
+			content, err := buildKustomization(testCase.path)
+			if err != nil {
+				t.Fatalf("TestBuildKustomization returned an error (%s)", err)
+			}
+
+			if !strings.Contains(content, testCase.replicas) {
+				t.Fatalf("TestBuildKustomization returned unexpected manifest (%s)", content)
+			}
+		})
+	}
+
+	t.Run("Missing kustomization", func(t *testing.T) {
+		_, err := buildKustomization("mock_yamls/kustomize/missing")
+		if err == nil {
+			t.Fatalf("TestBuildKustomization didn't return an error for a missing kustomization")
+		}
+	})
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csar
+
+import (
+	"sort"
+
+	pkgerrors "github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"k8-plugin-multicloud/krd"
+)
+
+// kindOrder is the order the objects rendered from a chart or a kustomization
+// are created in, so workloads find the objects they reference
+var kindOrder = []string{
+	"Secret",
+	"ConfigMap",
+	"PersistentVolumeClaim",
+	"Service",
+	"DaemonSet",
+	"Deployment",
+	"StatefulSet",
+	"Job",
+	"CronJob",
+	"Ingress",
+	"NetworkPolicy",
+}
+
+func kindIndex(kind string) int {
+	for i, k := range kindOrder {
+		if k == kind {
+			return i
+		}
+	}
+	return len(kindOrder)
+}
+
+// splitManifests returns the objects of a multi-document YAML rendered from a
+// chart or a kustomization, mapping their kinds to the plugins creating them
+func splitManifests(filename string, content string) ([]csarResource, error) {
+	var resources []csarResource
+
+	for _, document := range documentSeparator.Split(content, -1) {
+		var manifest struct {
+			Kind string `yaml:"kind"`
+		}
+		err := yaml.Unmarshal([]byte(document), &manifest)
+		if err != nil {
+			return nil, pkgerrors.Wrap(err, "Parse "+filename+" error")
+		}
+		if manifest.Kind == "" {
+			// Empty or commented out document
+			continue
+		}
+
+		resourceName, ok := krd.PluginForKind(manifest.Kind)
+		if !ok {
+			return nil, pkgerrors.New(filename + " contains an unsupported " + manifest.Kind)
+		}
+
+		resources = append(resources, csarResource{
+			resourceName: resourceName,
+			filename:     filename,
+			manifest:     []byte(document),
+		})
+	}
+
+	return resources, nil
+}
+
+// sortByKind orders rendered objects following kindOrder
+func sortByKind(resources []csarResource) {
+	sort.SliceStable(resources, func(i, j int) bool {
+		return kindIndex(krd.ResourceKinds[resources[i].resourceName]) < kindIndex(krd.ResourceKinds[resources[j].resourceName])
+	})
+}
+
+// resources returns the objects of the VNF in creation order: the ConfigMaps
+// and Secrets built from raw files, the objects rendered from the charts and
+// the kustomization, and then the manifests listed in the metadata
+func (v *vnfInstance) resources(seqFile MetadataFile) ([]csarResource, error) {
+	ordered := seqFile.orderedResources()
+	fileResources := len(seqFile.ConfigMaps) + len(seqFile.Secrets)
+
+	resources := append([]csarResource{}, ordered[:fileResources]...)
+	for _, c := range seqFile.Charts {
+		chartResources, err := v.chartResources(c)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, chartResources...)
+	}
+
+	if seqFile.Kustomize != nil {
+		kustomizeResources, err := v.kustomizeResources(*seqFile.Kustomize)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, kustomizeResources...)
+	}
+
+	return append(resources, ordered[fileResources:]...), nil
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"time"
+
 	"k8s.io/client-go/kubernetes"
 
-	"github.com/shank7485/k8-plugin-multicloud/krd"
+	"k8-plugin-multicloud/krd"
 )
 
 func main() {}
@@ -14,17 +16,54 @@ func CreateResource(kubedata *krd.GenericKubeResourceData, kubeclient *kubernete
 }
 
 // ListResources of existing resources
-func ListResources(limit int64, namespace string, kubeclient *kubernetes.Clientset) (*[]string, error) {
+func ListResources(limit int64, namespace string, labelSelector string, kubeclient *kubernetes.Clientset) (*[]string, error) {
 	returnVal := []string{"cloud1-default-uuid1", "cloud1-default-uuid2"}
 	return &returnVal, nil
 }
 
+// DeletedResources records the names of the resources deleted by the tests
+var DeletedResources []string
+
 // DeleteResource existing resources
 func DeleteResource(name string, namespace string, kubeclient *kubernetes.Clientset) error {
+	DeletedResources = append(DeletedResources, name)
 	return nil
 }
 
 // GetResource existing resource host
-func GetResource(namespace string, client *kubernetes.Clientset) (bool, error) {
-	return true, nil
+func GetResource(name string, namespace string, kubeclient *kubernetes.Clientset) (string, error) {
+	return name, nil
+}
+
+// UpdateResource existing resource
+func UpdateResource(kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) (string, error) {
+	return "externalUUID", nil
+}
+
+// WaitForResourceError is returned by WaitForResource when set by the tests
+var WaitForResourceError error
+
+// WaitForResource existing resource to be ready
+func WaitForResource(name string, namespace string, timeout time.Duration, kubeclient *kubernetes.Clientset) error {
+	return WaitForResourceError
+}
+
+// LabelResource existing resource
+func LabelResource(name string, namespace string, kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) error {
+	return nil
+}
+
+// ScaleResource existing resource
+func ScaleResource(name string, namespace string, replicas int32, kubeclient *kubernetes.Clientset) error {
+	return nil
+}
+
+// GetReplicas of existing resource
+func GetReplicas(name string, namespace string, kubeclient *kubernetes.Clientset) (int32, error) {
+	return 1, nil
+}
+
+// RetainResource existing resource
+func RetainResource(name string, namespace string, retain bool, kubeclient *kubernetes.Clientset) error {
+	return nil
 }
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"k8s.io/client-go/kubernetes"
+
+	"k8-plugin-multicloud/krd"
+)
+
+func main() {}
+
+// CreateResource is used to create a new Namespace
+func CreateResource(namespace string, client *kubernetes.Clientset) error {
+	return nil
+}
+
+// GetResource existing namespace
+func GetResource(namespace string, client *kubernetes.Clientset) (bool, error) {
+	return true, nil
+}
+
+// DeleteResource existing namespace
+func DeleteResource(namespace string, client *kubernetes.Clientset) error {
+	return nil
+}
+
+// ListResources of existing namespaces
+func ListResources(limit int64, client *kubernetes.Clientset) (*[]string, error) {
+	returnVal := []string{"default", "test"}
+	return &returnVal, nil
+}
+
+// ApplyQuota of a quota profile in an existing namespace
+func ApplyQuota(namespace string, profile *krd.QuotaProfile, client *kubernetes.Clientset) error {
+	return nil
+}
@@ -0,0 +1,3 @@
+{{- define "sise.name" -}}
+{{ .Release.Name }}-{{ .Chart.Name }}
+{{- end -}}
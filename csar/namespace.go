@@ -0,0 +1,152 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csar
+
+import (
+	"log"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+
+	"k8-plugin-multicloud/krd"
+)
+
+// EnsureNamespace creates a namespace with the namespace plugin unless it
+// already exists, applying the quota profile if any, and returns whether it
+// was created
+var EnsureNamespace = func(namespace string, profile *krd.QuotaProfile, kubeclient *kubernetes.Clientset) (bool, error) {
+	namespacePlugin, ok := krd.LoadedPlugins["namespace"]
+	if !ok {
+		return false, pkgerrors.New("No plugin for namespace resource found")
+	}
+
+	symGetNamespaceFunc, err := namespacePlugin.Lookup("GetResource")
+	if err != nil {
+		return false, pkgerrors.Wrap(err, "Error fetching namespace plugin")
+	}
+
+	present, err := symGetNamespaceFunc.(func(string, *kubernetes.Clientset) (bool, error))(
+		namespace, kubeclient)
+	if err != nil {
+		return false, pkgerrors.Wrap(err, "Error in plugin namespace plugin")
+	}
+
+	if present {
+		return false, nil
+	}
+
+	symCreateNamespaceFunc, err := namespacePlugin.Lookup("CreateResource")
+	if err != nil {
+		return false, pkgerrors.Wrap(err, "Error fetching namespace plugin")
+	}
+
+	log.Println("Creating namespace: " + namespace)
+
+	err = symCreateNamespaceFunc.(func(string, *kubernetes.Clientset) error)(
+		namespace, kubeclient)
+	if err != nil {
+		return false, pkgerrors.Wrap(err, "Error creating "+namespace+" namespace")
+	}
+
+	if profile == nil {
+		return true, nil
+	}
+
+	symApplyQuotaFunc, err := namespacePlugin.Lookup("ApplyQuota")
+	if err != nil {
+		return true, pkgerrors.Wrap(err, "Error fetching namespace plugin")
+	}
+
+	err = symApplyQuotaFunc.(func(string, *krd.QuotaProfile, *kubernetes.Clientset) error)(
+		namespace, profile, kubeclient)
+	if err != nil {
+		return true, pkgerrors.Wrap(err, "Error applying quota to "+namespace+" namespace")
+	}
+
+	return true, nil
+}
+
+// DeleteNamespace deletes a namespace, with every object left in it, using
+// the namespace plugin. See ForeignObjects.
+var DeleteNamespace = func(namespace string, kubeclient *kubernetes.Clientset) error {
+	namespacePlugin, ok := krd.LoadedPlugins["namespace"]
+	if !ok {
+		return pkgerrors.New("No plugin for namespace resource found")
+	}
+
+	symDeleteNamespaceFunc, err := namespacePlugin.Lookup("DeleteResource")
+	if err != nil {
+		return pkgerrors.Wrap(err, "Error fetching namespace plugin")
+	}
+
+	log.Println("Deleting namespace: " + namespace)
+
+	err = symDeleteNamespaceFunc.(func(string, *kubernetes.Clientset) error)(
+		namespace, kubeclient)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Error deleting "+namespace+" namespace")
+	}
+
+	return nil
+}
+
+// isSystemObject tells whether an object is created by Kubernetes in every
+// namespace, e.g. the default service account token
+func isSystemObject(resourceName string, name string) bool {
+	switch resourceName {
+	case "configmap":
+		return name == "kube-root-ca.crt"
+	case "secret":
+		return strings.HasPrefix(name, "default-token-")
+	}
+	return false
+}
+
+// ForeignObjects lists the objects of a namespace which were neither created
+// by the plugin nor by Kubernetes, by resource type
+var ForeignObjects = func(namespace string, kubeclient *kubernetes.Clientset) (map[string][]string, error) {
+	all, err := ListResources(namespace, "", kubeclient)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Error listing the objects of "+namespace+" namespace")
+	}
+
+	managed, err := ListResources(namespace, krd.ManagedBySelector, kubeclient)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Error listing the objects of "+namespace+" namespace")
+	}
+
+	foreign := make(map[string][]string)
+	for resourceName, names := range all {
+		owned := make(map[string]bool)
+		for _, name := range managed[resourceName] {
+			owned[name] = true
+		}
+
+		for _, name := range names {
+			if !owned[name] && !isSystemObject(resourceName, name) {
+				foreign[resourceName] = append(foreign[resourceName], name)
+			}
+		}
+	}
+
+	return foreign, nil
+}
+
+// NamespaceCleanupEnabled tells whether the namespaces created for the VNFs
+// of a cloud region are deleted along with their last VNF, as configured by
+// the comma separated NAMESPACE_CLEANUP_REGIONS, "*" meaning every region
+func NamespaceCleanupEnabled(cloudRegionID string) bool {
+	return regionListed("NAMESPACE_CLEANUP_REGIONS", cloudRegionID)
+}
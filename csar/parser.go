@@ -17,6 +17,9 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"k8s.io/client-go/kubernetes"
 
@@ -28,104 +31,112 @@ import (
 	"k8-plugin-multicloud/krd"
 )
 
-// CreateVNF reads the CSAR files from the files system and creates them one by one
-var CreateVNF = func(csarID string, cloudRegionID string, namespace string, kubeclient *kubernetes.Clientset) (string, map[string][]string, error) {
-	namespacePlugin, ok := krd.LoadedPlugins["namespace"]
-	if !ok {
-		return "", nil, pkgerrors.New("No plugin for namespace resource found")
+// CreateVNF reads the CSAR files from the files system and creates them one by one.
+// The parameters override the defaults declared in the metadata and overlay
+// selects the kustomization overlay. The persistent volume claims of the CSAR
+// listed in reusedClaims are not created, the retained claims they map to are
+// bound instead. The namespace is created beforehand with EnsureNamespace.
+// The objects already created are deleted when the VNF can't be created, e.g.
+// when a bootstrap job fails or times out.
+var CreateVNF = func(csarID string, cloudRegionID string, namespace string, parameters map[string]string, overlay string,
+	reusedClaims map[string]string, kubeclient *kubernetes.Clientset) (string, map[string][]string, error) {
+
+	// uuid
+	externalVNFID := string(uuid.NewUUID())
+
+	// cloud1-default-uuid
+	vnf := newVNFInstance(csarID, cloudRegionID, namespace, externalVNFID)
+
+	err := vnf.verify()
+	if err != nil {
+		return "", nil, err
 	}
+	defer vnf.release()
 
-	symGetNamespaceFunc, err := namespacePlugin.Lookup("GetResource")
+	seqFile, err := readCSARMetadata(vnf.csarDirPath)
 	if err != nil {
-		return "", nil, pkgerrors.Wrap(err, "Error fetching namespace plugin")
+		return "", nil, err
 	}
+	vnf.parameters = seqFile.parameterValues(parameters)
+	vnf.overlay = overlay
 
-	present, err := symGetNamespaceFunc.(func(string, *kubernetes.Clientset) (bool, error))(
-		namespace, kubeclient)
+	resources, err := vnf.resources(seqFile)
 	if err != nil {
-		return "", nil, pkgerrors.Wrap(err, "Error in plugin namespace plugin")
+		return "", nil, err
 	}
 
-	if present == false {
-		symGetNamespaceFunc, err := namespacePlugin.Lookup("CreateResource")
-		if err != nil {
-			return "", nil, pkgerrors.Wrap(err, "Error fetching namespace plugin")
+	resourceYAMLNameMap := make(map[string][]string)
+	var reused []string
+
+	// The objects created before a failure are deleted, the reused claims
+	// stay retained
+	created := make(map[string][]string)
+	succeeded := false
+	defer func() {
+		if succeeded || len(created) == 0 {
+			return
 		}
-
-		err = symGetNamespaceFunc.(func(string, *kubernetes.Clientset) error)(
-			namespace, kubeclient)
+		err := DestroyVNF(created, namespace, StoragePolicyDelete, kubeclient)
 		if err != nil {
-			return "", nil, pkgerrors.Wrap(err, "Error creating "+namespace+" namespace")
+			log.Println("Delete objects of failed VNF " + vnf.internalVNFID + " error: " + err.Error())
 		}
-	}
+	}()
 
-	var path string
-
-	// uuid
-	externalVNFID := string(uuid.NewUUID())
+	for _, resource := range resources {
+		if resource.resourceName == "pvc" && len(reusedClaims) > 0 {
+			internalResourceName, err := vnf.internalName(resource)
+			if err != nil {
+				return "", nil, err
+			}
 
-	// cloud1-default-uuid
-	internalVNFID := cloudRegionID + "-" + namespace + "-" + externalVNFID
+			originalName := strings.TrimPrefix(internalResourceName, vnf.internalVNFID+"-")
+			if claim, ok := reusedClaims[originalName]; ok {
+				log.Println("Reusing retained pvc: " + claim)
+				resourceYAMLNameMap["pvc"] = append(resourceYAMLNameMap["pvc"], claim)
+				vnf.reuseResource("pvc", originalName, claim)
+				reused = append(reused, claim)
+				continue
+			}
+		}
 
-	csarDirPath := os.Getenv("CSAR_DIR") + "/" + csarID
-	metadataYAMLPath := csarDirPath + "/metadata.yaml"
+		genericKubeData, err := vnf.kubeData(resource)
+		if err != nil {
+			return "", nil, err
+		}
 
-	seqFile, err := ReadMetadataFile(metadataYAMLPath)
-	if err != nil {
-		return "", nil, pkgerrors.Wrap(err, "Error while reading Metadata File: "+metadataYAMLPath)
-	}
+		// cloud1-default-uuid-sisedeploy
+		internalResourceName, err := createResource(resource.resourceName, genericKubeData, kubeclient)
+		if err != nil {
+			return "", nil, err
+		}
+		created[resource.resourceName] = append(created[resource.resourceName], internalResourceName)
 
-	resourceYAMLNameMap := make(map[string][]string)
+		if resource.resourceName == "job" && seqFile.isBootstrapJob(resource.filename) {
+			err = waitForResource(resource.resourceName, internalResourceName, namespace, seqFile.bootstrapTimeout(), kubeclient)
+			if err != nil {
+				return "", nil, pkgerrors.Wrap(err, "Bootstrap job "+internalResourceName+" failed")
+			}
+		}
 
-	for _, resource := range seqFile.ResourceTypePathMap {
-		for resourceName, resourceFileNames := range resource {
-			// Load/Use Deployment data/client
+		/*
+			{
+				"deployment": ["cloud1-default-uuid-sisedeploy1", "cloud1-default-uuid-sisedeploy2", ... ]
+			}
+		*/
+		resourceYAMLNameMap[resource.resourceName] = append(resourceYAMLNameMap[resource.resourceName], internalResourceName)
+		vnf.addResource(resource.resourceName, internalResourceName)
+	}
 
-			var resourceNameList []string
+	// The reused claims now belong to the new VNF
+	if len(reused) > 0 {
+		err = LabelVNF(map[string][]string{"pvc": reused}, csarID, cloudRegionID, namespace, externalVNFID, kubeclient)
+		if err != nil {
+			return "", nil, err
+		}
 
-			for _, filename := range resourceFileNames {
-				path = csarDirPath + "/" + filename
-
-				_, err = os.Stat(path)
-				if os.IsNotExist(err) {
-					return "", nil, pkgerrors.New("File " + path + "does not exists")
-				}
-
-				log.Println("Processing file: " + path)
-
-				genericKubeData := &krd.GenericKubeResourceData{
-					YamlFilePath:  path,
-					Namespace:     namespace,
-					InternalVNFID: internalVNFID,
-				}
-
-				typePlugin, ok := krd.LoadedPlugins[resourceName]
-				if !ok {
-					return "", nil, pkgerrors.New("No plugin for resource " + resourceName + " found")
-				}
-
-				symCreateResourceFunc, err := typePlugin.Lookup("CreateResource")
-				if err != nil {
-					return "", nil, pkgerrors.Wrap(err, "Error fetching "+resourceName+" plugin")
-				}
-
-				// cloud1-default-uuid-sisedeploy
-				internalResourceName, err := symCreateResourceFunc.(func(*krd.GenericKubeResourceData, *kubernetes.Clientset) (string, error))(
-					genericKubeData, kubeclient)
-				if err != nil {
-					return "", nil, pkgerrors.Wrap(err, "Error in plugin "+resourceName+" plugin")
-				}
-
-				// ["cloud1-default-uuid-sisedeploy1", "cloud1-default-uuid-sisedeploy2", ... ]
-				resourceNameList = append(resourceNameList, internalResourceName)
-
-				/*
-					{
-						"deployment": ["cloud1-default-uuid-sisedeploy1", "cloud1-default-uuid-sisedeploy2", ... ]
-					}
-				*/
-				resourceYAMLNameMap[resourceName] = resourceNameList
-			}
+		err = RetainClaims(reused, namespace, false, kubeclient)
+		if err != nil {
+			return "", nil, err
 		}
 	}
 
@@ -137,11 +148,319 @@ var CreateVNF = func(csarID string, cloudRegionID string, namespace string, kube
 		},
 		nil
 	*/
+	succeeded = true
 	return externalVNFID, resourceYAMLNameMap, nil
 }
 
-// DestroyVNF deletes VNFs based on data passed
-var DestroyVNF = func(data map[string][]string, namespace string, kubeclient *kubernetes.Clientset) error {
+// vnfInstance identifies the VNF whose objects are created from a CSAR
+type vnfInstance struct {
+	csarID        string
+	cloudRegionID string
+	namespace     string
+	externalVNFID string
+	internalVNFID string
+	csarDirPath   string
+
+	// private copy of the CSAR verified by verify
+	verifiedCopy string
+
+	// {"configmap": {"sise-config": "cloud1-default-uuid-sise-config"}, ... }
+	renamedResources map[string]map[string]string
+
+	// values substituted in the manifests
+	parameters map[string]string
+
+	// name of the kustomization overlay built
+	overlay string
+}
+
+func newVNFInstance(csarID string, cloudRegionID string, namespace string, externalVNFID string) *vnfInstance {
+	return &vnfInstance{
+		csarID:           csarID,
+		cloudRegionID:    cloudRegionID,
+		namespace:        namespace,
+		externalVNFID:    externalVNFID,
+		internalVNFID:    cloudRegionID + "-" + namespace + "-" + externalVNFID,
+		csarDirPath:      os.Getenv("CSAR_DIR") + "/" + csarID,
+		renamedResources: make(map[string]map[string]string),
+	}
+}
+
+// verify checks the CSAR of the VNF with VerifyCSAR. The files of the VNF
+// are read from the verified copy afterwards, until release removes it.
+func (v *vnfInstance) verify() error {
+	dir, err := VerifyCSAR(v.csarDirPath, v.cloudRegionID)
+	if err != nil {
+		return pkgerrors.Wrap(err, "CSAR "+v.csarID+" verification error")
+	}
+
+	if dir != v.csarDirPath {
+		v.verifiedCopy = dir
+		v.csarDirPath = dir
+	}
+
+	return nil
+}
+
+// release removes the verified copy of the CSAR, if any
+func (v *vnfInstance) release() {
+	if v.verifiedCopy != "" {
+		os.RemoveAll(v.verifiedCopy)
+		v.verifiedCopy = ""
+	}
+}
+
+// addResource records the name given to an object of the VNF so references
+// to it can be updated in the objects created afterwards
+func (v *vnfInstance) addResource(resourceName string, internalResourceName string) {
+	if _, ok := v.renamedResources[resourceName]; !ok {
+		v.renamedResources[resourceName] = make(map[string]string)
+	}
+	originalName := strings.TrimPrefix(internalResourceName, v.internalVNFID+"-")
+	v.renamedResources[resourceName][originalName] = internalResourceName
+}
+
+// reuseResource records an existing object used by the VNF in place of a
+// CSAR object, so references to the CSAR object point to it
+func (v *vnfInstance) reuseResource(resourceName string, originalName string, name string) {
+	if _, ok := v.renamedResources[resourceName]; !ok {
+		v.renamedResources[resourceName] = make(map[string]string)
+	}
+	v.renamedResources[resourceName][originalName] = name
+}
+
+// kubeData returns the data passed to the plugin creating a CSAR object
+func (v *vnfInstance) kubeData(resource csarResource) (*krd.GenericKubeResourceData, error) {
+	kubedata := &krd.GenericKubeResourceData{
+		Namespace:        v.namespace,
+		InternalVNFID:    v.internalVNFID,
+		ExternalVNFID:    v.externalVNFID,
+		CloudRegionID:    v.cloudRegionID,
+		CsarID:           v.csarID,
+		RenamedResources: v.renamedResources,
+		Parameters:       v.parameters,
+	}
+
+	if resource.fileResource != nil {
+		log.Println("Processing " + resource.resourceName + ": " + resource.fileResource.Name)
+
+		kubedata.Name = resource.fileResource.Name
+		kubedata.Files = make(map[string]string)
+		for key, filename := range resource.fileResource.Files {
+			path, err := csarFilePath(v.csarDirPath, filename)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				return nil, pkgerrors.New("File " + path + "does not exists")
+			}
+			kubedata.Files[key] = path
+		}
+
+		return kubedata, nil
+	}
+
+	if resource.manifest != nil {
+		log.Println("Processing chart template: " + resource.filename)
+		kubedata.YamlFilePath = resource.filename
+		kubedata.YamlData = resource.manifest
+
+		return kubedata, nil
+	}
+
+	path, err := csarFilePath(v.csarDirPath, resource.filename)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, pkgerrors.New("File " + path + "does not exists")
+	}
+
+	log.Println("Processing file: " + path)
+	kubedata.YamlFilePath = path
+
+	return kubedata, nil
+}
+
+// csarFilePath returns the path of a file referenced by the metadata of a
+// CSAR, which must be relative and stay within the CSAR directory
+func csarFilePath(csarDirPath string, filename string) (string, error) {
+	name := filepath.Clean(filename)
+	if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+		return "", pkgerrors.New("Invalid path in CSAR metadata: " + filename)
+	}
+
+	return csarDirPath + "/" + name, nil
+}
+
+// internalName returns the name given to a CSAR object in the VNF
+func (v *vnfInstance) internalName(resource csarResource) (string, error) {
+	if resource.fileResource != nil {
+		return v.internalVNFID + "-" + resource.fileResource.Name, nil
+	}
+
+	rawBytes := resource.manifest
+	if rawBytes == nil {
+		path, err := csarFilePath(v.csarDirPath, resource.filename)
+		if err != nil {
+			return "", err
+		}
+
+		fileBytes, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", pkgerrors.Wrap(err, "Read "+resource.filename+" error")
+		}
+
+		rawBytes, err = krd.SubstituteParameters(fileBytes, v.parameters)
+		if err != nil {
+			return "", pkgerrors.Wrap(err, "Substitute parameters in "+resource.filename+" error")
+		}
+	}
+
+	var manifest struct {
+		Metadata struct {
+			Name string `yaml:"name"`
+		} `yaml:"metadata"`
+	}
+	err := yaml.Unmarshal(rawBytes, &manifest)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Parse "+resource.filename+" error")
+	}
+
+	return v.internalVNFID + "-" + manifest.Metadata.Name, nil
+}
+
+// createResource calls the CreateResource function of the plugin registered
+// for resourceName and returns the name of the created object
+func createResource(resourceName string, kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) (string, error) {
+	typePlugin, ok := krd.LoadedPlugins[resourceName]
+	if !ok {
+		return "", pkgerrors.New("No plugin for resource " + resourceName + " found")
+	}
+
+	symCreateResourceFunc, err := typePlugin.Lookup("CreateResource")
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Error fetching "+resourceName+" plugin")
+	}
+
+	internalResourceName, err := symCreateResourceFunc.(func(*krd.GenericKubeResourceData, *kubernetes.Clientset) (string, error))(
+		kubedata, kubeclient)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Error in plugin "+resourceName+" plugin")
+	}
+
+	return internalResourceName, nil
+}
+
+// waitForResource calls the WaitForResource function of the plugin registered
+// for resourceName, which blocks until the object is ready
+func waitForResource(resourceName string, name string, namespace string, timeout time.Duration, kubeclient *kubernetes.Clientset) error {
+	typePlugin, ok := krd.LoadedPlugins[resourceName]
+	if !ok {
+		return pkgerrors.New("No plugin for resource " + resourceName + " found")
+	}
+
+	symWaitForResourceFunc, err := typePlugin.Lookup("WaitForResource")
+	if err != nil {
+		return pkgerrors.Wrap(err, "Error fetching "+resourceName+" plugin")
+	}
+
+	return symWaitForResourceFunc.(func(string, string, time.Duration, *kubernetes.Clientset) error)(
+		name, namespace, timeout, kubeclient)
+}
+
+// DiscoverVNF lists the objects of a VNF by their VNF ID label using every
+// loaded resource plugin. The persistent volume claims retained by a deleted
+// VNF keep its VNF ID label but are no longer part of it.
+var DiscoverVNF = func(externalVNFID string, namespace string, kubeclient *kubernetes.Clientset) (map[string][]string, error) {
+	return ListResources(namespace, krd.VNFSelector(externalVNFID)+","+krd.RetainedLabel+"!=true", kubeclient)
+}
+
+// ListResources lists the objects of a namespace matching a label selector
+// using every loaded resource plugin
+var ListResources = func(namespace string, labelSelector string, kubeclient *kubernetes.Clientset) (map[string][]string, error) {
+	resources := make(map[string][]string)
+
+	for resourceName, typePlugin := range krd.LoadedPlugins {
+		if resourceName == "namespace" {
+			continue
+		}
+
+		symListResourcesFunc, err := typePlugin.Lookup("ListResources")
+		if err != nil {
+			return nil, pkgerrors.Wrap(err, "Error fetching "+resourceName+" plugin")
+		}
+
+		names, err := symListResourcesFunc.(func(int64, string, string, *kubernetes.Clientset) (*[]string, error))(
+			0, namespace, labelSelector, kubeclient)
+		if err != nil {
+			return nil, pkgerrors.Wrap(err, "Error in plugin "+resourceName+" plugin")
+		}
+
+		if names != nil && len(*names) > 0 {
+			resources[resourceName] = *names
+		}
+	}
+
+	return resources, nil
+}
+
+// ListNamespaces lists the namespaces of a cluster using the namespace plugin
+var ListNamespaces = func(kubeclient *kubernetes.Clientset) ([]string, error) {
+	namespacePlugin, ok := krd.LoadedPlugins["namespace"]
+	if !ok {
+		return nil, pkgerrors.New("No plugin for namespace resource found")
+	}
+
+	symListNamespacesFunc, err := namespacePlugin.Lookup("ListResources")
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Error fetching namespace plugin")
+	}
+
+	names, err := symListNamespacesFunc.(func(int64, *kubernetes.Clientset) (*[]string, error))(
+		0, kubeclient)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Error in plugin namespace plugin")
+	}
+
+	return *names, nil
+}
+
+// Storage policies applied to the persistent volume claims of a VNF when it
+// is destroyed
+const (
+	StoragePolicyDelete = "delete"
+	StoragePolicyRetain = "retain"
+)
+
+// RetainClaims sets or removes the retained label of persistent volume
+// claims, which keeps them from being collected as orphans
+var RetainClaims = func(names []string, namespace string, retained bool, kubeclient *kubernetes.Clientset) error {
+	typePlugin, ok := krd.LoadedPlugins["pvc"]
+	if !ok {
+		return pkgerrors.New("No plugin for resource pvc found")
+	}
+
+	symRetainResourceFunc, err := typePlugin.Lookup("RetainResource")
+	if err != nil {
+		return pkgerrors.Wrap(err, "Error fetching pvc plugin")
+	}
+
+	for _, name := range names {
+		err = symRetainResourceFunc.(func(string, string, bool, *kubernetes.Clientset) error)(
+			name, namespace, retained, kubeclient)
+		if err != nil {
+			return pkgerrors.Wrap(err, "Error retaining "+name)
+		}
+	}
+
+	return nil
+}
+
+// DestroyVNF deletes VNFs based on data passed. Persistent volume claims are
+// kept and labelled as retained when the storage policy is
+// StoragePolicyRetain
+var DestroyVNF = func(data map[string][]string, namespace string, storagePolicy string, kubeclient *kubernetes.Clientset) error {
 	/* data:
 	{
 		"deployment": ["cloud1-default-uuid-sisedeploy1", "cloud1-default-uuid-sisedeploy2", ... ]
@@ -150,6 +469,15 @@ var DestroyVNF = func(data map[string][]string, namespace string, kubeclient *ku
 	*/
 
 	for resourceName, resourceList := range data {
+		if resourceName == "pvc" && storagePolicy == StoragePolicyRetain {
+			log.Println("Retaining persistent volume claims: " + strings.Join(resourceList, ", "))
+			err := RetainClaims(resourceList, namespace, true, kubeclient)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
 		typePlugin, ok := krd.LoadedPlugins[resourceName]
 		if !ok {
 			return pkgerrors.New("No plugin for resource " + resourceName + " found")
@@ -178,6 +506,97 @@ var DestroyVNF = func(data map[string][]string, namespace string, kubeclient *ku
 // MetadataFile stores the metadata of execution
 type MetadataFile struct {
 	ResourceTypePathMap []map[string][]string `yaml:"resources"`
+	ConfigMaps          []FileResource        `yaml:"configmaps"`
+	Secrets             []FileResource        `yaml:"secrets"`
+
+	// BootstrapJobs lists the Job files that must complete before the
+	// following resources are created, BootstrapTimeout is in seconds
+	BootstrapJobs    []string `yaml:"bootstrap_jobs"`
+	BootstrapTimeout int      `yaml:"bootstrap_timeout"`
+
+	// Charts and the kustomization are rendered locally into objects
+	// created through the plugins
+	Charts    []ChartResource    `yaml:"charts"`
+	Kustomize *KustomizeResource `yaml:"kustomize"`
+
+	// ScalingAspects are the named replica sets a VNF can be scaled to
+	ScalingAspects []ScalingAspect `yaml:"scaling_aspects"`
+
+	// Parameters declares the {{ name }} placeholders of the manifests with
+	// their default values
+	Parameters map[string]string `yaml:"parameters"`
+}
+
+// defaultBootstrapTimeout is used when the metadata doesn't set one
+const defaultBootstrapTimeout = 300 * time.Second
+
+func (m MetadataFile) isBootstrapJob(filename string) bool {
+	for _, job := range m.BootstrapJobs {
+		if job == filename {
+			return true
+		}
+	}
+	return false
+}
+
+// parameterValues returns the declared parameters with their values
+// overridden by the ones requested. Undeclared parameters are ignored.
+func (m MetadataFile) parameterValues(values map[string]string) map[string]string {
+	result := make(map[string]string)
+	for name, value := range m.Parameters {
+		if requested, ok := values[name]; ok {
+			value = requested
+		}
+		result[name] = value
+	}
+	return result
+}
+
+func (m MetadataFile) bootstrapTimeout() time.Duration {
+	if m.BootstrapTimeout <= 0 {
+		return defaultBootstrapTimeout
+	}
+	return time.Duration(m.BootstrapTimeout) * time.Second
+}
+
+// csarResource is an object created from a CSAR, either from a YAML manifest,
+// from raw files or from a chart template rendered in manifest
+type csarResource struct {
+	resourceName string
+	filename     string
+	fileResource *FileResource
+	manifest     []byte
+}
+
+// orderedResources returns the objects described by the metadata in creation
+// order. ConfigMaps and Secrets built from raw files are created first so
+// workloads can reference them.
+func (m MetadataFile) orderedResources() []csarResource {
+	var resources []csarResource
+
+	for i := range m.ConfigMaps {
+		resources = append(resources, csarResource{resourceName: "configmap", fileResource: &m.ConfigMaps[i]})
+	}
+	for i := range m.Secrets {
+		resources = append(resources, csarResource{resourceName: "secret", fileResource: &m.Secrets[i]})
+	}
+
+	for _, resource := range m.ResourceTypePathMap {
+		for resourceName, resourceFileNames := range resource {
+			for _, filename := range resourceFileNames {
+				resources = append(resources, csarResource{resourceName: resourceName, filename: filename})
+			}
+		}
+	}
+
+	return resources
+}
+
+// FileResource describes an object built from raw files stored in the CSAR,
+// Files maps every data key to a file path relative to the CSAR directory
+type FileResource struct {
+	Name  string            `yaml:"name"`
+	Files map[string]string `yaml:"files"`
 }
 
 // ReadMetadataFile reads the metadata yaml to return the order or reads
@@ -31,14 +31,26 @@ func LoadMockPlugins(krdLoadedPlugins *map[string]*plugin.Plugin) error {
 		return pkgerrors.New("mockplugin.so does not exist. Please compile mockplugin.go to generate")
 	}
 
+	if _, err := os.Stat("./mock_plugins/namespace/mockplugin.so"); os.IsNotExist(err) {
+		return pkgerrors.New("namespace/mockplugin.so does not exist. Please compile namespace/mockplugin.go to generate")
+	}
+
 	mockPlugin, err := plugin.Open("./mock_plugins/mockplugin.so")
 	if err != nil {
 		return pkgerrors.Cause(err)
 	}
 
-	(*krdLoadedPlugins)["namespace"] = mockPlugin
+	mockNamespacePlugin, err := plugin.Open("./mock_plugins/namespace/mockplugin.so")
+	if err != nil {
+		return pkgerrors.Cause(err)
+	}
+
+	(*krdLoadedPlugins)["namespace"] = mockNamespacePlugin
 	(*krdLoadedPlugins)["deployment"] = mockPlugin
 	(*krdLoadedPlugins)["service"] = mockPlugin
+	(*krdLoadedPlugins)["configmap"] = mockPlugin
+	(*krdLoadedPlugins)["secret"] = mockPlugin
+	(*krdLoadedPlugins)["job"] = mockPlugin
 
 	return nil
 }
@@ -78,7 +90,7 @@ func TestCreateVNF(t *testing.T) {
 	kubeclient := kubernetes.Clientset{}
 
 	t.Run("Successfully create VNF", func(t *testing.T) {
-		externaluuid, data, err := CreateVNF("uuid", "cloudregion1", "test", &kubeclient)
+		externaluuid, data, err := CreateVNF("uuid", "cloudregion1", "test", nil, "", nil, &kubeclient)
 		if err != nil {
 			t.Fatalf("TestCreateVNF returned an error (%s)", err)
 		}
@@ -90,6 +102,55 @@ func TestCreateVNF(t *testing.T) {
 		}
 	})
 
+	t.Run("Successfully create VNF with files from the CSAR", func(t *testing.T) {
+		oldCsarDir := os.Getenv("CSAR_DIR")
+		os.Setenv("CSAR_DIR", ".")
+		defer os.Setenv("CSAR_DIR", oldCsarDir)
+
+		_, data, err := CreateVNF("mock_yamls", "cloudregion1", "test", nil, "", nil, &kubeclient)
+		if err != nil {
+			t.Fatalf("TestCreateVNF returned an error (%s)", err)
+		}
+
+		for _, resourceName := range []string{"configmap", "job", "deployment", "service"} {
+			if len(data[resourceName]) != 1 {
+				t.Fatalf("TestCreateVNF returned unexpected %s list (%v)", resourceName, data)
+			}
+		}
+	})
+	t.Run("Delete the created objects when the bootstrap job fails", func(t *testing.T) {
+		oldCsarDir := os.Getenv("CSAR_DIR")
+		os.Setenv("CSAR_DIR", ".")
+		defer os.Setenv("CSAR_DIR", oldCsarDir)
+
+		symWaitError, err := krd.LoadedPlugins["job"].Lookup("WaitForResourceError")
+		if err != nil {
+			t.Fatalf("TestCreateVNF returned an error (%s)", err)
+		}
+		waitError := symWaitError.(*error)
+		*waitError = pkgerrors.New("Job timed out")
+		defer func() {
+			*waitError = nil
+		}()
+
+		symDeleted, err := krd.LoadedPlugins["job"].Lookup("DeletedResources")
+		if err != nil {
+			t.Fatalf("TestCreateVNF returned an error (%s)", err)
+		}
+		deleted := symDeleted.(*[]string)
+		*deleted = nil
+
+		_, _, err = CreateVNF("mock_yamls", "cloudregion1", "test", nil, "", nil, &kubeclient)
+		if err == nil {
+			t.Fatalf("TestCreateVNF didn't return an error for a failed bootstrap job")
+		}
+
+		// The configmap and the job were created, the workloads weren't
+		if len(*deleted) != 2 {
+			t.Fatalf("TestCreateVNF deleted unexpected resources (%v)", *deleted)
+		}
+	})
+
 }
 
 func TestDeleteVNF(t *testing.T) {
@@ -112,11 +173,82 @@ func TestDeleteVNF(t *testing.T) {
 			"service":    []string{"cloud1-default-uuid-sisesvc"},
 		}
 
-		err := DestroyVNF(data, "test", &kubeclient)
+		err := DestroyVNF(data, "test", StoragePolicyDelete, &kubeclient)
 		if err != nil {
 			t.Fatalf("TestCreateVNF returned an error (%s)", err)
 		}
 	})
+
+	t.Run("Successfully delete VNF retaining its storage", func(t *testing.T) {
+		data := map[string][]string{
+			"deployment": []string{"cloud1-default-uuid-sisedeploy"},
+			"pvc":        []string{"cloud1-default-uuid-sisedata"},
+		}
+
+		oldRetainClaims := RetainClaims
+		defer func() {
+			RetainClaims = oldRetainClaims
+		}()
+
+		var retained []string
+		RetainClaims = func(names []string, namespace string, value bool, kubeclient *kubernetes.Clientset) error {
+			retained = append(retained, names...)
+			return nil
+		}
+
+		krd.LoadedPlugins["pvc"] = krd.LoadedPlugins["deployment"]
+		defer delete(krd.LoadedPlugins, "pvc")
+
+		symDeleted, err := krd.LoadedPlugins["pvc"].Lookup("DeletedResources")
+		if err != nil {
+			t.Fatalf("TestDeleteVNF returned an error (%s)", err)
+		}
+		deleted := symDeleted.(*[]string)
+		*deleted = nil
+
+		err = DestroyVNF(data, "test", StoragePolicyRetain, &kubeclient)
+		if err != nil {
+			t.Fatalf("TestDeleteVNF returned an error (%s)", err)
+		}
+
+		if len(retained) != 1 || retained[0] != "cloud1-default-uuid-sisedata" {
+			t.Fatalf("TestDeleteVNF didn't label the retained claims (%v)", retained)
+		}
+		if len(*deleted) != 1 || (*deleted)[0] != "cloud1-default-uuid-sisedeploy" {
+			t.Fatalf("TestDeleteVNF deleted unexpected resources (%v)", *deleted)
+		}
+	})
+}
+
+func TestDiscoverVNF(t *testing.T) {
+	oldkrdPluginData := krd.LoadedPlugins
+
+	defer func() {
+		krd.LoadedPlugins = oldkrdPluginData
+	}()
+
+	krd.LoadedPlugins = map[string]*plugin.Plugin{}
+	err := LoadMockPlugins(&krd.LoadedPlugins)
+	if err != nil {
+		t.Fatalf("TestDiscoverVNF returned an error (%s)", err)
+	}
+
+	kubeclient := kubernetes.Clientset{}
+
+	t.Run("Successfully discover VNF", func(t *testing.T) {
+		data, err := DiscoverVNF("uuid", "test", &kubeclient)
+		if err != nil {
+			t.Fatalf("TestDiscoverVNF returned an error (%s)", err)
+		}
+
+		if _, ok := data["namespace"]; ok {
+			t.Fatalf("TestDiscoverVNF returned namespaces (%v)", data)
+		}
+
+		if len(data["deployment"]) == 0 {
+			t.Fatalf("TestDiscoverVNF returned empty data (%v)", data)
+		}
+	})
 }
 
 func TestReadMetadataFile(t *testing.T) {
@@ -127,3 +259,20 @@ func TestReadMetadataFile(t *testing.T) {
 		}
 	})
 }
+
+func TestCSARFilePath(t *testing.T) {
+	t.Run("Successfully resolve a file of the CSAR", func(t *testing.T) {
+		path, err := csarFilePath("/csars/uuid", "config/../files/app.conf")
+		if err != nil || path != "/csars/uuid/files/app.conf" {
+			t.Fatalf("TestCSARFilePath returned:\n result=%v %v\n expected=%v", path, err, "/csars/uuid/files/app.conf")
+		}
+	})
+	t.Run("Files outside of the CSAR failure", func(t *testing.T) {
+		for _, filename := range []string{"/etc/passwd", "../other/secret", "files/../../etc/passwd"} {
+			_, err := csarFilePath("/csars/uuid", filename)
+			if err == nil {
+				t.Fatalf("TestCSARFilePath accepted %s", filename)
+			}
+		}
+	})
+}
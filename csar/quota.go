@@ -0,0 +1,198 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csar
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+	"gopkg.in/inf.v0"
+	appsV1 "k8s.io/api/apps/v1"
+	batchV1 "k8s.io/api/batch/v1"
+	batchV1beta1 "k8s.io/api/batch/v1beta1"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"k8-plugin-multicloud/krd"
+)
+
+// quotaAliases maps the ResourceQuota names sharing the same usage
+var quotaAliases = map[coreV1.ResourceName]coreV1.ResourceName{
+	coreV1.ResourceCPU:    coreV1.ResourceRequestsCPU,
+	coreV1.ResourceMemory: coreV1.ResourceRequestsMemory,
+}
+
+// VNFRequests returns the resources declared by the objects a VNF would be
+// created with from a CSAR, keyed by ResourceQuota resource name. Daemonsets
+// are counted once as their number of pods depends on the cluster.
+var VNFRequests = func(csarID string, cloudRegionID string, namespace string, parameters map[string]string,
+	overlay string) (coreV1.ResourceList, error) {
+
+	objects, err := renderObjects(os.Getenv("CSAR_DIR")+"/"+csarID, csarID, cloudRegionID, namespace, "", parameters, overlay)
+	if err != nil {
+		return nil, err
+	}
+
+	requested := coreV1.ResourceList{}
+	for _, obj := range objects {
+		switch o := obj.(type) {
+		case *appsV1.Deployment:
+			addPodRequests(requested, &o.Spec.Template.Spec, replicaCount(o.Spec.Replicas))
+		case *appsV1.StatefulSet:
+			addPodRequests(requested, &o.Spec.Template.Spec, replicaCount(o.Spec.Replicas))
+		case *appsV1.DaemonSet:
+			addPodRequests(requested, &o.Spec.Template.Spec, 1)
+		case *batchV1.Job:
+			addPodRequests(requested, &o.Spec.Template.Spec, replicaCount(o.Spec.Parallelism))
+		case *batchV1beta1.CronJob:
+			addPodRequests(requested, &o.Spec.JobTemplate.Spec.Template.Spec, replicaCount(o.Spec.JobTemplate.Spec.Parallelism))
+		case *coreV1.PersistentVolumeClaim:
+			addQuantity(requested, coreV1.ResourcePersistentVolumeClaims, *resource.NewQuantity(1, resource.DecimalSI), 1)
+			if storage, ok := o.Spec.Resources.Requests[coreV1.ResourceStorage]; ok {
+				addQuantity(requested, coreV1.ResourceRequestsStorage, storage, 1)
+			}
+		}
+	}
+
+	return requested, nil
+}
+
+// replicaCount returns the number of pods of a workload, 1 by default
+func replicaCount(replicas *int32) int64 {
+	if replicas == nil {
+		return 1
+	}
+	return int64(*replicas)
+}
+
+// addPodRequests adds the requests and limits of count pods to requested.
+// Like for the scheduler, a pod needs the sum of its containers or the most
+// any of its init containers needs, which run one after the other.
+func addPodRequests(requested coreV1.ResourceList, spec *coreV1.PodSpec, count int64) {
+	if count == 0 {
+		return
+	}
+
+	pod := coreV1.ResourceList{}
+	addQuantity(pod, coreV1.ResourcePods, *resource.NewQuantity(1, resource.DecimalSI), 1)
+
+	for _, container := range spec.Containers {
+		for name, quantity := range containerRequests(container) {
+			addQuantity(pod, name, quantity, 1)
+		}
+	}
+
+	for _, container := range spec.InitContainers {
+		for name, quantity := range containerRequests(container) {
+			if current, ok := pod[name]; !ok || quantity.Cmp(current) > 0 {
+				pod[name] = quantity
+			}
+		}
+	}
+
+	for name, quantity := range pod {
+		addQuantity(requested, name, quantity, count)
+	}
+}
+
+// containerRequests returns the requests and limits of a container keyed by
+// ResourceQuota resource name. The requests default to the limits.
+func containerRequests(container coreV1.Container) coreV1.ResourceList {
+	requested := coreV1.ResourceList{}
+
+	for name, quantity := range container.Resources.Limits {
+		switch name {
+		case coreV1.ResourceCPU:
+			requested[coreV1.ResourceLimitsCPU] = quantity
+		case coreV1.ResourceMemory:
+			requested[coreV1.ResourceLimitsMemory] = quantity
+		}
+	}
+
+	requests := container.Resources.Requests
+	for _, name := range []coreV1.ResourceName{coreV1.ResourceCPU, coreV1.ResourceMemory} {
+		quantity, ok := requests[name]
+		if !ok {
+			quantity, ok = container.Resources.Limits[name]
+		}
+		if ok {
+			requested[quotaAliases[name]] = quantity
+		}
+	}
+
+	return requested
+}
+
+// addQuantity adds count times quantity to a resource of list. The product is
+// computed with arbitrary precision, so large counts can't overflow.
+func addQuantity(list coreV1.ResourceList, name coreV1.ResourceName, quantity resource.Quantity, count int64) {
+	product := new(inf.Dec).Mul(quantity.AsDec(), inf.NewDec(count, 0))
+
+	total := list[name]
+	total.Add(*resource.NewDecimalQuantity(*product, quantity.Format))
+	list[name] = total
+}
+
+// CheckQuota returns an error naming the resources whose requested amount
+// exceeds what is left of the ResourceQuotas of a namespace. Namespaces
+// without quota are checked against the quota profile they are created with.
+var CheckQuota = func(requested coreV1.ResourceList, namespace string, profile *krd.QuotaProfile, kubeclient *kubernetes.Clientset) error {
+	quotas, err := kubeclient.CoreV1().ResourceQuotas(namespace).List(metaV1.ListOptions{})
+	if err != nil {
+		return pkgerrors.Wrap(err, "List ResourceQuotas error")
+	}
+
+	statuses := make([]coreV1.ResourceQuotaStatus, 0, len(quotas.Items))
+	for _, quota := range quotas.Items {
+		statuses = append(statuses, quota.Status)
+	}
+	if len(statuses) == 0 && profile != nil && profile.ResourceQuota != nil {
+		statuses = append(statuses, coreV1.ResourceQuotaStatus{Hard: profile.ResourceQuota.Hard})
+	}
+
+	var exceeded []string
+	for _, status := range statuses {
+		for name, hard := range status.Hard {
+			requestedName := name
+			if alias, ok := quotaAliases[name]; ok {
+				requestedName = alias
+			}
+
+			quantity, ok := requested[requestedName]
+			if !ok {
+				continue
+			}
+
+			remaining := hard.DeepCopy()
+			if used, ok := status.Used[name]; ok {
+				remaining.Sub(used)
+			}
+
+			if quantity.Cmp(remaining) > 0 {
+				exceeded = append(exceeded, string(name)+" requested "+quantity.String()+", remaining "+remaining.String())
+			}
+		}
+	}
+
+	if len(exceeded) > 0 {
+		sort.Strings(exceeded)
+		return pkgerrors.New("Quota of namespace " + namespace + " exceeded: " + strings.Join(exceeded, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csar
+
+import (
+	"testing"
+
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestAddPodRequests(t *testing.T) {
+	spec := &coreV1.PodSpec{
+		Containers: []coreV1.Container{
+			{
+				Name: "sise",
+				Resources: coreV1.ResourceRequirements{
+					Requests: coreV1.ResourceList{
+						coreV1.ResourceCPU:    resource.MustParse("250m"),
+						coreV1.ResourceMemory: resource.MustParse("128Mi"),
+					},
+					Limits: coreV1.ResourceList{
+						coreV1.ResourceCPU: resource.MustParse("500m"),
+					},
+				},
+			},
+			{
+				Name: "sidecar",
+				Resources: coreV1.ResourceRequirements{
+					Limits: coreV1.ResourceList{
+						coreV1.ResourceMemory: resource.MustParse("64Mi"),
+					},
+				},
+			},
+		},
+	}
+
+	requested := coreV1.ResourceList{}
+	addPodRequests(requested, spec, 3)
+
+	expected := map[coreV1.ResourceName]string{
+		coreV1.ResourcePods:           "3",
+		coreV1.ResourceRequestsCPU:    "750m",
+		coreV1.ResourceRequestsMemory: "576Mi",
+		coreV1.ResourceLimitsCPU:      "1500m",
+		coreV1.ResourceLimitsMemory:   "192Mi",
+	}
+
+	for name, value := range expected {
+		quantity := requested[name]
+		if quantity.Cmp(resource.MustParse(value)) != 0 {
+			t.Fatalf("TestAddPodRequests returned %s=%s, expected %s", name, quantity.String(), value)
+		}
+	}
+}
+
+func TestAddPodRequestsInitContainers(t *testing.T) {
+	spec := &coreV1.PodSpec{
+		InitContainers: []coreV1.Container{
+			{
+				Name: "migrate",
+				Resources: coreV1.ResourceRequirements{
+					Requests: coreV1.ResourceList{
+						coreV1.ResourceCPU:    resource.MustParse("1"),
+						coreV1.ResourceMemory: resource.MustParse("64Mi"),
+					},
+				},
+			},
+		},
+		Containers: []coreV1.Container{
+			{
+				Name: "sise",
+				Resources: coreV1.ResourceRequirements{
+					Requests: coreV1.ResourceList{
+						coreV1.ResourceCPU:    resource.MustParse("250m"),
+						coreV1.ResourceMemory: resource.MustParse("128Mi"),
+					},
+				},
+			},
+		},
+	}
+
+	requested := coreV1.ResourceList{}
+	addPodRequests(requested, spec, 2)
+
+	expected := map[coreV1.ResourceName]string{
+		coreV1.ResourcePods:           "2",
+		coreV1.ResourceRequestsCPU:    "2",
+		coreV1.ResourceRequestsMemory: "256Mi",
+	}
+
+	for name, value := range expected {
+		quantity := requested[name]
+		if quantity.Cmp(resource.MustParse(value)) != 0 {
+			t.Fatalf("TestAddPodRequestsInitContainers returned %s=%s, expected %s", name, quantity.String(), value)
+		}
+	}
+}
+
+func TestAddQuantity(t *testing.T) {
+	requested := coreV1.ResourceList{}
+	addQuantity(requested, coreV1.ResourceRequestsMemory, resource.MustParse("1Gi"), 2000000000)
+	addQuantity(requested, coreV1.ResourceRequestsMemory, resource.MustParse("1Gi"), 2000000000)
+
+	quantity := requested[coreV1.ResourceRequestsMemory]
+	if quantity.Cmp(resource.MustParse("4000000000Gi")) != 0 {
+		t.Fatalf("TestAddQuantity returned %s, expected 4000000000Gi", quantity.String())
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csar
+
+import (
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+)
+
+// RenderVNF returns the multi-document YAML of the objects CreateVNF would
+// create from a CSAR directory, with their final names, namespace, labels
+// and parameters, without contacting any cluster. A VNF ID is generated when
+// none is given.
+var RenderVNF = func(csarDirPath string, csarID string, cloudRegionID string, namespace string, externalVNFID string,
+	parameters map[string]string, overlay string) (string, error) {
+
+	objects, err := renderObjects(csarDirPath, csarID, cloudRegionID, namespace, externalVNFID, parameters, overlay)
+	if err != nil {
+		return "", err
+	}
+
+	var manifests []string
+	for _, obj := range objects {
+		manifest, err := renderObject(obj)
+		if err != nil {
+			return "", err
+		}
+		manifests = append(manifests, manifest)
+	}
+
+	return strings.Join(manifests, "---\n"), nil
+}
+
+// renderObjects returns the objects CreateVNF would create from a CSAR
+// directory, in creation order. The CSAR is verified first like by CreateVNF.
+func renderObjects(csarDirPath string, csarID string, cloudRegionID string, namespace string, externalVNFID string,
+	parameters map[string]string, overlay string) ([]runtime.Object, error) {
+
+	if externalVNFID == "" {
+		externalVNFID = string(uuid.NewUUID())
+	}
+
+	vnf := newVNFInstance(csarID, cloudRegionID, namespace, externalVNFID)
+	vnf.csarDirPath = csarDirPath
+
+	err := vnf.verify()
+	if err != nil {
+		return nil, err
+	}
+	defer vnf.release()
+
+	seqFile, err := readCSARMetadata(vnf.csarDirPath)
+	if err != nil {
+		return nil, err
+	}
+	vnf.parameters = seqFile.parameterValues(parameters)
+	vnf.overlay = overlay
+
+	resources, err := vnf.resources(seqFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// The plugins don't use the client when only rendering
+	kubeclient := &kubernetes.Clientset{}
+
+	var objects []runtime.Object
+	for _, resource := range resources {
+		genericKubeData, err := vnf.kubeData(resource)
+		if err != nil {
+			return nil, err
+		}
+		genericKubeData.RenderOnly = true
+
+		internalResourceName, err := createResource(resource.resourceName, genericKubeData, kubeclient)
+		if err != nil {
+			return nil, err
+		}
+
+		if genericKubeData.Rendered == nil {
+			return nil, pkgerrors.New("The " + resource.resourceName + " plugin doesn't support rendering")
+		}
+		objects = append(objects, genericKubeData.Rendered)
+
+		vnf.addResource(resource.resourceName, internalResourceName)
+	}
+
+	return objects, nil
+}
+
+// redactedValue replaces the values of the Secrets redacted by RedactSecrets
+const redactedValue = "REDACTED"
+
+// RedactSecrets replaces the values of the data and stringData of the Secrets
+// of the multi-document YAML returned by RenderVNF, for the callers which may
+// render a CSAR but not read the files it ships
+func RedactSecrets(manifests string) (string, error) {
+	documents := documentSeparator.Split(manifests, -1)
+	for i, document := range documents {
+		if i > 0 {
+			// Left by the separator line
+			document = strings.TrimPrefix(document, "\n")
+			documents[i] = document
+		}
+
+		var manifest yaml.MapSlice
+		err := yaml.Unmarshal([]byte(document), &manifest)
+		if err != nil {
+			return "", pkgerrors.Wrap(err, "Parse rendered manifest error")
+		}
+
+		secret := false
+		for _, item := range manifest {
+			if item.Key == "kind" && item.Value == "Secret" {
+				secret = true
+			}
+		}
+		if !secret {
+			continue
+		}
+
+		for _, item := range manifest {
+			if item.Key != "data" && item.Key != "stringData" {
+				continue
+			}
+			values, ok := item.Value.(yaml.MapSlice)
+			if !ok {
+				continue
+			}
+			for j := range values {
+				values[j].Value = redactedValue
+			}
+		}
+
+		out, err := yaml.Marshal(manifest)
+		if err != nil {
+			return "", pkgerrors.Wrap(err, "Redact rendered Secret error")
+		}
+		documents[i] = string(out)
+	}
+
+	return strings.Join(documents, "---\n"), nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csar
+
+import (
+	"log"
+	"os"
+
+	"k8-plugin-multicloud/db"
+)
+
+// Lifecycle operations recorded in the VNF revisions
+const (
+	OperationCreate   = "create"
+	OperationAdopt    = "adopt"
+	OperationScale    = "scale"
+	OperationUpgrade  = "upgrade"
+	OperationRollback = "rollback"
+	OperationStop     = "stop"
+	OperationStart    = "start"
+	OperationHeal     = "heal"
+	OperationRecreate = "recreate"
+)
+
+// RecordRevision stores the state of a VNF after a lifecycle operation along
+// with the manifests rendered from its CSAR and the digest of the CSAR they
+// were rendered from. Failures are only logged as the operation itself
+// already succeeded.
+func RecordRevision(internalVNFID string, externalVNFID string, record db.VNFRecord, operation string) {
+	revision := db.Revision{
+		Operation:  operation,
+		CsarID:     record.CsarID,
+		Parameters: record.Parameters,
+		Overlay:    record.Overlay,
+		Replicas:   record.Replicas,
+		Resources:  record.Resources,
+	}
+
+	if record.CsarID != "" {
+		digest, err := CSARDigest(record.CsarID)
+		if err != nil {
+			log.Println("Digest revision CSAR of " + internalVNFID + " error: " + err.Error())
+		}
+		revision.CsarDigest = digest
+
+		manifests, err := RenderVNF(os.Getenv("CSAR_DIR")+"/"+record.CsarID, record.CsarID,
+			record.CloudRegionID, record.Namespace, externalVNFID, record.Parameters, record.Overlay)
+		if err != nil {
+			log.Println("Render revision of " + internalVNFID + " error: " + err.Error())
+		}
+		revision.Manifests = manifests
+	}
+
+	_, err := db.WriteRevision(internalVNFID, revision)
+	if err != nil {
+		log.Println("Record revision of " + internalVNFID + " error: " + err.Error())
+	}
+}
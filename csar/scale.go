@@ -0,0 +1,147 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csar
+
+import (
+	pkgerrors "github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+
+	"k8-plugin-multicloud/krd"
+)
+
+// ScalingAspect is a named set of replica counts for the workloads of a VNF,
+// keyed by their name in the CSAR
+type ScalingAspect struct {
+	Name     string           `yaml:"name"`
+	Replicas map[string]int32 `yaml:"replicas"`
+}
+
+// scalableResources are the plugins exporting ScaleResource
+var scalableResources = []string{"deployment", "statefulset"}
+
+// ReadScalingAspect returns the replica counts of a scaling aspect declared in
+// the metadata of a CSAR, once verified for the cloud region of the VNF
+var ReadScalingAspect = func(csarID string, cloudRegionID string, name string) (map[string]int32, error) {
+	vnf := newVNFInstance(csarID, cloudRegionID, "", "")
+	err := vnf.verify()
+	if err != nil {
+		return nil, err
+	}
+	defer vnf.release()
+
+	seqFile, err := readCSARMetadata(vnf.csarDirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, aspect := range seqFile.ScalingAspects {
+		if aspect.Name == name {
+			return aspect.Replicas, nil
+		}
+	}
+
+	return nil, pkgerrors.New("Scaling aspect " + name + " not found in CSAR " + csarID)
+}
+
+// ScaleVNF sets the number of replicas of the deployments and statefulsets of
+// a VNF, keyed by object name, using the ScaleResource function of their plugin
+var ScaleVNF = func(data map[string][]string, namespace string, replicas map[string]int32, kubeclient *kubernetes.Clientset) error {
+	for name, count := range replicas {
+		resourceName := ""
+		for _, scalable := range scalableResources {
+			if containsString(data[scalable], name) {
+				resourceName = scalable
+				break
+			}
+		}
+		if resourceName == "" {
+			return pkgerrors.New(name + " is not a deployment or statefulset of the VNF")
+		}
+
+		typePlugin, ok := krd.LoadedPlugins[resourceName]
+		if !ok {
+			return pkgerrors.New("No plugin for resource " + resourceName + " found")
+		}
+
+		symScaleResourceFunc, err := typePlugin.Lookup("ScaleResource")
+		if err != nil {
+			return pkgerrors.Wrap(err, "Error fetching "+resourceName+" plugin")
+		}
+
+		err = symScaleResourceFunc.(func(string, string, int32, *kubernetes.Clientset) error)(
+			name, namespace, count, kubeclient)
+		if err != nil {
+			return pkgerrors.Wrap(err, "Error scaling "+name)
+		}
+	}
+
+	return nil
+}
+
+// RestoreReplicas scales the recreated workloads of a VNF back to the replica
+// counts stored for it, or to zero while the VNF is stopped, instead of the
+// counts of their CSAR
+func RestoreReplicas(recreated map[string][]string, namespace string, replicas map[string]int32, stopped bool,
+	kubeclient *kubernetes.Clientset) error {
+	counts := make(map[string]int32)
+	for _, resourceName := range scalableResources {
+		for _, name := range recreated[resourceName] {
+			if stopped {
+				counts[name] = 0
+			} else if count, ok := replicas[name]; ok {
+				counts[name] = count
+			}
+		}
+	}
+
+	if len(counts) == 0 {
+		return nil
+	}
+
+	return ScaleVNF(recreated, namespace, counts, kubeclient)
+}
+
+// ReadReplicas returns the number of replicas of the deployments and
+// statefulsets of a VNF, keyed by object name, using the GetReplicas function
+// of their plugin
+var ReadReplicas = func(data map[string][]string, namespace string, kubeclient *kubernetes.Clientset) (map[string]int32, error) {
+	replicas := make(map[string]int32)
+
+	for _, resourceName := range scalableResources {
+		if len(data[resourceName]) == 0 {
+			continue
+		}
+
+		typePlugin, ok := krd.LoadedPlugins[resourceName]
+		if !ok {
+			return nil, pkgerrors.New("No plugin for resource " + resourceName + " found")
+		}
+
+		symGetReplicasFunc, err := typePlugin.Lookup("GetReplicas")
+		if err != nil {
+			return nil, pkgerrors.Wrap(err, "Error fetching "+resourceName+" plugin")
+		}
+
+		for _, name := range data[resourceName] {
+			count, err := symGetReplicasFunc.(func(string, string, *kubernetes.Clientset) (int32, error))(
+				name, namespace, kubeclient)
+			if err != nil {
+				return nil, pkgerrors.Wrap(err, "Error getting replicas of "+name)
+			}
+			replicas[name] = count
+		}
+	}
+
+	return replicas, nil
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csar
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"k8-plugin-multicloud/krd"
+)
+
+// toscaMetaPath is the location of the TOSCA metadata in a standard CSAR
+const toscaMetaPath = "TOSCA-Metadata/TOSCA.meta"
+
+// isTOSCA tells whether a CSAR follows the TOSCA layout
+func isTOSCA(csarDirPath string) bool {
+	_, err := os.Stat(csarDirPath + "/" + toscaMetaPath)
+	return err == nil
+}
+
+// readCSARMetadata returns the metadata of a CSAR, translated from its VNFD
+// for TOSCA CSARs or read from metadata.yaml for legacy packages
+func readCSARMetadata(csarDirPath string) (MetadataFile, error) {
+	if isTOSCA(csarDirPath) {
+		seqFile, err := ReadTOSCAMetadata(csarDirPath)
+		if err != nil {
+			return seqFile, pkgerrors.Wrap(err, "Error while reading TOSCA CSAR: "+csarDirPath)
+		}
+		return seqFile, nil
+	}
+
+	metadataYAMLPath := csarDirPath + "/metadata.yaml"
+	seqFile, err := ReadMetadataFile(metadataYAMLPath)
+	if err != nil {
+		return seqFile, pkgerrors.Wrap(err, "Error while reading Metadata File: "+metadataYAMLPath)
+	}
+	return seqFile, nil
+}
+
+// toscaServiceTemplate is the part of a VNFD used to find the Kubernetes
+// artifacts of a VNF
+type toscaServiceTemplate struct {
+	TopologyTemplate struct {
+		Inputs map[string]struct {
+			Default interface{} `yaml:"default"`
+		} `yaml:"inputs"`
+		NodeTemplates map[string]toscaNodeTemplate `yaml:"node_templates"`
+	} `yaml:"topology_template"`
+}
+
+type toscaNodeTemplate struct {
+	Type      string                   `yaml:"type"`
+	Artifacts map[string]toscaArtifact `yaml:"artifacts"`
+}
+
+// toscaArtifact accepts both the short notation, only the file, and the
+// extended one with the artifact type
+type toscaArtifact struct {
+	Type string `yaml:"type"`
+	File string `yaml:"file"`
+}
+
+func (a *toscaArtifact) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var file string
+	if err := unmarshal(&file); err == nil {
+		a.File = file
+		return nil
+	}
+
+	type extended toscaArtifact
+	return unmarshal((*extended)(a))
+}
+
+// isVDU tells whether a node template is a Virtualisation Deployment Unit
+func (n toscaNodeTemplate) isVDU() bool {
+	return strings.Contains(strings.ToLower(n.Type), ".vdu")
+}
+
+// manifestKind matches the kind of a manifest, which may still contain
+// template placeholders
+var manifestKind = regexp.MustCompile(`(?m)^kind:\s*(\S+)\s*$`)
+
+// ReadTOSCAMetadata reads a TOSCA CSAR: TOSCA-Metadata/TOSCA.meta points to
+// the VNFD whose VDU artifacts are the Kubernetes manifests and Helm charts
+// of the VNF. The VNFD inputs are the parameters of the manifests.
+var ReadTOSCAMetadata = func(csarDirPath string) (MetadataFile, error) {
+	var seqFile MetadataFile
+
+	meta, err := readTOSCAMeta(csarDirPath + "/" + toscaMetaPath)
+	if err != nil {
+		return seqFile, err
+	}
+
+	entryDefinitions := meta["Entry-Definitions"]
+	if entryDefinitions == "" {
+		return seqFile, pkgerrors.New("Missing Entry-Definitions in " + toscaMetaPath)
+	}
+
+	vnfdPath, err := csarFilePath(csarDirPath, entryDefinitions)
+	if err != nil {
+		return seqFile, err
+	}
+
+	log.Println("Reading VNFD: " + entryDefinitions)
+	rawBytes, err := ioutil.ReadFile(vnfdPath)
+	if err != nil {
+		return seqFile, pkgerrors.Wrap(err, "VNFD file read error")
+	}
+
+	var template toscaServiceTemplate
+	err = yaml.Unmarshal(rawBytes, &template)
+	if err != nil {
+		return seqFile, pkgerrors.Wrap(err, "VNFD file read error")
+	}
+
+	if len(template.TopologyTemplate.Inputs) > 0 {
+		seqFile.Parameters = make(map[string]string)
+		for name, input := range template.TopologyTemplate.Inputs {
+			if input.Default != nil {
+				seqFile.Parameters[name] = fmt.Sprint(input.Default)
+			}
+		}
+	}
+
+	nodeNames := make([]string, 0, len(template.TopologyTemplate.NodeTemplates))
+	for name := range template.TopologyTemplate.NodeTemplates {
+		nodeNames = append(nodeNames, name)
+	}
+	sort.Strings(nodeNames)
+
+	for _, nodeName := range nodeNames {
+		node := template.TopologyTemplate.NodeTemplates[nodeName]
+		if !node.isVDU() {
+			continue
+		}
+
+		artifactNames := make([]string, 0, len(node.Artifacts))
+		for name := range node.Artifacts {
+			artifactNames = append(artifactNames, name)
+		}
+		sort.Strings(artifactNames)
+
+		for _, artifactName := range artifactNames {
+			err = addTOSCAArtifact(&seqFile, csarDirPath, node.Artifacts[artifactName])
+			if err != nil {
+				return seqFile, pkgerrors.Wrap(err, "VDU "+nodeName+" artifact "+artifactName+" error")
+			}
+		}
+	}
+
+	// Like the objects rendered from charts, the manifests are created
+	// following kindOrder, e.g. the ConfigMaps before the Deployments
+	sort.SliceStable(seqFile.ResourceTypePathMap, func(i, j int) bool {
+		return kindIndex(artifactKind(seqFile.ResourceTypePathMap[i])) <
+			kindIndex(artifactKind(seqFile.ResourceTypePathMap[j]))
+	})
+
+	return seqFile, nil
+}
+
+// artifactKind returns the kind of the manifest of a resources entry added by
+// addTOSCAArtifact
+func artifactKind(resource map[string][]string) string {
+	for resourceName := range resource {
+		return krd.ResourceKinds[resourceName]
+	}
+	return ""
+}
+
+// addTOSCAArtifact maps a VDU artifact to a Helm chart or to the resource
+// plugin handling the kind of its manifest. Other artifacts are ignored.
+func addTOSCAArtifact(seqFile *MetadataFile, csarDirPath string, artifact toscaArtifact) error {
+	file := strings.TrimPrefix(artifact.File, "/")
+
+	switch {
+	case strings.Contains(strings.ToLower(artifact.Type), "helm"),
+		strings.HasSuffix(file, ".tgz"), strings.HasSuffix(file, ".tar.gz"):
+		seqFile.Charts = append(seqFile.Charts, ChartResource{Chart: file})
+
+	case strings.HasSuffix(file, ".yaml"), strings.HasSuffix(file, ".yml"):
+		path, err := csarFilePath(csarDirPath, file)
+		if err != nil {
+			return err
+		}
+
+		rawBytes, err := ioutil.ReadFile(path)
+		if err != nil {
+			return pkgerrors.Wrap(err, "Read "+file+" error")
+		}
+
+		match := manifestKind.FindSubmatch(rawBytes)
+		if match == nil {
+			return pkgerrors.New(file + " is not a Kubernetes manifest")
+		}
+
+		resourceName, ok := krd.PluginForKind(string(match[1]))
+		if !ok {
+			return pkgerrors.New(file + " contains an unsupported " + string(match[1]))
+		}
+
+		seqFile.ResourceTypePathMap = append(seqFile.ResourceTypePathMap, map[string][]string{
+			resourceName: []string{file},
+		})
+
+	default:
+		log.Println("Ignoring artifact " + file)
+	}
+
+	return nil
+}
+
+// readTOSCAMeta parses the "Key: value" lines of a TOSCA.meta file
+func readTOSCAMeta(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "TOSCA.meta file read error")
+	}
+	defer file.Close()
+
+	meta := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		meta[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "TOSCA.meta file read error")
+	}
+
+	return meta, nil
+}
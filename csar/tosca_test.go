@@ -0,0 +1,92 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csar
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadTOSCAMetadata(t *testing.T) {
+	t.Run("Successfully read a TOSCA CSAR", func(t *testing.T) {
+		if !isTOSCA("mock_tosca") || isTOSCA("mock_yamls") {
+			t.Fatalf("TestReadTOSCAMetadata didn't detect the CSAR layouts")
+		}
+
+		seqFile, err := readCSARMetadata("mock_tosca")
+		if err != nil {
+			t.Fatalf("TestReadTOSCAMetadata returned an error (%s)", err)
+		}
+
+		expected := []map[string][]string{
+			{"service": []string{"Artifacts/Deployment/service.yaml"}},
+			{"deployment": []string{"Artifacts/Deployment/deployment.yaml"}},
+		}
+		if !reflect.DeepEqual(seqFile.ResourceTypePathMap, expected) {
+			t.Fatalf("TestReadTOSCAMetadata returned unexpected resources (%v)", seqFile.ResourceTypePathMap)
+		}
+
+		if seqFile.Parameters["sise_image"] != "mhausenblas/simpleservice:0.5.0" {
+			t.Fatalf("TestReadTOSCAMetadata returned unexpected parameters (%v)", seqFile.Parameters)
+		}
+	})
+
+	t.Run("Files outside of the CSAR failure", func(t *testing.T) {
+		testCases := map[string]map[string]string{
+			"Entry-Definitions": {
+				"csar/TOSCA-Metadata/TOSCA.meta": "Entry-Definitions: ../vnfd.yaml\n",
+				"vnfd.yaml":                      "topology_template:\n  node_templates: {}\n",
+			},
+			"VDU artifact": {
+				"csar/TOSCA-Metadata/TOSCA.meta": "Entry-Definitions: Definitions/vnfd.yaml\n",
+				"csar/Definitions/vnfd.yaml": "topology_template:\n  node_templates:\n    sise:\n" +
+					"      type: tosca.nodes.nfv.Vdu.Compute\n      artifacts:\n        deployment: ../deployment.yaml\n",
+				"deployment.yaml": "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: sise-deploy\n",
+			},
+		}
+
+		for label, files := range testCases {
+			dir, err := ioutil.TempDir("", "csar")
+			if err != nil {
+				t.Fatalf("TestReadTOSCAMetadata returned an error (%s)", err)
+			}
+			defer os.RemoveAll(dir)
+
+			for name, content := range files {
+				err = os.MkdirAll(filepath.Dir(dir+"/"+name), 0755)
+				if err == nil {
+					err = ioutil.WriteFile(dir+"/"+name, []byte(content), 0644)
+				}
+				if err != nil {
+					t.Fatalf("TestReadTOSCAMetadata returned an error (%s)", err)
+				}
+			}
+
+			_, err = readCSARMetadata(dir + "/csar")
+			if err == nil {
+				t.Fatalf("TestReadTOSCAMetadata accepted a %s outside of the CSAR", label)
+			}
+		}
+	})
+
+	t.Run("Validate a TOSCA CSAR", func(t *testing.T) {
+		report := ValidateCSAR("mock_tosca")
+		if !report.Valid {
+			t.Fatalf("TestReadTOSCAMetadata returned unexpected issues: %v", report.Issues)
+		}
+	})
+}
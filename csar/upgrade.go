@@ -0,0 +1,241 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csar
+
+import (
+	"log"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+
+	"k8-plugin-multicloud/krd"
+)
+
+// VNFRelease identifies the CSAR, parameters and overlay the objects of a
+// VNF are created from
+type VNFRelease struct {
+	CsarID     string
+	Parameters map[string]string
+	Overlay    string
+}
+
+// updatableResources are the plugins exporting UpdateResource. Objects of
+// the other kinds found in both CSARs, i.e. persistent volume claims and jobs
+// whose specs are mostly immutable, are left unchanged by an upgrade.
+var updatableResources = []string{"configmap", "secret", "service", "ingress", "networkpolicy", "cronjob",
+	"daemonset", "deployment", "statefulset"}
+
+// rolloutResources are the plugins whose WaitForResource waits for a rollout
+var rolloutResources = []string{"daemonset", "deployment", "statefulset"}
+
+// UpgradeVNF moves the objects of a VNF from one release to another. Objects
+// only found in the new CSAR are created, the ones found in both are updated
+// and the ones only found in the current release are deleted once the rollout
+// of every updated or created workload completes within timeout. Otherwise
+// the VNF is rolled back to its current release. The resources of the
+// upgraded VNF are returned along with the ones left unchanged although found
+// in both CSARs.
+var UpgradeVNF = func(from VNFRelease, to VNFRelease, cloudRegionID string, namespace string, externalVNFID string,
+	data map[string][]string, storagePolicy string, timeout time.Duration,
+	kubeclient *kubernetes.Clientset) (map[string][]string, map[string][]string, error) {
+
+	deadline := time.Now().Add(timeout)
+
+	vnf, seqFile, resources, err := releaseResources(to, cloudRegionID, namespace, externalVNFID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer vnf.release()
+
+	upgraded := make(map[string][]string)
+	unchanged := make(map[string][]string)
+	created := make(map[string][]string)
+	updated := make(map[string][]string)
+
+	for _, resource := range resources {
+		name, err := vnf.internalName(resource)
+		if err != nil {
+			return nil, nil, rollbackVNF(from, cloudRegionID, namespace, externalVNFID, data, created, updated, kubeclient, err)
+		}
+
+		genericKubeData, err := vnf.kubeData(resource)
+		if err != nil {
+			return nil, nil, rollbackVNF(from, cloudRegionID, namespace, externalVNFID, data, created, updated, kubeclient, err)
+		}
+
+		if containsString(data[resource.resourceName], name) {
+			if containsString(updatableResources, resource.resourceName) {
+				log.Println("Upgrading resource: " + name)
+
+				name, err = updateResource(resource.resourceName, genericKubeData, kubeclient)
+				if err != nil {
+					return nil, nil, rollbackVNF(from, cloudRegionID, namespace, externalVNFID, data, created, updated, kubeclient, err)
+				}
+				updated[resource.resourceName] = append(updated[resource.resourceName], name)
+			} else {
+				log.Println("Keeping resource unchanged: " + name)
+				unchanged[resource.resourceName] = append(unchanged[resource.resourceName], name)
+			}
+		} else {
+			name, err = createResource(resource.resourceName, genericKubeData, kubeclient)
+			if err != nil {
+				return nil, nil, rollbackVNF(from, cloudRegionID, namespace, externalVNFID, data, created, updated, kubeclient, err)
+			}
+			created[resource.resourceName] = append(created[resource.resourceName], name)
+
+			if resource.resourceName == "job" && seqFile.isBootstrapJob(resource.filename) {
+				err = waitForResource(resource.resourceName, name, namespace, seqFile.bootstrapTimeout(), kubeclient)
+				if err != nil {
+					err = pkgerrors.Wrap(err, "Bootstrap job "+name+" failed")
+					return nil, nil, rollbackVNF(from, cloudRegionID, namespace, externalVNFID, data, created, updated, kubeclient, err)
+				}
+			}
+		}
+
+		upgraded[resource.resourceName] = append(upgraded[resource.resourceName], name)
+		vnf.addResource(resource.resourceName, name)
+	}
+
+	for _, resourceName := range rolloutResources {
+		for _, name := range append(updated[resourceName], created[resourceName]...) {
+			err = waitForResource(resourceName, name, namespace, time.Until(deadline), kubeclient)
+			if err != nil {
+				err = pkgerrors.Wrap(err, "Rollout of "+name+" failed")
+				return nil, nil, rollbackVNF(from, cloudRegionID, namespace, externalVNFID, data, created, updated, kubeclient, err)
+			}
+		}
+	}
+
+	removed := make(map[string][]string)
+	for resourceName, resourceList := range data {
+		for _, name := range resourceList {
+			if !containsString(upgraded[resourceName], name) {
+				removed[resourceName] = append(removed[resourceName], name)
+			}
+		}
+	}
+
+	err = DestroyVNF(removed, namespace, storagePolicy, kubeclient)
+	if err != nil {
+		return upgraded, unchanged, pkgerrors.Wrap(err, "Delete resources removed from CSAR "+to.CsarID+" error")
+	}
+
+	return upgraded, unchanged, nil
+}
+
+// rollbackVNF deletes the objects created by a failed upgrade and updates
+// the changed ones back to their current release. The returned error wraps
+// cause along with the rollback error if any.
+func rollbackVNF(from VNFRelease, cloudRegionID string, namespace string, externalVNFID string,
+	data map[string][]string, created map[string][]string, updated map[string][]string,
+	kubeclient *kubernetes.Clientset, cause error) error {
+
+	log.Println("Rolling back to CSAR " + from.CsarID + ": " + cause.Error())
+
+	err := DestroyVNF(created, namespace, StoragePolicyDelete, kubeclient)
+	if err != nil {
+		return pkgerrors.Wrap(cause, "Upgrade failed and rollback error: "+err.Error())
+	}
+
+	if len(updated) == 0 {
+		return pkgerrors.Wrap(cause, "Upgrade failed, rolled back to CSAR "+from.CsarID)
+	}
+
+	vnf, _, resources, err := releaseResources(from, cloudRegionID, namespace, externalVNFID)
+	if err != nil {
+		return pkgerrors.Wrap(cause, "Upgrade failed and rollback error: "+err.Error())
+	}
+	defer vnf.release()
+
+	for resourceName, resourceList := range data {
+		for _, name := range resourceList {
+			vnf.addResource(resourceName, name)
+		}
+	}
+
+	for _, resource := range resources {
+		name, err := vnf.internalName(resource)
+		if err != nil {
+			return pkgerrors.Wrap(cause, "Upgrade failed and rollback error: "+err.Error())
+		}
+
+		if !containsString(updated[resource.resourceName], name) {
+			continue
+		}
+
+		log.Println("Rolling back resource: " + name)
+
+		genericKubeData, err := vnf.kubeData(resource)
+		if err != nil {
+			return pkgerrors.Wrap(cause, "Upgrade failed and rollback error: "+err.Error())
+		}
+
+		_, err = updateResource(resource.resourceName, genericKubeData, kubeclient)
+		if err != nil {
+			return pkgerrors.Wrap(cause, "Upgrade failed and rollback error: "+err.Error())
+		}
+	}
+
+	return pkgerrors.Wrap(cause, "Upgrade failed, rolled back to CSAR "+from.CsarID)
+}
+
+// releaseResources returns the VNF instance and the ordered resources of a
+// release, after verifying its CSAR. The caller releases the VNF instance.
+func releaseResources(release VNFRelease, cloudRegionID string, namespace string, externalVNFID string) (*vnfInstance, MetadataFile, []csarResource, error) {
+	vnf := newVNFInstance(release.CsarID, cloudRegionID, namespace, externalVNFID)
+
+	err := vnf.verify()
+	if err != nil {
+		return nil, MetadataFile{}, nil, err
+	}
+
+	seqFile, err := readCSARMetadata(vnf.csarDirPath)
+	if err != nil {
+		vnf.release()
+		return nil, MetadataFile{}, nil, err
+	}
+	vnf.parameters = seqFile.parameterValues(release.Parameters)
+	vnf.overlay = release.Overlay
+
+	resources, err := vnf.resources(seqFile)
+	if err != nil {
+		vnf.release()
+		return nil, MetadataFile{}, nil, err
+	}
+
+	return vnf, seqFile, resources, nil
+}
+
+// updateResource calls the UpdateResource function of the plugin registered
+// for resourceName and returns the name of the updated object
+func updateResource(resourceName string, kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) (string, error) {
+	typePlugin, ok := krd.LoadedPlugins[resourceName]
+	if !ok {
+		return "", pkgerrors.New("No plugin for resource " + resourceName + " found")
+	}
+
+	symUpdateResourceFunc, err := typePlugin.Lookup("UpdateResource")
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Error fetching "+resourceName+" plugin")
+	}
+
+	internalResourceName, err := symUpdateResourceFunc.(func(*krd.GenericKubeResourceData, *kubernetes.Clientset) (string, error))(
+		kubedata, kubeclient)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Error in plugin "+resourceName+" plugin")
+	}
+
+	return internalResourceName, nil
+}
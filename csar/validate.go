@@ -0,0 +1,389 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csar
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	ghodssyaml "github.com/ghodss/yaml"
+	"gopkg.in/yaml.v2"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"k8-plugin-multicloud/krd"
+)
+
+// Severities of the issues found while validating a CSAR
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// ValidationIssue is a problem found in a CSAR
+type ValidationIssue struct {
+	Severity string `json:"severity"`
+	File     string `json:"file,omitempty"`
+	Resource string `json:"resource,omitempty"`
+	Message  string `json:"message"`
+}
+
+// ValidationReport lists the issues found in a CSAR. A CSAR is valid when
+// none of them is an error.
+type ValidationReport struct {
+	Path   string            `json:"path"`
+	Valid  bool              `json:"valid"`
+	Issues []ValidationIssue `json:"issues"`
+}
+
+func (r *ValidationReport) add(severity string, file string, resource string, message string) {
+	r.Issues = append(r.Issues, ValidationIssue{
+		Severity: severity,
+		File:     file,
+		Resource: resource,
+		Message:  message,
+	})
+	if severity == SeverityError {
+		r.Valid = false
+	}
+}
+
+// documentSeparator matches the separator of multi-document YAML files
+var documentSeparator = regexp.MustCompile(`(?m)^---\s*$`)
+
+// ValidateCSAR checks a CSAR directory without contacting any cluster: the
+// metadata structure, the files it references, that every manifest decodes
+// to the group, version and kind handled by its plugin and that it matches
+// the object schema
+func ValidateCSAR(csarDirPath string) ValidationReport {
+	report := ValidationReport{
+		Path:   csarDirPath,
+		Valid:  true,
+		Issues: []ValidationIssue{},
+	}
+
+	validateIntegrity(&report, csarDirPath)
+
+	var seqFile MetadataFile
+	if isTOSCA(csarDirPath) {
+		var err error
+		seqFile, err = ReadTOSCAMetadata(csarDirPath)
+		if err != nil {
+			report.add(SeverityError, toscaMetaPath, "", err.Error())
+			return report
+		}
+	} else {
+		var ok bool
+		seqFile, ok = validateMetadataFile(&report, csarDirPath)
+		if !ok {
+			return report
+		}
+	}
+
+	resources := seqFile.orderedResources()
+	resources = append(resources, validateCharts(&report, csarDirPath, seqFile)...)
+	if seqFile.Kustomize != nil {
+		validateKustomize(&report, csarDirPath, *seqFile.Kustomize)
+	}
+	if len(resources) == 0 {
+		report.add(SeverityWarning, "metadata.yaml", "", "No resources defined")
+	}
+
+	jobs := make(map[string]bool)
+	names := make(map[string]bool)
+
+	for _, resource := range resources {
+		if _, ok := krd.ResourceKinds[resource.resourceName]; !ok {
+			report.add(SeverityError, resource.filename, resource.resourceName, "No plugin for resource "+resource.resourceName)
+			continue
+		}
+
+		var name string
+		if resource.fileResource != nil {
+			name = validateFileResource(&report, csarDirPath, resource)
+		} else if resource.manifest != nil {
+			name = validateObject(&report, resource, resource.manifest)
+		} else {
+			name = validateManifest(&report, csarDirPath, resource, seqFile.Parameters)
+		}
+
+		if name != "" {
+			key := resource.resourceName + "/" + name
+			if names[key] {
+				report.add(SeverityError, resource.filename, resource.resourceName, "Duplicated name "+name)
+			}
+			names[key] = true
+		}
+
+		if resource.resourceName == "job" {
+			jobs[resource.filename] = true
+		}
+	}
+
+	for _, job := range seqFile.BootstrapJobs {
+		if !jobs[job] {
+			report.add(SeverityError, job, "job", "Bootstrap job is not listed in the job resources")
+		}
+	}
+
+	return report
+}
+
+// validateMetadataFile reads the metadata.yaml file of a legacy CSAR
+func validateMetadataFile(report *ValidationReport, csarDirPath string) (MetadataFile, bool) {
+	metadataYAMLPath := csarDirPath + "/metadata.yaml"
+	rawBytes, err := ioutil.ReadFile(metadataYAMLPath)
+	if err != nil {
+		report.add(SeverityError, "metadata.yaml", "", "Metadata file can't be read: "+err.Error())
+		return MetadataFile{}, false
+	}
+
+	seqFile, err := ReadMetadataFile(metadataYAMLPath)
+	if err != nil {
+		report.add(SeverityError, "metadata.yaml", "", err.Error())
+		return seqFile, false
+	}
+
+	var strict MetadataFile
+	err = yaml.UnmarshalStrict(rawBytes, &strict)
+	if err != nil {
+		report.add(SeverityWarning, "metadata.yaml", "", "Unknown metadata fields: "+err.Error())
+	}
+
+	return seqFile, true
+}
+
+// validateFileResource checks a ConfigMap or Secret built from raw files and
+// returns its name
+func validateFileResource(report *ValidationReport, csarDirPath string, resource csarResource) string {
+	fileResource := resource.fileResource
+
+	if fileResource.Name == "" {
+		report.add(SeverityError, "metadata.yaml", resource.resourceName, "Missing name")
+	}
+	if len(fileResource.Files) == 0 {
+		report.add(SeverityWarning, "metadata.yaml", resource.resourceName, fileResource.Name+" has no files")
+	}
+
+	for _, filename := range fileResource.Files {
+		path, err := csarFilePath(csarDirPath, filename)
+		if err != nil {
+			report.add(SeverityError, filename, resource.resourceName, err.Error())
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			report.add(SeverityError, filename, resource.resourceName, "Referenced file does not exist")
+		}
+	}
+
+	return fileResource.Name
+}
+
+// validateManifest checks a YAML manifest the same way its plugin decodes it,
+// with the default parameter values, and returns the name of the object
+func validateManifest(report *ValidationReport, csarDirPath string, resource csarResource, parameters map[string]string) string {
+	filename := resource.filename
+
+	path, err := csarFilePath(csarDirPath, filename)
+	if err != nil {
+		report.add(SeverityError, filename, resource.resourceName, err.Error())
+		return ""
+	}
+
+	rawBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		report.add(SeverityError, filename, resource.resourceName, "Referenced file does not exist")
+		return ""
+	}
+
+	for _, variable := range krd.TemplateVariables(rawBytes) {
+		if _, ok := parameters[variable]; !ok {
+			report.add(SeverityWarning, filename, resource.resourceName,
+				"Template variable "+variable+" is not declared in the parameters and is left as is")
+		}
+	}
+
+	rawBytes, err = krd.SubstituteParameters(rawBytes, parameters)
+	if err != nil {
+		report.add(SeverityError, filename, resource.resourceName, err.Error())
+		return ""
+	}
+
+	return validateObject(report, resource, rawBytes)
+}
+
+// validateObject decodes a manifest, checks it contains the group, version
+// and kind handled by its plugin and matches the object schema, and returns
+// the object name
+func validateObject(report *ValidationReport, resource csarResource, rawBytes []byte) string {
+	filename := resource.filename
+
+	documents := 0
+	for _, document := range documentSeparator.Split(string(rawBytes), -1) {
+		if len(strings.TrimSpace(document)) > 0 {
+			documents++
+		}
+	}
+	if documents > 1 {
+		report.add(SeverityError, filename, resource.resourceName, "Only one object per file is supported")
+		return ""
+	}
+
+	decode := scheme.Codecs.UniversalDeserializer().Decode
+	obj, gvk, err := decode(rawBytes, nil, nil)
+	if err != nil {
+		report.add(SeverityError, filename, resource.resourceName, "Deserialize error: "+err.Error())
+		return ""
+	}
+
+	// The plugins only accept the objects of their own API group and version
+	if !krd.IsResourceObject(resource.resourceName, obj) {
+		expected := krd.ResourceGroupVersionKinds[resource.resourceName]
+		report.add(SeverityError, filename, resource.resourceName,
+			"Contains a "+gvk.GroupVersion().String()+" "+gvk.Kind+" but the "+resource.resourceName+
+				" plugin expects a "+expected.GroupVersion().String()+" "+expected.Kind)
+		return ""
+	}
+
+	// The plugins ignore unknown fields, which usually are typos
+	jsonBytes, err := ghodssyaml.YAMLToJSON(rawBytes)
+	if err != nil {
+		report.add(SeverityError, filename, resource.resourceName, "Deserialize error: "+err.Error())
+		return ""
+	}
+	typed, err := scheme.Scheme.New(*gvk)
+	if err != nil {
+		report.add(SeverityError, filename, resource.resourceName, "Unsupported API version: "+gvk.GroupVersion().String())
+		return ""
+	}
+	decoder := json.NewDecoder(bytes.NewReader(jsonBytes))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(typed)
+	if err != nil {
+		report.add(SeverityError, filename, resource.resourceName, "Schema error: "+err.Error())
+	}
+
+	accessor, err := meta.Accessor(obj)
+	if err != nil || accessor.GetName() == "" {
+		report.add(SeverityError, filename, resource.resourceName, "Missing metadata.name")
+		return ""
+	}
+
+	return accessor.GetName()
+}
+
+// validateCharts renders the charts of a CSAR with their values files and
+// returns the objects rendered
+func validateCharts(report *ValidationReport, csarDirPath string, seqFile MetadataFile) []csarResource {
+	vnf := newVNFInstance("validation", "cloud", "default", "uuid")
+	vnf.csarDirPath = csarDirPath
+
+	var resources []csarResource
+	for _, c := range seqFile.Charts {
+		if c.Values != "" {
+			if _, err := os.Stat(csarDirPath + "/" + c.Values); err != nil {
+				report.add(SeverityError, c.Values, "", "Referenced values file does not exist")
+				continue
+			}
+		}
+
+		chartResources, err := vnf.chartResources(c)
+		if err != nil {
+			report.add(SeverityError, c.Chart, "", err.Error())
+			continue
+		}
+		resources = append(resources, chartResources...)
+	}
+
+	return resources
+}
+
+// validateKustomize builds the base and every overlay of a kustomization and
+// checks the objects they produce
+func validateKustomize(report *ValidationReport, csarDirPath string, k KustomizeResource) {
+	dirs := make(map[string]string)
+	if k.Base != "" {
+		dirs[k.Base] = k.Base
+	}
+	for name, dir := range k.Overlays {
+		dirs[dir] = name
+	}
+	if len(dirs) == 0 {
+		report.add(SeverityError, "metadata.yaml", "", "Kustomize section without base nor overlays")
+	}
+
+	for region, overlay := range k.DefaultOverlays {
+		if _, ok := k.Overlays[overlay]; !ok {
+			report.add(SeverityError, "metadata.yaml", "", "Default overlay "+overlay+" of cloud region "+region+" not found")
+		}
+	}
+
+	for dir := range dirs {
+		content, err := buildKustomization(csarDirPath + "/" + dir)
+		if err != nil {
+			report.add(SeverityError, dir, "", "Build kustomization error: "+err.Error())
+			continue
+		}
+
+		resources, err := splitManifests(dir, content)
+		if err != nil {
+			report.add(SeverityError, dir, "", err.Error())
+			continue
+		}
+
+		for _, resource := range resources {
+			validateObject(report, resource, resource.manifest)
+		}
+	}
+}
+
+// validateIntegrity checks the digest manifest of a CSAR and its signature
+// when trusted keys are configured
+func validateIntegrity(report *ValidationReport, csarDirPath string) {
+	manifest, err := ioutil.ReadFile(csarDirPath + "/" + DigestManifestFile)
+	if err != nil {
+		report.add(SeverityWarning, DigestManifestFile, "", "CSAR has no digest manifest, it can't be signed")
+		return
+	}
+
+	err = verifyDigests(csarDirPath, manifest)
+	if err != nil {
+		report.add(SeverityError, DigestManifestFile, "", err.Error())
+		return
+	}
+
+	signature, err := ioutil.ReadFile(csarDirPath + "/" + SignatureFile)
+	if err != nil {
+		report.add(SeverityWarning, SignatureFile, "", "CSAR is not signed")
+		return
+	}
+
+	if os.Getenv("CSAR_TRUSTED_KEYS_DIR") == "" {
+		report.add(SeverityWarning, SignatureFile, "", "Signature not verified, CSAR_TRUSTED_KEYS_DIR is not set")
+		return
+	}
+
+	keys, err := loadTrustedKeys(os.Getenv("CSAR_TRUSTED_KEYS_DIR"))
+	if err == nil {
+		err = verifySignature(manifest, signature, keys)
+	}
+	if err != nil {
+		report.add(SeverityError, SignatureFile, "", err.Error())
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csar
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestValidateCSAR(t *testing.T) {
+	t.Run("Valid CSAR", func(t *testing.T) {
+		report := ValidateCSAR("mock_yamls")
+		if !report.Valid {
+			t.Fatalf("TestValidateCSAR returned unexpected issues: %v", report.Issues)
+		}
+	})
+
+	t.Run("Invalid CSAR", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "csar")
+		if err != nil {
+			t.Fatalf("TestValidateCSAR returned an error (%s)", err)
+		}
+		defer os.RemoveAll(dir)
+
+		files := map[string]string{
+			"metadata.yaml": "resources:\n  - deployment:\n    - service.yaml\n    - missing.yaml\n    - beta.yaml\n" +
+				"  - service:\n    - typo.yaml\n    - template.yaml\n    - crd.yaml\n    - noversion.yaml\n" +
+				"bootstrap_jobs:\n  - job.yaml\n",
+			"service.yaml":   "apiVersion: v1\nkind: Service\nmetadata:\n  name: sise-svc\n",
+			"beta.yaml":      "apiVersion: apps/v1beta1\nkind: Deployment\nmetadata:\n  name: sise-deploy\n",
+			"crd.yaml":       "apiVersion: example.com/v1\nkind: Service\nmetadata:\n  name: sise-crd\n",
+			"noversion.yaml": "kind: Service\nmetadata:\n  name: sise-noversion\n",
+			"typo.yaml":      "apiVersion: v1\nkind: Service\nmetadata:\n  name: sise-svc\nspec:\n  prots: []\n",
+			"template.yaml":  "apiVersion: v1\nkind: Service\nmetadata:\n  name: sise-tpl\n  annotations:\n    greeting: \"{{ name }}\"\n",
+		}
+		for name, content := range files {
+			err = ioutil.WriteFile(dir+"/"+name, []byte(content), 0644)
+			if err != nil {
+				t.Fatalf("TestValidateCSAR returned an error (%s)", err)
+			}
+		}
+
+		report := ValidateCSAR(dir)
+		if report.Valid {
+			t.Fatalf("TestValidateCSAR didn't detect the errors")
+		}
+
+		expected := map[string]bool{
+			"service.yaml":   true,
+			"missing.yaml":   true,
+			"typo.yaml":      true,
+			"job.yaml":       true,
+			"beta.yaml":      true,
+			"crd.yaml":       true,
+			"noversion.yaml": true,
+		}
+		undeclared := false
+		for _, issue := range report.Issues {
+			if issue.Severity == SeverityError {
+				delete(expected, issue.File)
+			}
+			if issue.File == "template.yaml" {
+				undeclared = issue.Severity == SeverityWarning
+			}
+		}
+		if len(expected) > 0 {
+			t.Fatalf("TestValidateCSAR didn't report errors for %v: %v", expected, report.Issues)
+		}
+		if !undeclared {
+			t.Fatalf("TestValidateCSAR didn't only warn about the undeclared variable: %v", report.Issues)
+		}
+	})
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"sync"
+)
+
+// keyLocks holds a lock per database key, serializing the operations of the
+// plugin on a VNF or the read-modify-write cycles of a record. A lock is
+// dropped once no caller holds or waits for it.
+var keyLocks = struct {
+	sync.Mutex
+	held map[string]*keyLock
+}{
+	held: make(map[string]*keyLock),
+}
+
+// keyLock is the lock of a key with the number of callers holding or waiting
+// for it
+type keyLock struct {
+	lock  chan struct{}
+	users int
+}
+
+// acquireKeyLock returns the lock of a key, counting the caller as one of its
+// users
+func acquireKeyLock(key string) *keyLock {
+	keyLocks.Lock()
+	defer keyLocks.Unlock()
+
+	entry, ok := keyLocks.held[key]
+	if !ok {
+		entry = &keyLock{lock: make(chan struct{}, 1)}
+		keyLocks.held[key] = entry
+	}
+	entry.users++
+	return entry
+}
+
+// releaseKeyLock stops counting a caller as a user of the lock of a key,
+// dropping the lock after its last user
+func releaseKeyLock(key string, entry *keyLock) {
+	keyLocks.Lock()
+	defer keyLocks.Unlock()
+
+	entry.users--
+	if entry.users == 0 {
+		delete(keyLocks.held, key)
+	}
+}
+
+// LockKey waits for the lock of a key and returns the function releasing it
+func LockKey(key string) func() {
+	entry := acquireKeyLock(key)
+	entry.lock <- struct{}{}
+	return func() {
+		<-entry.lock
+		releaseKeyLock(key, entry)
+	}
+}
+
+// TryLockKey takes the lock of a key unless it is already held
+func TryLockKey(key string) (func(), bool) {
+	entry := acquireKeyLock(key)
+	select {
+	case entry.lock <- struct{}{}:
+		return func() {
+			<-entry.lock
+			releaseKeyLock(key, entry)
+		}, true
+	default:
+		releaseKeyLock(key, entry)
+		return nil, false
+	}
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"testing"
+)
+
+func TestLockKey(t *testing.T) {
+	t.Run("Lock held until released", func(t *testing.T) {
+		unlock := LockKey("cloud1-default-uuid")
+
+		_, ok := TryLockKey("cloud1-default-uuid")
+		if ok {
+			t.Fatalf("TestLockKey took a held lock")
+		}
+
+		unlock()
+
+		unlock, ok = TryLockKey("cloud1-default-uuid")
+		if !ok {
+			t.Fatalf("TestLockKey didn't take a released lock")
+		}
+		unlock()
+	})
+
+	t.Run("Locks dropped after their last user", func(t *testing.T) {
+		for _, key := range []string{"creating-1", "creating-2", "creating-3"} {
+			unlock := LockKey(key)
+			unlock()
+		}
+
+		keyLocks.Lock()
+		held := len(keyLocks.held)
+		keyLocks.Unlock()
+		if held != 0 {
+			t.Fatalf("TestLockKey kept %d locks", held)
+		}
+	})
+}
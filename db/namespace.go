@@ -0,0 +1,215 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// namespacePrefix is the key prefix of the namespaces created by the plugin
+const namespacePrefix = "namespaces/"
+
+// NamespaceRecord is stored for every namespace created by the plugin, with
+// the VNFs living in it by internal VNF ID and the persistent volume claims
+// retained by the VNFs deleted with the retain storage policy
+type NamespaceRecord struct {
+	CloudRegionID  string    `json:"cloud_region_id"`
+	Namespace      string    `json:"namespace"`
+	CreatedAt      time.Time `json:"created_at"`
+	VNFs           []string  `json:"vnfs"`
+	RetainedClaims []string  `json:"retained_claims,omitempty"`
+}
+
+// CreatingVNFPrefix starts the placeholder registered in the record of a
+// namespace while a VNF is created in it, before its VNF ID is known
+const CreatingVNFPrefix = "creating-"
+
+// namespaceKey returns the key of a namespace record, e.g.
+// namespaces/cloud1/default
+func namespaceKey(cloudRegionID string, namespace string) string {
+	return namespacePrefix + cloudRegionID + "/" + namespace
+}
+
+// LockNamespace serializes the updates of the record of a namespace, which
+// are read-modify-write cycles, and its deletion. It returns the function
+// releasing the lock.
+func LockNamespace(cloudRegionID string, namespace string) func() {
+	return LockKey(namespaceKey(cloudRegionID, namespace))
+}
+
+// WriteNamespaceRecord stores the record of a namespace created by the plugin
+func WriteNamespaceRecord(record NamespaceRecord) error {
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now().UTC()
+	}
+
+	out, err := json.Marshal(record)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Serialize namespace record error")
+	}
+
+	err = DBconn.CreateEntry(namespaceKey(record.CloudRegionID, record.Namespace), string(out))
+	if err != nil {
+		return pkgerrors.Wrap(err, "Write namespace record error")
+	}
+
+	return nil
+}
+
+// ReadNamespaceRecord reads the record of a namespace. Namespaces not created
+// by the plugin aren't found.
+func ReadNamespaceRecord(cloudRegionID string, namespace string) (NamespaceRecord, bool, error) {
+	var record NamespaceRecord
+
+	value, found, err := DBconn.ReadEntry(namespaceKey(cloudRegionID, namespace))
+	if err != nil || found == false {
+		return record, found, err
+	}
+
+	err = json.Unmarshal([]byte(value), &record)
+	if err != nil {
+		return record, true, pkgerrors.Wrap(err, "Deserialize namespace record error")
+	}
+
+	return record, true, nil
+}
+
+// DeleteNamespaceRecord deletes the record of a namespace
+func DeleteNamespaceRecord(cloudRegionID string, namespace string) error {
+	err := DBconn.DeleteEntry(namespaceKey(cloudRegionID, namespace))
+	if err != nil {
+		return pkgerrors.Wrap(err, "Delete namespace record error")
+	}
+
+	return nil
+}
+
+// AddNamespaceVNF records a VNF living in a namespace created by the plugin.
+// Nothing is recorded for the other namespaces. The caller holds the
+// LockNamespace lock.
+func AddNamespaceVNF(cloudRegionID string, namespace string, internalVNFID string) error {
+	record, found, err := ReadNamespaceRecord(cloudRegionID, namespace)
+	if err != nil || found == false {
+		return err
+	}
+
+	for _, vnf := range record.VNFs {
+		if vnf == internalVNFID {
+			return nil
+		}
+	}
+	record.VNFs = append(record.VNFs, internalVNFID)
+
+	return WriteNamespaceRecord(record)
+}
+
+// RemoveNamespaceVNF forgets a VNF terminated in a namespace created by the
+// plugin, records the persistent volume claims it retained and returns the
+// updated namespace record. The caller holds the LockNamespace lock.
+func RemoveNamespaceVNF(cloudRegionID string, namespace string, internalVNFID string,
+	retainedClaims []string) (NamespaceRecord, bool, error) {
+
+	record, found, err := ReadNamespaceRecord(cloudRegionID, namespace)
+	if err != nil || found == false {
+		return record, found, err
+	}
+
+	vnfs := make([]string, 0, len(record.VNFs))
+	for _, vnf := range record.VNFs {
+		if vnf != internalVNFID {
+			vnfs = append(vnfs, vnf)
+		}
+	}
+	record.VNFs = vnfs
+
+	for _, claim := range retainedClaims {
+		if !contains(record.RetainedClaims, claim) {
+			record.RetainedClaims = append(record.RetainedClaims, claim)
+		}
+	}
+
+	return record, true, WriteNamespaceRecord(record)
+}
+
+// CreatingVNFs tells whether VNFs are being created in a namespace created by
+// the plugin
+func CreatingVNFs(cloudRegionID string, namespace string) (bool, error) {
+	record, found, err := ReadNamespaceRecord(cloudRegionID, namespace)
+	if err != nil || found == false {
+		return false, err
+	}
+
+	for _, vnf := range record.VNFs {
+		if strings.HasPrefix(vnf, CreatingVNFPrefix) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ReplaceNamespaceVNF renames a VNF recorded in a namespace created by the
+// plugin, e.g. the placeholder registered while the VNF was being created
+func ReplaceNamespaceVNF(cloudRegionID string, namespace string, oldVNFID string, newVNFID string) error {
+	unlock := LockNamespace(cloudRegionID, namespace)
+	defer unlock()
+
+	record, found, err := ReadNamespaceRecord(cloudRegionID, namespace)
+	if err != nil || found == false {
+		return err
+	}
+
+	for i, vnf := range record.VNFs {
+		if vnf == oldVNFID {
+			record.VNFs[i] = newVNFID
+		}
+	}
+
+	return WriteNamespaceRecord(record)
+}
+
+// RemoveNamespaceClaims forgets the retained persistent volume claims of a
+// namespace created by the plugin, e.g. once reused by a new VNF
+func RemoveNamespaceClaims(cloudRegionID string, namespace string, claims []string) error {
+	unlock := LockNamespace(cloudRegionID, namespace)
+	defer unlock()
+
+	record, found, err := ReadNamespaceRecord(cloudRegionID, namespace)
+	if err != nil || found == false {
+		return err
+	}
+
+	retained := make([]string, 0, len(record.RetainedClaims))
+	for _, claim := range record.RetainedClaims {
+		if !contains(claims, claim) {
+			retained = append(retained, claim)
+		}
+	}
+	record.RetainedClaims = retained
+
+	return WriteNamespaceRecord(record)
+}
+
+// contains tells whether a list of names holds a name
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// revisionPrefix is the key prefix of the VNF revisions. The keys contain a
+// "/" so they are never taken for VNF instances.
+const revisionPrefix = "revisions/"
+
+// Revision is the state of a VNF instance after a lifecycle operation
+type Revision struct {
+	Number     int                 `json:"revision"`
+	Operation  string              `json:"operation"`
+	CsarID     string              `json:"csar_id,omitempty"`
+	CsarDigest string              `json:"csar_digest,omitempty"`
+	Parameters map[string]string   `json:"parameters,omitempty"`
+	Overlay    string              `json:"overlay,omitempty"`
+	Replicas   map[string]int32    `json:"replicas,omitempty"`
+	Manifests  string              `json:"manifests,omitempty"`
+	Resources  map[string][]string `json:"resources"`
+	CreatedAt  time.Time           `json:"created_at"`
+}
+
+// revisionKey returns the key of a revision, e.g.
+// revisions/cloud1-default-uuid/000001
+func revisionKey(internalVNFID string, number int) string {
+	return fmt.Sprintf("%s%s/%06d", revisionPrefix, internalVNFID, number)
+}
+
+// revisionNumbers returns the numbers of the stored revisions of a VNF in
+// ascending order
+func revisionNumbers(internalVNFID string) ([]int, error) {
+	prefix := revisionPrefix + internalVNFID + "/"
+
+	keys, err := DBconn.ReadAll(prefix)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "List VNF revisions error")
+	}
+
+	var numbers []int
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		number, err := strconv.Atoi(strings.TrimPrefix(key, prefix))
+		if err != nil {
+			continue
+		}
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+
+	return numbers, nil
+}
+
+// WriteRevision stores a new revision of a VNF, numbered after the last
+// stored one, and returns it
+func WriteRevision(internalVNFID string, revision Revision) (Revision, error) {
+	numbers, err := revisionNumbers(internalVNFID)
+	if err != nil {
+		return revision, err
+	}
+
+	revision.Number = 1
+	if len(numbers) > 0 {
+		revision.Number = numbers[len(numbers)-1] + 1
+	}
+	if revision.CreatedAt.IsZero() {
+		revision.CreatedAt = time.Now().UTC()
+	}
+
+	out, err := json.Marshal(revision)
+	if err != nil {
+		return revision, pkgerrors.Wrap(err, "Serialize VNF revision error")
+	}
+
+	err = DBconn.CreateEntry(revisionKey(internalVNFID, revision.Number), string(out))
+	if err != nil {
+		return revision, pkgerrors.Wrap(err, "Write VNF revision error")
+	}
+
+	return revision, nil
+}
+
+// ReadRevision reads a revision of a VNF
+func ReadRevision(internalVNFID string, number int) (Revision, bool, error) {
+	var revision Revision
+
+	value, found, err := DBconn.ReadEntry(revisionKey(internalVNFID, number))
+	if err != nil || found == false {
+		return revision, found, err
+	}
+
+	err = json.Unmarshal([]byte(value), &revision)
+	if err != nil {
+		return revision, true, pkgerrors.Wrap(err, "Deserialize VNF revision error")
+	}
+
+	return revision, true, nil
+}
+
+// ListRevisions returns the revisions of a VNF from the oldest to the newest
+func ListRevisions(internalVNFID string) ([]Revision, error) {
+	numbers, err := revisionNumbers(internalVNFID)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make([]Revision, 0, len(numbers))
+	for _, number := range numbers {
+		revision, found, err := ReadRevision(internalVNFID, number)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			revisions = append(revisions, revision)
+		}
+	}
+
+	return revisions, nil
+}
+
+// DeleteRevisions deletes every revision of a VNF
+func DeleteRevisions(internalVNFID string) error {
+	numbers, err := revisionNumbers(internalVNFID)
+	if err != nil {
+		return err
+	}
+
+	for _, number := range numbers {
+		err = DBconn.DeleteEntry(revisionKey(internalVNFID, number))
+		if err != nil {
+			return pkgerrors.Wrap(err, "Delete VNF revision error")
+		}
+	}
+
+	return nil
+}
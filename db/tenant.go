@@ -0,0 +1,150 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// tenantPrefix is the key prefix of the tenants
+const tenantPrefix = "tenants/"
+
+// Tenant owns a set of namespaces per cloud region. New namespaces are
+// claimed by the tenant when their name matches NamespacePattern.
+type Tenant struct {
+	Name             string              `json:"name"`
+	Description      string              `json:"description,omitempty"`
+	NamespacePattern string              `json:"namespace_pattern,omitempty"`
+	Namespaces       map[string][]string `json:"namespaces"`
+	CreatedAt        time.Time           `json:"created_at"`
+}
+
+// OwnsNamespace tells whether a namespace of a cloud region belongs to the
+// tenant
+func (t Tenant) OwnsNamespace(cloudRegionID string, namespace string) bool {
+	for _, name := range t.Namespaces[cloudRegionID] {
+		if name == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesNamespace tells whether the tenant may claim a new namespace
+func (t Tenant) MatchesNamespace(namespace string) bool {
+	if t.NamespacePattern == "" {
+		return false
+	}
+
+	pattern, err := regexp.Compile("^(?:" + t.NamespacePattern + ")$")
+	if err != nil {
+		return false
+	}
+
+	return pattern.MatchString(namespace)
+}
+
+// LockTenant serializes the read-modify-write cycles of the record of a
+// tenant and its deletion. It returns the function releasing the lock.
+func LockTenant(name string) func() {
+	return LockKey(tenantPrefix + name)
+}
+
+// WriteTenant stores a tenant
+func WriteTenant(tenant Tenant) error {
+	if tenant.CreatedAt.IsZero() {
+		tenant.CreatedAt = time.Now().UTC()
+	}
+
+	out, err := json.Marshal(tenant)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Serialize tenant error")
+	}
+
+	err = DBconn.CreateEntry(tenantPrefix+tenant.Name, string(out))
+	if err != nil {
+		return pkgerrors.Wrap(err, "Write tenant error")
+	}
+
+	return nil
+}
+
+// ReadTenant reads a tenant by name
+func ReadTenant(name string) (Tenant, bool, error) {
+	var tenant Tenant
+
+	value, found, err := DBconn.ReadEntry(tenantPrefix + name)
+	if err != nil || found == false {
+		return tenant, found, err
+	}
+
+	err = json.Unmarshal([]byte(value), &tenant)
+	if err != nil {
+		return tenant, true, pkgerrors.Wrap(err, "Deserialize tenant error")
+	}
+
+	return tenant, true, nil
+}
+
+// ListTenants returns the names of the stored tenants
+func ListTenants() ([]string, error) {
+	keys, err := DBconn.ReadAll(tenantPrefix)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "List tenants error")
+	}
+
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if strings.HasPrefix(key, tenantPrefix) {
+			names = append(names, strings.TrimPrefix(key, tenantPrefix))
+		}
+	}
+
+	return names, nil
+}
+
+// DeleteTenant deletes a tenant
+func DeleteTenant(name string) error {
+	err := DBconn.DeleteEntry(tenantPrefix + name)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Delete tenant error")
+	}
+
+	return nil
+}
+
+// FindNamespaceOwner returns the tenant owning a namespace of a cloud region
+func FindNamespaceOwner(cloudRegionID string, namespace string) (Tenant, bool, error) {
+	names, err := ListTenants()
+	if err != nil {
+		return Tenant{}, false, err
+	}
+
+	for _, name := range names {
+		tenant, found, err := ReadTenant(name)
+		if err != nil {
+			return Tenant{}, false, err
+		}
+		if found && tenant.OwnsNamespace(cloudRegionID, namespace) {
+			return tenant, true, nil
+		}
+	}
+
+	return Tenant{}, false, nil
+}
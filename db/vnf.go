@@ -0,0 +1,134 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package db
+
+import (
+	"encoding/json"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// States of a VNF instance. Records without a state are running.
+const (
+	VNFStateRunning = "Running"
+	VNFStateStopped = "Stopped"
+)
+
+// VNFRecord is the value stored for every VNF instance, keyed by its
+// internal VNF ID
+type VNFRecord struct {
+	CsarID        string `json:"csar_id,omitempty"`
+	CloudRegionID string `json:"cloud_region_id,omitempty"`
+	Namespace     string `json:"namespace,omitempty"`
+	StoragePolicy string `json:"storage_policy,omitempty"`
+	Tenant        string `json:"tenant,omitempty"`
+
+	// Parameters and kustomization overlay requested when the VNF was created
+	Parameters map[string]string `json:"parameters,omitempty"`
+	Overlay    string            `json:"overlay,omitempty"`
+
+	// Replicas is the desired replica count of the scaled workloads, keyed
+	// by object name
+	Replicas map[string]int32 `json:"replicas,omitempty"`
+
+	// State is VNFStateStopped while the workloads are scaled to zero, and
+	// StoppedReplicas the replica counts they are started again with
+	State           string           `json:"state,omitempty"`
+	StoppedReplicas map[string]int32 `json:"stopped_replicas,omitempty"`
+
+	/*
+		{
+			"deployment": ["cloud1-default-uuid-sisedeploy1", "cloud1-default-uuid-sisedeploy2", ... ]
+			"service": ["cloud1-default-uuid-sisesvc1", "cloud1-default-uuid-sisesvc2", ... ]
+		}
+	*/
+	Resources map[string][]string `json:"resources"`
+}
+
+// WriteVNFRecord serializes a VNF record and stores it in the database
+func WriteVNFRecord(internalVNFID string, record VNFRecord) error {
+	out, err := json.Marshal(record)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Serialize VNF record error")
+	}
+
+	err = DBconn.CreateEntry(internalVNFID, string(out))
+	if err != nil {
+		return pkgerrors.Wrap(err, "Write VNF record error")
+	}
+
+	return nil
+}
+
+// ReadVNFRecord reads the record of a VNF instance. Entries stored before
+// records were introduced only contain the resource map.
+func ReadVNFRecord(internalVNFID string) (VNFRecord, bool, error) {
+	var record VNFRecord
+
+	value, found, err := DBconn.ReadEntry(internalVNFID)
+	if err != nil || found == false {
+		return record, found, err
+	}
+
+	fields := make(map[string]json.RawMessage)
+	err = json.Unmarshal([]byte(value), &fields)
+	if err != nil {
+		return record, true, pkgerrors.Wrap(err, "Deserialize VNF record error")
+	}
+
+	if _, ok := fields["resources"]; !ok {
+		err = json.Unmarshal([]byte(value), &record.Resources)
+	} else {
+		err = json.Unmarshal([]byte(value), &record)
+	}
+	if err != nil {
+		return record, true, pkgerrors.Wrap(err, "Deserialize VNF record error")
+	}
+
+	return record, true, nil
+}
+
+// ListVNFResources returns the names of the objects stored in the records of
+// the VNFs whose internal VNF ID starts with prefix, e.g. cloud1-default-
+func ListVNFResources(prefix string) (map[string]bool, error) {
+	keys, err := DBconn.ReadAll(prefix)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "List VNF records error")
+	}
+
+	recorded := make(map[string]bool)
+	for _, key := range keys {
+		// Revisions and other records aren't VNF records
+		if key == "" || strings.Contains(key, "/") {
+			continue
+		}
+
+		record, found, err := ReadVNFRecord(key)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			continue
+		}
+
+		for _, names := range record.Resources {
+			for _, name := range names {
+				recorded[name] = true
+			}
+		}
+	}
+
+	return recorded, nil
+}
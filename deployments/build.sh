@@ -18,10 +18,10 @@ function generate_binary {
     rm -f *.so
     pushd $GOPATH/src/github.com/shank7485/k8-plugin-multicloud
     $GOPATH/bin/dep ensure -v
-    for plugin in deployment namespace service; do
+    for plugin in deployment namespace service configmap secret daemonset job cronjob pvc ingress networkpolicy statefulset; do
         CGO_ENABLED=0 GOOS=linux GOARCH=amd64 go build -buildmode=plugin -o ./deployments/$plugin.so plugins/$plugin/plugin.go
     done
-    CGO_ENABLED=0 GOOS=linux GOARCH=amd64 go build -a -tags netgo -ldflags '-w' -o ./deployments/k8plugin cmd/main.go
+    CGO_ENABLED=0 GOOS=linux GOARCH=amd64 go build -a -tags netgo -ldflags '-w' -o ./deployments/k8plugin ./cmd
     popd
 }
 
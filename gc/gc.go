@@ -0,0 +1,319 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gc
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+
+	"k8-plugin-multicloud/csar"
+	"k8-plugin-multicloud/db"
+	"k8-plugin-multicloud/krd"
+)
+
+// Orphan is an object created by the plugin for a VNF which is unknown to
+// the database
+type Orphan struct {
+	CloudRegionID string    `json:"cloud_region_id"`
+	Namespace     string    `json:"namespace"`
+	VNFID         string    `json:"vnf_id"`
+	ResourceName  string    `json:"resource"`
+	Name          string    `json:"name"`
+	FirstSeen     time.Time `json:"first_seen"`
+}
+
+func (o Orphan) key() string {
+	return o.CloudRegionID + "|" + o.Namespace + "|" + o.ResourceName + "|" + o.Name
+}
+
+// uuidPrefix matches the external VNF ID at the beginning of an object name
+var uuidPrefix = regexp.MustCompile("^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}-")
+
+var (
+	mutex   sync.Mutex
+	orphans = make(map[string]Orphan)
+)
+
+// ListCloudRegions returns the cloud regions which have a kubeconfig file
+var ListCloudRegions = func() ([]string, error) {
+	files, err := ioutil.ReadDir(os.Getenv("KUBE_CONFIG_DIR"))
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Read kubeconfig directory error")
+	}
+
+	var regions []string
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		regions = append(regions, file.Name())
+	}
+
+	return regions, nil
+}
+
+// GracePeriod is how long orphans are reported before being deleted, longer
+// than the creation of a VNF including its bootstrap jobs
+var GracePeriod = 10 * time.Minute
+
+// Start scans the cloud regions once per interval in the background. Orphans
+// reported for longer than GracePeriod are deleted when enabled, otherwise
+// they are only reported.
+func Start(interval time.Duration, deleteOrphans bool) {
+	log.Println("Starting orphaned resource collector every " + interval.String())
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			_, err := Scan()
+			if err != nil {
+				log.Println("Orphaned resource scan error: " + err.Error())
+				continue
+			}
+
+			if deleteOrphans {
+				_, err = DeleteOrphans("", GracePeriod)
+				if err != nil {
+					log.Println("Orphaned resource deletion error: " + err.Error())
+				}
+			}
+		}
+	}()
+}
+
+// Scan lists the objects created by the plugin in every cloud region whose
+// name carries a cloudregion-namespace-uuid prefix without a matching database
+// entry
+func Scan() ([]Orphan, error) {
+	regions, err := ListCloudRegions()
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]Orphan)
+	for _, region := range regions {
+		err = scanCloudRegion(region, found)
+		if err != nil {
+			return nil, pkgerrors.Wrap(err, "Scan cloud region "+region+" error")
+		}
+	}
+
+	// Keep the time orphans were first seen across scans
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for key, orphan := range found {
+		if previous, ok := orphans[key]; ok {
+			orphan.FirstSeen = previous.FirstSeen
+			found[key] = orphan
+		}
+	}
+	orphans = found
+
+	return listOrphans(), nil
+}
+
+func scanCloudRegion(region string, found map[string]Orphan) error {
+	kubeclient, err := krd.GetKubeClient(os.Getenv("KUBE_CONFIG_DIR") + "/" + region)
+	if err != nil {
+		return err
+	}
+
+	namespaces, err := csar.ListNamespaces(&kubeclient)
+	if err != nil {
+		return err
+	}
+
+	// key: cloud1-default-uuid
+	known := make(map[string]bool)
+	now := time.Now()
+
+	for _, namespace := range namespaces {
+		prefix := region + "-" + namespace + "-"
+
+		// Only the objects created by the plugin can be orphans, their name
+		// only gives the VNF they were created for
+		resources, err := csar.ListResources(namespace, krd.ManagedBySelector, &kubeclient)
+		if err != nil {
+			return err
+		}
+
+		// Claims kept by the retain storage policy outlive their VNF record
+		retained, err := csar.ListResources(namespace, krd.RetainedSelector, &kubeclient)
+		if err != nil {
+			return err
+		}
+		skipped := make(map[string]bool)
+		for _, name := range retained["pvc"] {
+			skipped[name] = true
+		}
+
+		// Retained claims reused by a new VNF keep the VNF ID of their
+		// former VNF in their name
+		recorded, err := db.ListVNFResources(prefix)
+		if err != nil {
+			return err
+		}
+
+		for resourceName, names := range resources {
+			for _, name := range names {
+				if !strings.HasPrefix(name, prefix) || (resourceName == "pvc" && (skipped[name] || recorded[name])) {
+					continue
+				}
+
+				externalVNFID := uuidPrefix.FindString(strings.TrimPrefix(name, prefix))
+				if externalVNFID == "" {
+					continue
+				}
+				externalVNFID = strings.TrimSuffix(externalVNFID, "-")
+				internalVNFID := prefix + externalVNFID
+
+				stored, ok := known[internalVNFID]
+				if !ok {
+					_, stored, err = db.DBconn.ReadEntry(internalVNFID)
+					if err != nil {
+						return err
+					}
+					known[internalVNFID] = stored
+				}
+				if stored {
+					continue
+				}
+
+				orphan := Orphan{
+					CloudRegionID: region,
+					Namespace:     namespace,
+					VNFID:         externalVNFID,
+					ResourceName:  resourceName,
+					Name:          name,
+					FirstSeen:     now,
+				}
+				found[orphan.key()] = orphan
+			}
+		}
+	}
+
+	return nil
+}
+
+// ListOrphans returns the orphans found by the last scan
+func ListOrphans() []Orphan {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return listOrphans()
+}
+
+func listOrphans() []Orphan {
+	result := make([]Orphan, 0, len(orphans))
+	for _, orphan := range orphans {
+		result = append(result, orphan)
+	}
+	return result
+}
+
+// DeleteOrphans deletes the orphans found by the last scan which have been
+// reported for at least minAge. An empty VNF ID deletes the orphans of every
+// VNF.
+func DeleteOrphans(externalVNFID string, minAge time.Duration) ([]Orphan, error) {
+	// The objects are deleted without holding the lock, which only guards
+	// the list of orphans
+	var selected []Orphan
+	mutex.Lock()
+	for _, orphan := range orphans {
+		if externalVNFID != "" && orphan.VNFID != externalVNFID {
+			continue
+		}
+		if time.Since(orphan.FirstSeen) < minAge {
+			continue
+		}
+		selected = append(selected, orphan)
+	}
+	mutex.Unlock()
+
+	var deleted []Orphan
+	for _, orphan := range selected {
+		ok, err := deleteOrphan(orphan)
+		if err != nil {
+			return deleted, err
+		}
+		if ok {
+			deleted = append(deleted, orphan)
+		}
+	}
+
+	return deleted, nil
+}
+
+// deleteOrphan deletes an orphan unless its VNF has been stored since the
+// scan, e.g. once created or adopted, or VNFs are being created in its
+// namespace. The VNF key is locked meanwhile like by the VNF operations.
+func deleteOrphan(orphan Orphan) (bool, error) {
+	prefix := orphan.CloudRegionID + "-" + orphan.Namespace + "-"
+	internalVNFID := prefix + orphan.VNFID
+
+	unlock := db.LockKey(internalVNFID)
+	defer unlock()
+
+	_, stored, err := db.DBconn.ReadEntry(internalVNFID)
+	if err != nil {
+		return false, err
+	}
+
+	recorded, err := db.ListVNFResources(prefix)
+	if err != nil {
+		return false, err
+	}
+
+	if stored || recorded[orphan.Name] {
+		mutex.Lock()
+		delete(orphans, orphan.key())
+		mutex.Unlock()
+		return false, nil
+	}
+
+	creating, err := db.CreatingVNFs(orphan.CloudRegionID, orphan.Namespace)
+	if err != nil || creating {
+		return false, err
+	}
+
+	kubeclient, err := krd.GetKubeClient(os.Getenv("KUBE_CONFIG_DIR") + "/" + orphan.CloudRegionID)
+	if err != nil {
+		return false, err
+	}
+
+	log.Println("Deleting orphaned resource: " + orphan.Name)
+
+	data := map[string][]string{
+		orphan.ResourceName: []string{orphan.Name},
+	}
+	err = csar.DestroyVNF(data, orphan.Namespace, csar.StoragePolicyDelete, &kubeclient)
+	if err != nil {
+		return false, err
+	}
+
+	mutex.Lock()
+	delete(orphans, orphan.key())
+	mutex.Unlock()
+	return true, nil
+}
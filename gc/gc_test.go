@@ -0,0 +1,163 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gc
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+
+	"k8-plugin-multicloud/csar"
+	"k8-plugin-multicloud/db"
+	"k8-plugin-multicloud/krd"
+)
+
+const storedVNFID = "11111111-2222-3333-4444-555555555555"
+const orphanVNFID = "aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee"
+const userVNFID = "99999999-8888-7777-6666-555555555555"
+
+type mockDB struct {
+	db.DatabaseConnection
+}
+
+func (c *mockDB) ReadEntry(key string) (string, bool, error) {
+	if key == "cloud1-default-"+storedVNFID {
+		return "{\"deployment\":[\"cloud1-default-" + storedVNFID + "-sisedeploy\"]," +
+			"\"pvc\":[\"cloud1-default-" + orphanVNFID + "-reused\"]}", true, nil
+	}
+	return "", false, nil
+}
+
+func (c *mockDB) ReadAll(key string) ([]string, error) {
+	return []string{"cloud1-default-" + storedVNFID}, nil
+}
+
+// mockCreatingDB stores a VNF being created in the default namespace
+type mockCreatingDB struct {
+	mockDB
+}
+
+func (c *mockCreatingDB) ReadEntry(key string) (string, bool, error) {
+	if key == "namespaces/cloud1/default" {
+		return "{\"cloud_region_id\":\"cloud1\",\"namespace\":\"default\",\"vnfs\":[\"creating-uuid\"]}", true, nil
+	}
+	return c.mockDB.ReadEntry(key)
+}
+
+func TestOrphanCollection(t *testing.T) {
+	oldListCloudRegions := ListCloudRegions
+	oldGetKubeClient := krd.GetKubeClient
+	oldListNamespaces := csar.ListNamespaces
+	oldListResources := csar.ListResources
+	oldDestroyVNF := csar.DestroyVNF
+
+	defer func() {
+		ListCloudRegions = oldListCloudRegions
+		krd.GetKubeClient = oldGetKubeClient
+		csar.ListNamespaces = oldListNamespaces
+		csar.ListResources = oldListResources
+		csar.DestroyVNF = oldDestroyVNF
+	}()
+
+	db.DBconn = &mockDB{}
+
+	ListCloudRegions = func() ([]string, error) {
+		return []string{"cloud1"}, nil
+	}
+
+	krd.GetKubeClient = func(configPath string) (kubernetes.Clientset, error) {
+		return kubernetes.Clientset{}, nil
+	}
+
+	csar.ListNamespaces = func(kubeclient *kubernetes.Clientset) ([]string, error) {
+		return []string{"default"}, nil
+	}
+
+	csar.ListResources = func(namespace string, labelSelector string, kubeclient *kubernetes.Clientset) (map[string][]string, error) {
+		if labelSelector == krd.RetainedSelector {
+			return map[string][]string{
+				"pvc": []string{"cloud1-default-" + orphanVNFID + "-data"},
+			}, nil
+		}
+		resources := map[string][]string{
+			"deployment": []string{
+				"cloud1-default-" + storedVNFID + "-sisedeploy",
+				"cloud1-default-" + orphanVNFID + "-sisedeploy",
+			},
+			"pvc": []string{"cloud1-default-" + orphanVNFID + "-data", "cloud1-default-" + orphanVNFID + "-reused"},
+		}
+		if labelSelector != krd.ManagedBySelector {
+			// Objects of the users, one of them named like a VNF object
+			resources["deployment"] = append(resources["deployment"], "unmanaged-deploy",
+				"cloud1-default-"+userVNFID+"-userdeploy")
+		}
+		return resources, nil
+	}
+
+	t.Run("Successfully find orphaned resources", func(t *testing.T) {
+		result, err := Scan()
+		if err != nil {
+			t.Fatalf("TestOrphanCollection returned an error (%s)", err)
+		}
+
+		if len(result) != 1 || result[0].VNFID != orphanVNFID || result[0].ResourceName != "deployment" {
+			t.Fatalf("TestOrphanCollection returned unexpected orphans (%v)", result)
+		}
+	})
+
+	t.Run("Keep orphaned resources during the grace period", func(t *testing.T) {
+		csar.DestroyVNF = func(data map[string][]string, namespace string, storagePolicy string, kubeclient *kubernetes.Clientset) error {
+			t.Fatalf("TestOrphanCollection deleted resources during the grace period")
+			return nil
+		}
+
+		deleted, err := DeleteOrphans("", time.Hour)
+		if err != nil || len(deleted) != 0 {
+			t.Fatalf("TestOrphanCollection returned unexpected result (%v, %s)", deleted, err)
+		}
+	})
+
+	t.Run("Keep orphaned resources while VNFs are created in their namespace", func(t *testing.T) {
+		db.DBconn = &mockCreatingDB{}
+		defer func() {
+			db.DBconn = &mockDB{}
+		}()
+
+		csar.DestroyVNF = func(data map[string][]string, namespace string, storagePolicy string, kubeclient *kubernetes.Clientset) error {
+			t.Fatalf("TestOrphanCollection deleted resources while a VNF is created")
+			return nil
+		}
+
+		deleted, err := DeleteOrphans("", 0)
+		if err != nil || len(deleted) != 0 || len(ListOrphans()) != 1 {
+			t.Fatalf("TestOrphanCollection returned unexpected result (%v, %s)", deleted, err)
+		}
+	})
+
+	t.Run("Successfully delete orphaned resources", func(t *testing.T) {
+		csar.DestroyVNF = func(data map[string][]string, namespace string, storagePolicy string, kubeclient *kubernetes.Clientset) error {
+			return nil
+		}
+
+		deleted, err := DeleteOrphans(orphanVNFID, 0)
+		if err != nil || len(deleted) != 1 {
+			t.Fatalf("TestOrphanCollection returned unexpected result (%v, %s)", deleted, err)
+		}
+
+		if len(ListOrphans()) != 0 {
+			t.Fatalf("TestOrphanCollection kept deleted orphans (%v)", ListOrphans())
+		}
+	})
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package krd
+
+import (
+	"strconv"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// ErrDryRunUnsupported is returned for clusters without server side dry run
+var ErrDryRunUnsupported = pkgerrors.New("Server side dry run not supported by the cluster")
+
+// CheckDryRunSupport fails for the clusters older than Kubernetes 1.13, where
+// server side dry run became beta. Older API servers ignore the dryRun
+// parameter and would persist the objects.
+var CheckDryRunSupport = func(kubeclient *kubernetes.Clientset) error {
+	info, err := kubeclient.Discovery().ServerVersion()
+	if err != nil {
+		return pkgerrors.Wrap(err, "Get server version error")
+	}
+
+	major, err := strconv.Atoi(info.Major)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Parse server version "+info.GitVersion+" error")
+	}
+
+	// Some providers report minor versions like "13+"
+	minor, err := strconv.Atoi(strings.TrimSuffix(info.Minor, "+"))
+	if err != nil {
+		return pkgerrors.Wrap(err, "Parse server version "+info.GitVersion+" error")
+	}
+
+	if major < 1 || (major == 1 && minor < 13) {
+		return pkgerrors.Wrap(ErrDryRunUnsupported, "Kubernetes "+info.GitVersion)
+	}
+
+	return nil
+}
+
+// DryRunCreate submits an object with a server side dry run, so it goes
+// through validation and admission without being persisted. The object as
+// returned by the cluster is stored in kubedata.Rendered. With RenderOnly set
+// the object is only stored.
+func DryRunCreate(client rest.Interface, resource string, obj runtime.Object, kubedata *GenericKubeResourceData) (string, error) {
+	kubedata.Rendered = obj
+
+	if kubedata.RenderOnly {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return "", pkgerrors.Wrap(err, "Render "+resource+" error")
+		}
+		return accessor.GetName(), nil
+	}
+
+	result := obj.DeepCopyObject()
+	err := client.Post().
+		Namespace(kubedata.Namespace).
+		Resource(resource).
+		Param("dryRun", "All").
+		Body(obj).
+		Do().
+		Into(result)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Dry run create "+resource+" error")
+	}
+	kubedata.Rendered = result
+
+	accessor, err := meta.Accessor(result)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Dry run create "+resource+" error")
+	}
+
+	return accessor.GetName(), nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package krd
+
+import (
+	"reflect"
+
+	appsV1 "k8s.io/api/apps/v1"
+	batchV1 "k8s.io/api/batch/v1"
+	batchV1beta1 "k8s.io/api/batch/v1beta1"
+	coreV1 "k8s.io/api/core/v1"
+	extensionsV1beta1 "k8s.io/api/extensions/v1beta1"
+	networkingV1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// ResourceObjects maps every resource plugin to the type of the objects it
+// accepts once their manifest is decoded
+var ResourceObjects = map[string]runtime.Object{
+	"deployment":    &appsV1.Deployment{},
+	"service":       &coreV1.Service{},
+	"configmap":     &coreV1.ConfigMap{},
+	"secret":        &coreV1.Secret{},
+	"daemonset":     &appsV1.DaemonSet{},
+	"statefulset":   &appsV1.StatefulSet{},
+	"job":           &batchV1.Job{},
+	"cronjob":       &batchV1beta1.CronJob{},
+	"pvc":           &coreV1.PersistentVolumeClaim{},
+	"ingress":       &extensionsV1beta1.Ingress{},
+	"networkpolicy": &networkingV1.NetworkPolicy{},
+}
+
+// ResourceGroupVersionKinds maps every resource plugin to the group, version
+// and kind of the objects it creates
+var ResourceGroupVersionKinds = resourceGroupVersionKinds()
+
+// ResourceKinds maps every resource plugin to the kind of the objects it
+// creates
+var ResourceKinds = resourceKinds()
+
+func resourceGroupVersionKinds() map[string]schema.GroupVersionKind {
+	result := make(map[string]schema.GroupVersionKind)
+	for resourceName, obj := range ResourceObjects {
+		gvks, _, err := scheme.Scheme.ObjectKinds(obj)
+		if err != nil {
+			panic("No kind registered for resource " + resourceName)
+		}
+		result[resourceName] = gvks[0]
+	}
+	return result
+}
+
+func resourceKinds() map[string]string {
+	result := make(map[string]string)
+	for resourceName, gvk := range ResourceGroupVersionKinds {
+		result[resourceName] = gvk.Kind
+	}
+	return result
+}
+
+// IsResourceObject reports whether a decoded object has the type accepted by
+// a resource plugin
+func IsResourceObject(resourceName string, obj runtime.Object) bool {
+	expected, ok := ResourceObjects[resourceName]
+	return ok && reflect.TypeOf(obj) == reflect.TypeOf(expected)
+}
+
+// PluginForKind returns the resource plugin creating the objects of a kind
+func PluginForKind(kind string) (string, bool) {
+	for resourceName, resourceKind := range ResourceKinds {
+		if resourceKind == kind {
+			return resourceName, true
+		}
+	}
+	return "", false
+}
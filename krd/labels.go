@@ -0,0 +1,87 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package krd
+
+import (
+	"encoding/json"
+
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Labels and annotations stamped on every object created for a VNF
+const (
+	ManagedByLabel    = "app.kubernetes.io/managed-by"
+	VNFIDLabel        = "k8plugin.onap.org/vnf-id"
+	CloudRegionLabel  = "k8plugin.onap.org/cloud-region"
+	CsarIDAnnotation  = "k8plugin.onap.org/csar-id"
+	VersionAnnotation = "k8plugin.onap.org/plugin-version"
+
+	// RetainedLabel marks the persistent volume claims kept after their VNF
+	// was deleted with the retain storage policy
+	RetainedLabel = "k8plugin.onap.org/retained"
+)
+
+// ManagedByValue identifies the objects created by this plugin
+const ManagedByValue = "k8plugin"
+
+// PluginVersion is the version of the plugin that created an object
+var PluginVersion = "v1"
+
+// ManagedBySelector selects every object created by this plugin
+var ManagedBySelector = ManagedByLabel + "=" + ManagedByValue
+
+// RetainedSelector selects the persistent volume claims kept after their VNF
+// was deleted
+var RetainedSelector = RetainedLabel + "=true"
+
+// VNFSelector returns the label selector matching the objects of a VNF
+func VNFSelector(externalVNFID string) string {
+	return VNFIDLabel + "=" + externalVNFID
+}
+
+// AddOwnershipMetadata stamps the VNF ownership labels and annotations on an
+// object or a pod template. The cloud region is only used as a label when it
+// is a valid label value.
+func AddOwnershipMetadata(meta *metaV1.ObjectMeta, kubedata *GenericKubeResourceData) {
+	if meta.Labels == nil {
+		meta.Labels = make(map[string]string)
+	}
+	meta.Labels[ManagedByLabel] = ManagedByValue
+	meta.Labels[VNFIDLabel] = kubedata.ExternalVNFID
+	if len(validation.IsValidLabelValue(kubedata.CloudRegionID)) == 0 {
+		meta.Labels[CloudRegionLabel] = kubedata.CloudRegionID
+	}
+
+	if meta.Annotations == nil {
+		meta.Annotations = make(map[string]string)
+	}
+	meta.Annotations[CloudRegionLabel] = kubedata.CloudRegionID
+	meta.Annotations[CsarIDAnnotation] = kubedata.CsarID
+	meta.Annotations[VersionAnnotation] = PluginVersion
+}
+
+// OwnershipPatch returns the JSON merge patch stamping the VNF ownership
+// labels and annotations on an existing object
+func OwnershipPatch(kubedata *GenericKubeResourceData) ([]byte, error) {
+	var meta metaV1.ObjectMeta
+	AddOwnershipMetadata(&meta, kubedata)
+
+	return json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels":      meta.Labels,
+			"annotations": meta.Annotations,
+		},
+	})
+}
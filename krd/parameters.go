@@ -0,0 +1,201 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package krd
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	pkgerrors "github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// templateVariable matches the {{ name }} placeholders of a manifest
+var templateVariable = regexp.MustCompile(`\{\{\s*([^}]*?)\s*\}\}`)
+
+// TemplateVariables returns the sorted names of the placeholders used in a
+// manifest
+func TemplateVariables(rawBytes []byte) []string {
+	found := make(map[string]bool)
+	for _, match := range templateVariable.FindAllSubmatch(rawBytes, -1) {
+		found[string(match[1])] = true
+	}
+
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// SubstituteParameters replaces the {{ name }} placeholders of a manifest
+// naming a parameter with its value. The other placeholders are left as they
+// are, e.g. the templates of an application configuration.
+//
+// The values are set in the parsed YAML scalars holding the placeholders and
+// the manifest is then written again, so a value can't add keys or objects to
+// the manifest. Values containing a line break or another control character
+// are rejected. An unquoted placeholder making up a whole scalar gives the
+// number or boolean its value stands for, e.g. `replicas: {{ replicas }}`.
+func SubstituteParameters(rawBytes []byte, parameters map[string]string) ([]byte, error) {
+	for name, value := range parameters {
+		if strings.IndexFunc(value, unicode.IsControl) >= 0 {
+			return nil, pkgerrors.New("The value of parameter " + name + " contains a line break or a control character")
+		}
+	}
+
+	// The placeholders are replaced by plain scalar tokens which don't occur
+	// in the manifest, so the manifest can be parsed whatever their position
+	prefix := "krdparameter"
+	for bytes.Contains(rawBytes, []byte(prefix)) {
+		prefix += "x"
+	}
+
+	var replacements []string
+	tokens := make(map[string]string)
+	typedValues := make(map[string]interface{})
+	var tokenized []byte
+	last := 0
+	for _, match := range templateVariable.FindAllSubmatchIndex(rawBytes, -1) {
+		start, end := match[0], match[1]
+		name := string(rawBytes[match[2]:match[3]])
+		value, ok := parameters[name]
+		if !ok {
+			continue
+		}
+
+		// Quoted placeholders always give strings, the others the type of
+		// their value when they make up the whole scalar
+		quoted := start > 0 && end < len(rawBytes) &&
+			(rawBytes[start-1] == '"' || rawBytes[start-1] == '\'') && rawBytes[end] == rawBytes[start-1]
+		key := name
+		if quoted {
+			key += "\""
+		}
+
+		token, ok := tokens[key]
+		if !ok {
+			token = prefix + strconv.Itoa(len(tokens)) + "x"
+			tokens[key] = token
+			replacements = append(replacements, token, value)
+			if typedValue, ok := scalarValue(value); ok && !quoted {
+				typedValues[token] = typedValue
+			}
+		}
+		tokenized = append(append(tokenized, rawBytes[last:start]...), token...)
+		last = end
+	}
+	if len(tokens) == 0 {
+		return rawBytes, nil
+	}
+	tokenized = append(tokenized, rawBytes[last:]...)
+	replacer := strings.NewReplacer(replacements...)
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	decoder := yaml.NewDecoder(bytes.NewReader(tokenized))
+	for {
+		var document yaml.MapSlice
+		err := decoder.Decode(&document)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, pkgerrors.Wrap(err, "Parse manifest error")
+		}
+		if len(document) == 0 {
+			// Empty or commented out document
+			continue
+		}
+
+		err = encoder.Encode(substituteScalars(document, replacer, typedValues))
+		if err != nil {
+			return nil, pkgerrors.Wrap(err, "Write manifest error")
+		}
+	}
+
+	err := encoder.Close()
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Write manifest error")
+	}
+
+	return out.Bytes(), nil
+}
+
+// substituteScalars replaces the parameter tokens of the string scalars of a
+// parsed YAML document, keys included. Scalars made of a single token of a
+// typed value are replaced by the value itself.
+func substituteScalars(node interface{}, replacer *strings.Replacer, typedValues map[string]interface{}) interface{} {
+	switch n := node.(type) {
+	case string:
+		if value, ok := typedValues[n]; ok {
+			return value
+		}
+		return replacer.Replace(n)
+	case yaml.MapSlice:
+		for i := range n {
+			n[i].Key = substituteScalars(n[i].Key, replacer, typedValues)
+			n[i].Value = substituteScalars(n[i].Value, replacer, typedValues)
+		}
+	case []interface{}:
+		for i := range n {
+			n[i] = substituteScalars(n[i], replacer, typedValues)
+		}
+	}
+	return node
+}
+
+// scalarValue returns the number or boolean a parameter value stands for in
+// YAML, e.g. 3 for "3". Other values are strings.
+func scalarValue(value string) (interface{}, bool) {
+	var typedValue interface{}
+	err := yaml.Unmarshal([]byte(value), &typedValue)
+	if err != nil {
+		return nil, false
+	}
+
+	switch typedValue.(type) {
+	case int, int64, uint64, float64, bool:
+		return typedValue, true
+	}
+	return nil, false
+}
+
+// ReadManifest reads the YAML manifest of a resource with its parameters
+// substituted. Manifests rendered from charts are returned as they are.
+func ReadManifest(kubedata *GenericKubeResourceData) ([]byte, error) {
+	if kubedata.YamlData != nil {
+		return kubedata.YamlData, nil
+	}
+
+	if _, err := os.Stat(kubedata.YamlFilePath); err != nil {
+		return nil, pkgerrors.New("File " + kubedata.YamlFilePath + " not found")
+	}
+
+	rawBytes, err := ioutil.ReadFile(kubedata.YamlFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return SubstituteParameters(rawBytes, kubedata.Parameters)
+}
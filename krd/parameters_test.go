@@ -0,0 +1,122 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package krd
+
+import (
+	"testing"
+
+	ghodssyaml "github.com/ghodss/yaml"
+	"gopkg.in/yaml.v2"
+	appsV1 "k8s.io/api/apps/v1"
+)
+
+const parameterManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: sise-deploy
+  annotations:
+    greeting: "{{ greeting }}"
+spec:
+  template:
+    spec:
+      containers:
+      - name: sise
+        image: {{ sise_image }}
+`
+
+func TestSubstituteParameters(t *testing.T) {
+	t.Run("Values set in the scalars", func(t *testing.T) {
+		rawBytes, err := SubstituteParameters([]byte(parameterManifest), map[string]string{
+			"sise_image": "sise:1.0 # {a: b}",
+		})
+		if err != nil {
+			t.Fatalf("TestSubstituteParameters returned an error (%s)", err)
+		}
+
+		var manifest struct {
+			Metadata struct {
+				Annotations map[string]string `yaml:"annotations"`
+			} `yaml:"metadata"`
+			Spec struct {
+				Template struct {
+					Spec struct {
+						Containers []map[string]interface{} `yaml:"containers"`
+					} `yaml:"spec"`
+				} `yaml:"template"`
+			} `yaml:"spec"`
+		}
+		err = yaml.Unmarshal(rawBytes, &manifest)
+		if err != nil {
+			t.Fatalf("TestSubstituteParameters returned an invalid manifest (%s):\n%s", err, rawBytes)
+		}
+
+		containers := manifest.Spec.Template.Spec.Containers
+		if len(containers) != 1 || len(containers[0]) != 2 || containers[0]["image"] != "sise:1.0 # {a: b}" {
+			t.Fatalf("TestSubstituteParameters returned unexpected containers: %v", containers)
+		}
+		if manifest.Metadata.Annotations["greeting"] != "{{ greeting }}" {
+			t.Fatalf("TestSubstituteParameters replaced an undeclared placeholder: %v", manifest.Metadata.Annotations)
+		}
+	})
+
+	t.Run("Value adding keys", func(t *testing.T) {
+		_, err := SubstituteParameters([]byte(parameterManifest), map[string]string{
+			"sise_image": "sise:1.0\n  hostNetwork: true",
+		})
+		if err == nil {
+			t.Fatalf("TestSubstituteParameters didn't reject a value containing a line break")
+		}
+	})
+
+	t.Run("Typed values", func(t *testing.T) {
+		manifest := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: sise-deploy
+  annotations:
+    replicas: "{{ replicas }}"
+spec:
+  replicas: {{ replicas }}
+  paused: {{ paused }}
+  template:
+    spec:
+      containers:
+      - name: sise
+        image: sise:{{ replicas }}
+`
+		rawBytes, err := SubstituteParameters([]byte(manifest), map[string]string{
+			"replicas": "3",
+			"paused":   "false",
+		})
+		if err != nil {
+			t.Fatalf("TestSubstituteParameters returned an error (%s)", err)
+		}
+
+		var deployment appsV1.Deployment
+		err = ghodssyaml.Unmarshal(rawBytes, &deployment)
+		if err != nil {
+			t.Fatalf("TestSubstituteParameters returned an invalid Deployment (%s):\n%s", err, rawBytes)
+		}
+
+		if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas != 3 || deployment.Spec.Paused {
+			t.Fatalf("TestSubstituteParameters returned an unexpected spec:\n%s", rawBytes)
+		}
+		if deployment.Annotations["replicas"] != "3" {
+			t.Fatalf("TestSubstituteParameters didn't keep a quoted value a string:\n%s", rawBytes)
+		}
+		if deployment.Spec.Template.Spec.Containers[0].Image != "sise:3" {
+			t.Fatalf("TestSubstituteParameters returned an unexpected image:\n%s", rawBytes)
+		}
+	})
+}
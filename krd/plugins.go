@@ -4,7 +4,12 @@ import (
 	"plugin"
 
 	appsV1 "k8s.io/api/apps/v1"
+	batchV1 "k8s.io/api/batch/v1"
+	batchV1beta1 "k8s.io/api/batch/v1beta1"
 	coreV1 "k8s.io/api/core/v1"
+	extensionsV1beta1 "k8s.io/api/extensions/v1beta1"
+	networkingV1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -14,7 +19,7 @@ var LoadedPlugins = map[string]*plugin.Plugin{}
 // KubeResourceClient has the signature methods to create Kubernetes reources
 type KubeResourceClient interface {
 	CreateResource(GenericKubeResourceData, *kubernetes.Clientset) (string, error)
-	ListResources(string, string) (*[]string, error)
+	ListResources(int64, string, string, *kubernetes.Clientset) (*[]string, error)
 	DeleteResource(string, string, *kubernetes.Clientset) error
 	GetResource(string, string, *kubernetes.Clientset) (string, error)
 }
@@ -24,8 +29,43 @@ type GenericKubeResourceData struct {
 	YamlFilePath  string
 	Namespace     string
 	InternalVNFID string
+	ExternalVNFID string
+	CloudRegionID string
+	CsarID        string
+
+	// Name and Files are used by plugins that build objects from raw CSAR
+	// files instead of a YAML manifest. Files maps data keys to file paths.
+	Name  string
+	Files map[string]string
+
+	// RenamedResources maps, per plugin, the names declared in the CSAR to
+	// the names created for this VNF
+	RenamedResources map[string]map[string]string
+
+	// YamlData is a manifest rendered from a chart, used instead of the
+	// YamlFilePath file
+	YamlData []byte
+
+	// Parameters are substituted in the {{ name }} placeholders of the manifest
+	Parameters map[string]string
+
+	// DryRun submits the object with a server side dry run instead of
+	// creating it, RenderOnly doesn't contact the cluster at all. Rendered
+	// holds the object built by the plugin.
+	DryRun     bool
+	RenderOnly bool
+	Rendered   runtime.Object
 
 	// Add additional Kubernetes plugins below kinds
-	DeploymentData *appsV1.Deployment
-	ServiceData    *coreV1.Service
+	DeploymentData    *appsV1.Deployment
+	ServiceData       *coreV1.Service
+	ConfigMapData     *coreV1.ConfigMap
+	SecretData        *coreV1.Secret
+	DaemonSetData     *appsV1.DaemonSet
+	StatefulSetData   *appsV1.StatefulSet
+	JobData           *batchV1.Job
+	CronJobData       *batchV1beta1.CronJob
+	PVCData           *coreV1.PersistentVolumeClaim
+	IngressData       *extensionsV1beta1.Ingress
+	NetworkPolicyData *networkingV1.NetworkPolicy
 }
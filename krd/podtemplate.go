@@ -0,0 +1,99 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package krd
+
+import (
+	coreV1 "k8s.io/api/core/v1"
+)
+
+// UpdatePodReferences replaces the ConfigMap, Secret and PersistentVolumeClaim
+// names referenced by a pod spec with the names created for the VNF
+func UpdatePodReferences(spec *coreV1.PodSpec, renamed map[string]map[string]string) {
+	if spec == nil || len(renamed) == 0 {
+		return
+	}
+
+	configMaps := renamed["configmap"]
+	secrets := renamed["secret"]
+	claims := renamed["pvc"]
+
+	for i := range spec.Volumes {
+		volume := &spec.Volumes[i]
+		if volume.ConfigMap != nil {
+			volume.ConfigMap.Name = lookupName(configMaps, volume.ConfigMap.Name)
+		}
+		if volume.Secret != nil {
+			volume.Secret.SecretName = lookupName(secrets, volume.Secret.SecretName)
+		}
+		if volume.PersistentVolumeClaim != nil {
+			volume.PersistentVolumeClaim.ClaimName = lookupName(claims, volume.PersistentVolumeClaim.ClaimName)
+		}
+		if volume.Projected != nil {
+			for j := range volume.Projected.Sources {
+				source := &volume.Projected.Sources[j]
+				if source.ConfigMap != nil {
+					source.ConfigMap.Name = lookupName(configMaps, source.ConfigMap.Name)
+				}
+				if source.Secret != nil {
+					source.Secret.Name = lookupName(secrets, source.Secret.Name)
+				}
+			}
+		}
+	}
+
+	for i := range spec.ImagePullSecrets {
+		spec.ImagePullSecrets[i].Name = lookupName(secrets, spec.ImagePullSecrets[i].Name)
+	}
+
+	updateContainerReferences(spec.InitContainers, configMaps, secrets)
+	updateContainerReferences(spec.Containers, configMaps, secrets)
+}
+
+func updateContainerReferences(containers []coreV1.Container, configMaps, secrets map[string]string) {
+	for i := range containers {
+		container := &containers[i]
+
+		for j := range container.EnvFrom {
+			envFrom := &container.EnvFrom[j]
+			if envFrom.ConfigMapRef != nil {
+				envFrom.ConfigMapRef.Name = lookupName(configMaps, envFrom.ConfigMapRef.Name)
+			}
+			if envFrom.SecretRef != nil {
+				envFrom.SecretRef.Name = lookupName(secrets, envFrom.SecretRef.Name)
+			}
+		}
+
+		for j := range container.Env {
+			valueFrom := container.Env[j].ValueFrom
+			if valueFrom == nil {
+				continue
+			}
+			if valueFrom.ConfigMapKeyRef != nil {
+				valueFrom.ConfigMapKeyRef.Name = lookupName(configMaps, valueFrom.ConfigMapKeyRef.Name)
+			}
+			if valueFrom.SecretKeyRef != nil {
+				valueFrom.SecretKeyRef.Name = lookupName(secrets, valueFrom.SecretKeyRef.Name)
+			}
+		}
+	}
+}
+
+// lookupName returns the VNF name for a CSAR name, or the same name when the
+// object wasn't created as part of the VNF
+func lookupName(names map[string]string, name string) string {
+	if newName, ok := names[name]; ok {
+		return newName
+	}
+	return name
+}
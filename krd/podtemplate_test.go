@@ -0,0 +1,110 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package krd
+
+import (
+	"reflect"
+	"testing"
+
+	coreV1 "k8s.io/api/core/v1"
+)
+
+// referencingPodSpec returns a pod spec referencing the sise-config ConfigMap,
+// the sise-secret Secret and the sise-data claim with a prefix in every way a
+// pod can, along with the shared-config ConfigMap and shared-secret Secret
+// which aren't part of the VNF
+func referencingPodSpec(prefix string) coreV1.PodSpec {
+	config := coreV1.LocalObjectReference{Name: prefix + "sise-config"}
+	secret := coreV1.LocalObjectReference{Name: prefix + "sise-secret"}
+	sharedConfig := coreV1.LocalObjectReference{Name: "shared-config"}
+	sharedSecret := coreV1.LocalObjectReference{Name: "shared-secret"}
+
+	container := coreV1.Container{
+		Name: "sise",
+		EnvFrom: []coreV1.EnvFromSource{
+			{ConfigMapRef: &coreV1.ConfigMapEnvSource{LocalObjectReference: config}},
+			{SecretRef: &coreV1.SecretEnvSource{LocalObjectReference: secret}},
+			{ConfigMapRef: &coreV1.ConfigMapEnvSource{LocalObjectReference: sharedConfig}},
+		},
+		Env: []coreV1.EnvVar{
+			{Name: "PLAIN", Value: "sise-config"},
+			{Name: "MODE", ValueFrom: &coreV1.EnvVarSource{
+				ConfigMapKeyRef: &coreV1.ConfigMapKeySelector{LocalObjectReference: config, Key: "mode"},
+			}},
+			{Name: "PASSWORD", ValueFrom: &coreV1.EnvVarSource{
+				SecretKeyRef: &coreV1.SecretKeySelector{LocalObjectReference: secret, Key: "password"},
+			}},
+			{Name: "TOKEN", ValueFrom: &coreV1.EnvVarSource{
+				SecretKeyRef: &coreV1.SecretKeySelector{LocalObjectReference: sharedSecret, Key: "token"},
+			}},
+		},
+	}
+
+	return coreV1.PodSpec{
+		Volumes: []coreV1.Volume{
+			{Name: "config", VolumeSource: coreV1.VolumeSource{
+				ConfigMap: &coreV1.ConfigMapVolumeSource{LocalObjectReference: config},
+			}},
+			{Name: "secret", VolumeSource: coreV1.VolumeSource{
+				Secret: &coreV1.SecretVolumeSource{SecretName: prefix + "sise-secret"},
+			}},
+			{Name: "data", VolumeSource: coreV1.VolumeSource{
+				PersistentVolumeClaim: &coreV1.PersistentVolumeClaimVolumeSource{ClaimName: prefix + "sise-data"},
+			}},
+			{Name: "shared", VolumeSource: coreV1.VolumeSource{
+				PersistentVolumeClaim: &coreV1.PersistentVolumeClaimVolumeSource{ClaimName: "shared-data"},
+			}},
+			{Name: "projected", VolumeSource: coreV1.VolumeSource{
+				Projected: &coreV1.ProjectedVolumeSource{
+					Sources: []coreV1.VolumeProjection{
+						{ConfigMap: &coreV1.ConfigMapProjection{LocalObjectReference: config}},
+						{Secret: &coreV1.SecretProjection{LocalObjectReference: secret}},
+						{Secret: &coreV1.SecretProjection{LocalObjectReference: sharedSecret}},
+					},
+				},
+			}},
+		},
+		ImagePullSecrets: []coreV1.LocalObjectReference{secret, sharedSecret},
+		InitContainers:   []coreV1.Container{*container.DeepCopy()},
+		Containers:       []coreV1.Container{*container.DeepCopy()},
+	}
+}
+
+func TestUpdatePodReferences(t *testing.T) {
+	t.Run("Successfully rename the references of a pod", func(t *testing.T) {
+		spec := referencingPodSpec("")
+		UpdatePodReferences(&spec, map[string]map[string]string{
+			"configmap": {"sise-config": "vnf-sise-config"},
+			"secret":    {"sise-secret": "vnf-sise-secret"},
+			"pvc":       {"sise-data": "vnf-sise-data"},
+		})
+
+		expected := referencingPodSpec("vnf-")
+		if !reflect.DeepEqual(expected, spec) {
+			t.Fatalf("TestUpdatePodReferences returned:\n result=%+v\n expected=%+v", spec, expected)
+		}
+	})
+
+	t.Run("Keep the references without renamed objects", func(t *testing.T) {
+		spec := referencingPodSpec("")
+		UpdatePodReferences(&spec, map[string]map[string]string{
+			"configmap": {"other-config": "vnf-other-config"},
+		})
+
+		expected := referencingPodSpec("")
+		if !reflect.DeepEqual(expected, spec) {
+			t.Fatalf("TestUpdatePodReferences returned:\n result=%+v\n expected=%+v", spec, expected)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package krd
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/ghodss/yaml"
+	pkgerrors "github.com/pkg/errors"
+	coreV1 "k8s.io/api/core/v1"
+)
+
+// Names of the ResourceQuota and LimitRange created from a quota profile
+const (
+	QuotaName      = "k8plugin-quota"
+	LimitRangeName = "k8plugin-limits"
+)
+
+// defaultQuotaProfile is the profile used when none matches
+const defaultQuotaProfile = "default"
+
+// QuotaProfile holds the ResourceQuota and LimitRange specs applied to the
+// namespaces created by the plugin
+type QuotaProfile struct {
+	ResourceQuota *coreV1.ResourceQuotaSpec `json:"resource_quota,omitempty"`
+	LimitRange    *coreV1.LimitRangeSpec    `json:"limit_range,omitempty"`
+}
+
+// ReadQuotaProfile reads the first profile found among the given names as
+// <name>.yaml in QUOTA_PROFILE_DIR, falling back to default.yaml. No profile
+// is returned when QUOTA_PROFILE_DIR is not set or none is found.
+var ReadQuotaProfile = func(names ...string) (*QuotaProfile, error) {
+	dir := os.Getenv("QUOTA_PROFILE_DIR")
+	if dir == "" {
+		return nil, nil
+	}
+
+	for _, name := range append(names, defaultQuotaProfile) {
+		if name == "" {
+			continue
+		}
+
+		rawBytes, err := ioutil.ReadFile(dir + "/" + name + ".yaml")
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, pkgerrors.Wrap(err, "Read quota profile "+name+" error")
+		}
+
+		var profile QuotaProfile
+		err = yaml.Unmarshal(rawBytes, &profile)
+		if err != nil {
+			return nil, pkgerrors.Wrap(err, "Parse quota profile "+name+" error")
+		}
+
+		return &profile, nil
+	}
+
+	return nil, nil
+}
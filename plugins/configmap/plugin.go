@@ -0,0 +1,217 @@
+package main
+
+import (
+	"io/ioutil"
+	"log"
+	"unicode/utf8"
+
+	"k8s.io/client-go/kubernetes"
+
+	pkgerrors "github.com/pkg/errors"
+
+	coreV1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"k8-plugin-multicloud/krd"
+)
+
+func main() {}
+
+// CreateResource object in a specific Kubernetes ConfigMap
+func CreateResource(kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) (string, error) {
+	if kubedata.Namespace == "" {
+		kubedata.Namespace = "default"
+	}
+
+	if kubedata.YamlFilePath != "" {
+		err := readConfigMapYAML(kubedata)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		err := buildConfigMapFromFiles(kubedata)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	kubedata.ConfigMapData.Namespace = kubedata.Namespace
+	kubedata.ConfigMapData.Name = kubedata.InternalVNFID + "-" + kubedata.ConfigMapData.Name
+	krd.AddOwnershipMetadata(&kubedata.ConfigMapData.ObjectMeta, kubedata)
+
+	if kubedata.DryRun || kubedata.RenderOnly {
+		return krd.DryRunCreate(kubeclient.CoreV1().RESTClient(), "configmaps", kubedata.ConfigMapData, kubedata)
+	}
+
+	result, err := kubeclient.CoreV1().ConfigMaps(kubedata.Namespace).Create(kubedata.ConfigMapData)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Create ConfigMap error")
+	}
+
+	return result.GetObjectMeta().GetName(), nil
+}
+
+func readConfigMapYAML(kubedata *krd.GenericKubeResourceData) error {
+	log.Println("Reading configmap YAML")
+	rawBytes, err := krd.ReadManifest(kubedata)
+	if err != nil {
+		return pkgerrors.Wrap(err, "ConfigMap YAML file read error")
+	}
+
+	log.Println("Decoding configmap YAML")
+	decode := scheme.Codecs.UniversalDeserializer().Decode
+	obj, _, err := decode(rawBytes, nil, nil)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Deserialize configmap error")
+	}
+
+	switch o := obj.(type) {
+	case *coreV1.ConfigMap:
+		kubedata.ConfigMapData = o
+	default:
+		return pkgerrors.New(kubedata.YamlFilePath + " contains another resource different than ConfigMap")
+	}
+
+	return nil
+}
+
+func buildConfigMapFromFiles(kubedata *krd.GenericKubeResourceData) error {
+	if kubedata.Name == "" {
+		return pkgerrors.New("ConfigMap name not provided")
+	}
+
+	kubedata.ConfigMapData = &coreV1.ConfigMap{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name: kubedata.Name,
+		},
+		Data:       map[string]string{},
+		BinaryData: map[string][]byte{},
+	}
+
+	for key, path := range kubedata.Files {
+		log.Println("Reading configmap file: " + path)
+		rawBytes, err := ioutil.ReadFile(path)
+		if err != nil {
+			return pkgerrors.Wrap(err, "ConfigMap file "+path+" read error")
+		}
+
+		if utf8.Valid(rawBytes) {
+			kubedata.ConfigMapData.Data[key] = string(rawBytes)
+		} else {
+			kubedata.ConfigMapData.BinaryData[key] = rawBytes
+		}
+	}
+
+	return nil
+}
+
+// ListResources of existing configmaps hosted in a specific Kubernetes namespace
+func ListResources(limit int64, namespace string, labelSelector string, kubeclient *kubernetes.Clientset) (*[]string, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	opts := metaV1.ListOptions{
+		Limit:         limit,
+		LabelSelector: labelSelector,
+	}
+	opts.APIVersion = "v1"
+	opts.Kind = "ConfigMap"
+
+	list, err := kubeclient.CoreV1().ConfigMaps(namespace).List(opts)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Get ConfigMap list error")
+	}
+
+	result := make([]string, 0, limit)
+	if list != nil {
+		for _, configMap := range list.Items {
+			result = append(result, configMap.Name)
+		}
+	}
+
+	return &result, nil
+}
+
+// DeleteResource deletes an existing Kubernetes configmap
+func DeleteResource(name string, namespace string, kubeclient *kubernetes.Clientset) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	log.Println("Deleting configmap: " + name)
+
+	deletePolicy := metaV1.DeletePropagationForeground
+	err := kubeclient.CoreV1().ConfigMaps(namespace).Delete(name, &metaV1.DeleteOptions{
+		PropagationPolicy: &deletePolicy,
+	})
+	if err != nil {
+		return pkgerrors.Wrap(err, "Delete ConfigMap error")
+	}
+
+	return nil
+}
+
+// GetResource existing configmap hosted in a specific Kubernetes namespace
+func GetResource(name string, namespace string, kubeclient *kubernetes.Clientset) (string, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	configMap, err := kubeclient.CoreV1().ConfigMaps(namespace).Get(name, metaV1.GetOptions{})
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", pkgerrors.Wrap(err, "Get ConfigMap error")
+	}
+
+	return configMap.Name, nil
+}
+
+// LabelResource stamps the VNF ownership metadata on an existing configmap
+func LabelResource(name string, namespace string, kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	patch, err := krd.OwnershipPatch(kubedata)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Label ConfigMap error")
+	}
+
+	_, err = kubeclient.CoreV1().ConfigMaps(namespace).Patch(name, types.MergePatchType, patch)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Label ConfigMap error")
+	}
+
+	return nil
+}
+
+// UpdateResource replaces an existing configmap with the one read from kubedata
+func UpdateResource(kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) (string, error) {
+	kubedata.RenderOnly = true
+	_, err := CreateResource(kubedata, kubeclient)
+	kubedata.RenderOnly = false
+	if err != nil {
+		return "", err
+	}
+
+	log.Println("Updating configmap: " + kubedata.ConfigMapData.Name)
+
+	current, err := kubeclient.CoreV1().ConfigMaps(kubedata.Namespace).Get(kubedata.ConfigMapData.Name, metaV1.GetOptions{})
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Get ConfigMap error")
+	}
+	kubedata.ConfigMapData.ResourceVersion = current.ResourceVersion
+
+	result, err := kubeclient.CoreV1().ConfigMaps(kubedata.Namespace).Update(kubedata.ConfigMapData)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Update ConfigMap error")
+	}
+
+	return result.GetObjectMeta().GetName(), nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"log"
+
+	"k8s.io/client-go/kubernetes"
+
+	pkgerrors "github.com/pkg/errors"
+
+	batchV1beta1 "k8s.io/api/batch/v1beta1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"k8-plugin-multicloud/krd"
+)
+
+func main() {}
+
+// CreateResource object in a specific Kubernetes CronJob
+func CreateResource(kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) (string, error) {
+	if kubedata.Namespace == "" {
+		kubedata.Namespace = "default"
+	}
+
+	log.Println("Reading cronjob YAML")
+	rawBytes, err := krd.ReadManifest(kubedata)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "CronJob YAML file read error")
+	}
+
+	log.Println("Decoding cronjob YAML")
+	decode := scheme.Codecs.UniversalDeserializer().Decode
+	obj, _, err := decode(rawBytes, nil, nil)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Deserialize cronjob error")
+	}
+
+	switch o := obj.(type) {
+	case *batchV1beta1.CronJob:
+		kubedata.CronJobData = o
+	default:
+		return "", pkgerrors.New(kubedata.YamlFilePath + " contains another resource different than CronJob")
+	}
+
+	kubedata.CronJobData.Namespace = kubedata.Namespace
+	kubedata.CronJobData.Name = kubedata.InternalVNFID + "-" + kubedata.CronJobData.Name
+	krd.AddOwnershipMetadata(&kubedata.CronJobData.ObjectMeta, kubedata)
+	krd.UpdatePodReferences(&kubedata.CronJobData.Spec.JobTemplate.Spec.Template.Spec, kubedata.RenamedResources)
+	krd.AddOwnershipMetadata(&kubedata.CronJobData.Spec.JobTemplate.ObjectMeta, kubedata)
+	krd.AddOwnershipMetadata(&kubedata.CronJobData.Spec.JobTemplate.Spec.Template.ObjectMeta, kubedata)
+
+	if kubedata.DryRun || kubedata.RenderOnly {
+		return krd.DryRunCreate(kubeclient.BatchV1beta1().RESTClient(), "cronjobs", kubedata.CronJobData, kubedata)
+	}
+
+	result, err := kubeclient.BatchV1beta1().CronJobs(kubedata.Namespace).Create(kubedata.CronJobData)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Create CronJob error")
+	}
+
+	return result.GetObjectMeta().GetName(), nil
+}
+
+// ListResources of existing cronjobs hosted in a specific Kubernetes namespace
+func ListResources(limit int64, namespace string, labelSelector string, kubeclient *kubernetes.Clientset) (*[]string, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	opts := metaV1.ListOptions{
+		Limit:         limit,
+		LabelSelector: labelSelector,
+	}
+	opts.APIVersion = "batch/v1beta1"
+	opts.Kind = "CronJob"
+
+	list, err := kubeclient.BatchV1beta1().CronJobs(namespace).List(opts)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Get CronJob list error")
+	}
+
+	result := make([]string, 0, limit)
+	if list != nil {
+		for _, cronJob := range list.Items {
+			result = append(result, cronJob.Name)
+		}
+	}
+
+	return &result, nil
+}
+
+// DeleteResource deletes an existing Kubernetes cronjob
+func DeleteResource(name string, namespace string, kubeclient *kubernetes.Clientset) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	log.Println("Deleting cronjob: " + name)
+
+	deletePolicy := metaV1.DeletePropagationForeground
+	err := kubeclient.BatchV1beta1().CronJobs(namespace).Delete(name, &metaV1.DeleteOptions{
+		PropagationPolicy: &deletePolicy,
+	})
+	if err != nil {
+		return pkgerrors.Wrap(err, "Delete CronJob error")
+	}
+
+	return nil
+}
+
+// GetResource existing cronjob hosted in a specific Kubernetes namespace
+func GetResource(name string, namespace string, kubeclient *kubernetes.Clientset) (string, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	cronJob, err := kubeclient.BatchV1beta1().CronJobs(namespace).Get(name, metaV1.GetOptions{})
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", pkgerrors.Wrap(err, "Get CronJob error")
+	}
+
+	return cronJob.Name, nil
+}
+
+// LabelResource stamps the VNF ownership metadata on an existing cronjob
+func LabelResource(name string, namespace string, kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	patch, err := krd.OwnershipPatch(kubedata)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Label CronJob error")
+	}
+
+	_, err = kubeclient.BatchV1beta1().CronJobs(namespace).Patch(name, types.MergePatchType, patch)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Label CronJob error")
+	}
+
+	return nil
+}
+
+// UpdateResource replaces an existing cronjob with the one read from kubedata
+func UpdateResource(kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) (string, error) {
+	kubedata.RenderOnly = true
+	_, err := CreateResource(kubedata, kubeclient)
+	kubedata.RenderOnly = false
+	if err != nil {
+		return "", err
+	}
+
+	log.Println("Updating cronjob: " + kubedata.CronJobData.Name)
+
+	current, err := kubeclient.BatchV1beta1().CronJobs(kubedata.Namespace).Get(kubedata.CronJobData.Name, metaV1.GetOptions{})
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Get CronJob error")
+	}
+	kubedata.CronJobData.ResourceVersion = current.ResourceVersion
+
+	result, err := kubeclient.BatchV1beta1().CronJobs(kubedata.Namespace).Update(kubedata.CronJobData)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Update CronJob error")
+	}
+
+	return result.GetObjectMeta().GetName(), nil
+}
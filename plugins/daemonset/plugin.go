@@ -0,0 +1,202 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+
+	pkgerrors "github.com/pkg/errors"
+
+	appsV1 "k8s.io/api/apps/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"k8-plugin-multicloud/krd"
+)
+
+func main() {}
+
+// CreateResource object in a specific Kubernetes DaemonSet
+func CreateResource(kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) (string, error) {
+	if kubedata.Namespace == "" {
+		kubedata.Namespace = "default"
+	}
+
+	log.Println("Reading daemonset YAML")
+	rawBytes, err := krd.ReadManifest(kubedata)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "DaemonSet YAML file read error")
+	}
+
+	log.Println("Decoding daemonset YAML")
+	decode := scheme.Codecs.UniversalDeserializer().Decode
+	obj, _, err := decode(rawBytes, nil, nil)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Deserialize daemonset error")
+	}
+
+	switch o := obj.(type) {
+	case *appsV1.DaemonSet:
+		kubedata.DaemonSetData = o
+	default:
+		return "", pkgerrors.New(kubedata.YamlFilePath + " contains another resource different than DaemonSet")
+	}
+
+	kubedata.DaemonSetData.Namespace = kubedata.Namespace
+	kubedata.DaemonSetData.Name = kubedata.InternalVNFID + "-" + kubedata.DaemonSetData.Name
+	krd.AddOwnershipMetadata(&kubedata.DaemonSetData.ObjectMeta, kubedata)
+	krd.UpdatePodReferences(&kubedata.DaemonSetData.Spec.Template.Spec, kubedata.RenamedResources)
+	krd.AddOwnershipMetadata(&kubedata.DaemonSetData.Spec.Template.ObjectMeta, kubedata)
+
+	if kubedata.DryRun || kubedata.RenderOnly {
+		return krd.DryRunCreate(kubeclient.AppsV1().RESTClient(), "daemonsets", kubedata.DaemonSetData, kubedata)
+	}
+
+	result, err := kubeclient.AppsV1().DaemonSets(kubedata.Namespace).Create(kubedata.DaemonSetData)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Create DaemonSet error")
+	}
+
+	return result.GetObjectMeta().GetName(), nil
+}
+
+// ListResources of existing daemonsets hosted in a specific Kubernetes namespace
+func ListResources(limit int64, namespace string, labelSelector string, kubeclient *kubernetes.Clientset) (*[]string, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	opts := metaV1.ListOptions{
+		Limit:         limit,
+		LabelSelector: labelSelector,
+	}
+	opts.APIVersion = "apps/v1"
+	opts.Kind = "DaemonSet"
+
+	list, err := kubeclient.AppsV1().DaemonSets(namespace).List(opts)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Get DaemonSet list error")
+	}
+
+	result := make([]string, 0, limit)
+	if list != nil {
+		for _, daemonSet := range list.Items {
+			result = append(result, daemonSet.Name)
+		}
+	}
+
+	return &result, nil
+}
+
+// DeleteResource deletes an existing Kubernetes daemonset
+func DeleteResource(name string, namespace string, kubeclient *kubernetes.Clientset) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	log.Println("Deleting daemonset: " + name)
+
+	deletePolicy := metaV1.DeletePropagationForeground
+	err := kubeclient.AppsV1().DaemonSets(namespace).Delete(name, &metaV1.DeleteOptions{
+		PropagationPolicy: &deletePolicy,
+	})
+	if err != nil {
+		return pkgerrors.Wrap(err, "Delete DaemonSet error")
+	}
+
+	return nil
+}
+
+// GetResource existing daemonset hosted in a specific Kubernetes namespace
+func GetResource(name string, namespace string, kubeclient *kubernetes.Clientset) (string, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	daemonSet, err := kubeclient.AppsV1().DaemonSets(namespace).Get(name, metaV1.GetOptions{})
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", pkgerrors.Wrap(err, "Get DaemonSet error")
+	}
+
+	return daemonSet.Name, nil
+}
+
+// LabelResource stamps the VNF ownership metadata on an existing daemonset
+func LabelResource(name string, namespace string, kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	patch, err := krd.OwnershipPatch(kubedata)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Label DaemonSet error")
+	}
+
+	_, err = kubeclient.AppsV1().DaemonSets(namespace).Patch(name, types.MergePatchType, patch)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Label DaemonSet error")
+	}
+
+	return nil
+}
+
+// UpdateResource replaces an existing daemonset with the one read from kubedata.
+// Changes to the pod template start a rolling update.
+func UpdateResource(kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) (string, error) {
+	kubedata.RenderOnly = true
+	_, err := CreateResource(kubedata, kubeclient)
+	kubedata.RenderOnly = false
+	if err != nil {
+		return "", err
+	}
+
+	log.Println("Updating daemonset: " + kubedata.DaemonSetData.Name)
+
+	current, err := kubeclient.AppsV1().DaemonSets(kubedata.Namespace).Get(kubedata.DaemonSetData.Name, metaV1.GetOptions{})
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Get DaemonSet error")
+	}
+	kubedata.DaemonSetData.ResourceVersion = current.ResourceVersion
+
+	result, err := kubeclient.AppsV1().DaemonSets(kubedata.Namespace).Update(kubedata.DaemonSetData)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Update DaemonSet error")
+	}
+
+	return result.GetObjectMeta().GetName(), nil
+}
+
+// WaitForResource blocks until the rollout of a daemonset completes or the
+// timeout expires
+func WaitForResource(name string, namespace string, timeout time.Duration, kubeclient *kubernetes.Clientset) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	log.Println("Waiting for daemonset: " + name)
+
+	err := wait.PollImmediate(2*time.Second, timeout, func() (bool, error) {
+		daemonSet, err := kubeclient.AppsV1().DaemonSets(namespace).Get(name, metaV1.GetOptions{})
+		if err != nil {
+			return false, pkgerrors.Wrap(err, "Get DaemonSet error")
+		}
+
+		if daemonSet.Status.ObservedGeneration < daemonSet.Generation {
+			return false, nil
+		}
+
+		return daemonSet.Status.UpdatedNumberScheduled == daemonSet.Status.DesiredNumberScheduled &&
+			daemonSet.Status.NumberAvailable == daemonSet.Status.DesiredNumberScheduled, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return pkgerrors.New("DaemonSet " + name + " did not roll out within " + timeout.String())
+	}
+	return err
+}
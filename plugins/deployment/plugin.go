@@ -1,16 +1,19 @@
 package main
 
 import (
-	"io/ioutil"
+	"fmt"
 	"log"
-	"os"
+	"time"
 
 	"k8s.io/client-go/kubernetes"
 
 	pkgerrors "github.com/pkg/errors"
 
 	appsV1 "k8s.io/api/apps/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes/scheme"
 
 	"k8-plugin-multicloud/krd"
@@ -24,12 +27,8 @@ func CreateResource(kubedata *krd.GenericKubeResourceData, kubeclient *kubernete
 		kubedata.Namespace = "default"
 	}
 
-	if _, err := os.Stat(kubedata.YamlFilePath); err != nil {
-		return "", pkgerrors.New("File " + kubedata.YamlFilePath + " not found")
-	}
-
 	log.Println("Reading deployment YAML")
-	rawBytes, err := ioutil.ReadFile(kubedata.YamlFilePath)
+	rawBytes, err := krd.ReadManifest(kubedata)
 	if err != nil {
 		return "", pkgerrors.Wrap(err, "Deployment YAML file read error")
 	}
@@ -50,6 +49,13 @@ func CreateResource(kubedata *krd.GenericKubeResourceData, kubeclient *kubernete
 
 	kubedata.DeploymentData.Namespace = kubedata.Namespace
 	kubedata.DeploymentData.Name = kubedata.InternalVNFID + "-" + kubedata.DeploymentData.Name
+	krd.AddOwnershipMetadata(&kubedata.DeploymentData.ObjectMeta, kubedata)
+	krd.UpdatePodReferences(&kubedata.DeploymentData.Spec.Template.Spec, kubedata.RenamedResources)
+	krd.AddOwnershipMetadata(&kubedata.DeploymentData.Spec.Template.ObjectMeta, kubedata)
+
+	if kubedata.DryRun || kubedata.RenderOnly {
+		return krd.DryRunCreate(kubeclient.AppsV1().RESTClient(), "deployments", kubedata.DeploymentData, kubedata)
+	}
 
 	result, err := kubeclient.AppsV1().Deployments(kubedata.Namespace).Create(kubedata.DeploymentData)
 	if err != nil {
@@ -60,13 +66,14 @@ func CreateResource(kubedata *krd.GenericKubeResourceData, kubeclient *kubernete
 }
 
 // ListResources of existing deployments hosted in a specific Kubernetes Deployment
-func ListResources(limit int64, namespace string, kubeclient *kubernetes.Clientset) (*[]string, error) {
+func ListResources(limit int64, namespace string, labelSelector string, kubeclient *kubernetes.Clientset) (*[]string, error) {
 	if namespace == "" {
 		namespace = "default"
 	}
 
 	opts := metaV1.ListOptions{
-		Limit: limit,
+		Limit:         limit,
+		LabelSelector: labelSelector,
 	}
 	opts.APIVersion = "apps/v1"
 	opts.Kind = "Deployment"
@@ -112,21 +119,141 @@ func GetResource(name string, namespace string, kubeclient *kubernetes.Clientset
 		namespace = "default"
 	}
 
-	opts := metaV1.ListOptions{
-		Limit: 10,
+	deployment, err := kubeclient.AppsV1().Deployments(namespace).Get(name, metaV1.GetOptions{})
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", pkgerrors.Wrap(err, "Get Deployment error")
 	}
-	opts.APIVersion = "apps/v1"
-	opts.Kind = "Deployment"
 
-	list, err := kubeclient.AppsV1().Deployments(namespace).List(opts)
+	return deployment.Name, nil
+}
+
+// LabelResource stamps the VNF ownership metadata on an existing deployment
+func LabelResource(name string, namespace string, kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	patch, err := krd.OwnershipPatch(kubedata)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Label Deployment error")
+	}
+
+	_, err = kubeclient.AppsV1().Deployments(namespace).Patch(name, types.MergePatchType, patch)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Label Deployment error")
+	}
+
+	return nil
+}
+
+// ScaleResource sets the number of replicas of an existing deployment
+func ScaleResource(name string, namespace string, replicas int32, kubeclient *kubernetes.Clientset) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	log.Println("Scaling deployment: " + name)
+
+	patch := []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas))
+	_, err := kubeclient.AppsV1().Deployments(namespace).Patch(name, types.MergePatchType, patch)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Scale Deployment error")
+	}
+
+	return nil
+}
+
+// GetReplicas returns the number of replicas requested for an existing deployment
+func GetReplicas(name string, namespace string, kubeclient *kubernetes.Clientset) (int32, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	deployment, err := kubeclient.AppsV1().Deployments(namespace).Get(name, metaV1.GetOptions{})
+	if err != nil {
+		return 0, pkgerrors.Wrap(err, "Get Deployment error")
+	}
+
+	if deployment.Spec.Replicas == nil {
+		return 1, nil
+	}
+
+	return *deployment.Spec.Replicas, nil
+}
+
+// UpdateResource replaces an existing deployment with the one read from kubedata,
+// keeping its current number of replicas. Changes to the pod template start a
+// rolling update.
+func UpdateResource(kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) (string, error) {
+	kubedata.RenderOnly = true
+	_, err := CreateResource(kubedata, kubeclient)
+	kubedata.RenderOnly = false
+	if err != nil {
+		return "", err
+	}
+
+	log.Println("Updating deployment: " + kubedata.DeploymentData.Name)
+
+	current, err := kubeclient.AppsV1().Deployments(kubedata.Namespace).Get(kubedata.DeploymentData.Name, metaV1.GetOptions{})
 	if err != nil {
 		return "", pkgerrors.Wrap(err, "Get Deployment error")
 	}
+	kubedata.DeploymentData.ResourceVersion = current.ResourceVersion
+	kubedata.DeploymentData.Spec.Replicas = current.Spec.Replicas
 
-	for _, deployment := range list.Items {
-		if deployment.Name == name {
-			return name, nil
+	result, err := kubeclient.AppsV1().Deployments(kubedata.Namespace).Update(kubedata.DeploymentData)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Update Deployment error")
+	}
+
+	return result.GetObjectMeta().GetName(), nil
+}
+
+// WaitForResource blocks until the rollout of a deployment completes or the
+// timeout expires
+func WaitForResource(name string, namespace string, timeout time.Duration, kubeclient *kubernetes.Clientset) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	log.Println("Waiting for deployment: " + name)
+
+	var failure error
+	err := wait.PollImmediate(2*time.Second, timeout, func() (bool, error) {
+		deployment, err := kubeclient.AppsV1().Deployments(namespace).Get(name, metaV1.GetOptions{})
+		if err != nil {
+			return false, pkgerrors.Wrap(err, "Get Deployment error")
+		}
+
+		if deployment.Status.ObservedGeneration < deployment.Generation {
+			return false, nil
 		}
+
+		for _, condition := range deployment.Status.Conditions {
+			if condition.Type == appsV1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
+				failure = pkgerrors.New("Deployment " + name + " failed: " + condition.Message)
+				return true, nil
+			}
+		}
+
+		replicas := int32(1)
+		if deployment.Spec.Replicas != nil {
+			replicas = *deployment.Spec.Replicas
+		}
+
+		return deployment.Status.UpdatedReplicas == replicas &&
+			deployment.Status.Replicas == replicas &&
+			deployment.Status.AvailableReplicas == replicas, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return pkgerrors.New("Deployment " + name + " did not roll out within " + timeout.String())
+	}
+	if err != nil {
+		return err
 	}
-	return "", nil
+
+	return failure
 }
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"log"
+
+	"k8s.io/client-go/kubernetes"
+
+	pkgerrors "github.com/pkg/errors"
+
+	extensionsV1beta1 "k8s.io/api/extensions/v1beta1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"k8-plugin-multicloud/krd"
+)
+
+func main() {}
+
+// CreateResource object in a specific Kubernetes Ingress
+func CreateResource(kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) (string, error) {
+	if kubedata.Namespace == "" {
+		kubedata.Namespace = "default"
+	}
+
+	log.Println("Reading ingress YAML")
+	rawBytes, err := krd.ReadManifest(kubedata)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Ingress YAML file read error")
+	}
+
+	log.Println("Decoding ingress YAML")
+	decode := scheme.Codecs.UniversalDeserializer().Decode
+	obj, _, err := decode(rawBytes, nil, nil)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Deserialize ingress error")
+	}
+
+	switch o := obj.(type) {
+	case *extensionsV1beta1.Ingress:
+		kubedata.IngressData = o
+	default:
+		return "", pkgerrors.New(kubedata.YamlFilePath + " contains another resource different than Ingress")
+	}
+
+	kubedata.IngressData.Namespace = kubedata.Namespace
+	kubedata.IngressData.Name = kubedata.InternalVNFID + "-" + kubedata.IngressData.Name
+	krd.AddOwnershipMetadata(&kubedata.IngressData.ObjectMeta, kubedata)
+	updateBackendReferences(kubedata.IngressData, kubedata.RenamedResources)
+
+	if kubedata.DryRun || kubedata.RenderOnly {
+		return krd.DryRunCreate(kubeclient.ExtensionsV1beta1().RESTClient(), "ingresses", kubedata.IngressData, kubedata)
+	}
+
+	result, err := kubeclient.ExtensionsV1beta1().Ingresses(kubedata.Namespace).Create(kubedata.IngressData)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Create Ingress error")
+	}
+
+	return result.GetObjectMeta().GetName(), nil
+}
+
+// ListResources of existing ingresses hosted in a specific Kubernetes namespace
+func ListResources(limit int64, namespace string, labelSelector string, kubeclient *kubernetes.Clientset) (*[]string, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	opts := metaV1.ListOptions{
+		Limit:         limit,
+		LabelSelector: labelSelector,
+	}
+	opts.APIVersion = "extensions/v1beta1"
+	opts.Kind = "Ingress"
+
+	list, err := kubeclient.ExtensionsV1beta1().Ingresses(namespace).List(opts)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Get Ingress list error")
+	}
+
+	result := make([]string, 0, limit)
+	if list != nil {
+		for _, ingress := range list.Items {
+			result = append(result, ingress.Name)
+		}
+	}
+
+	return &result, nil
+}
+
+// DeleteResource deletes an existing Kubernetes ingress
+func DeleteResource(name string, namespace string, kubeclient *kubernetes.Clientset) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	log.Println("Deleting ingress: " + name)
+
+	deletePolicy := metaV1.DeletePropagationForeground
+	err := kubeclient.ExtensionsV1beta1().Ingresses(namespace).Delete(name, &metaV1.DeleteOptions{
+		PropagationPolicy: &deletePolicy,
+	})
+	if err != nil {
+		return pkgerrors.Wrap(err, "Delete Ingress error")
+	}
+
+	return nil
+}
+
+// GetResource existing ingress hosted in a specific Kubernetes namespace
+func GetResource(name string, namespace string, kubeclient *kubernetes.Clientset) (string, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	ingress, err := kubeclient.ExtensionsV1beta1().Ingresses(namespace).Get(name, metaV1.GetOptions{})
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", pkgerrors.Wrap(err, "Get Ingress error")
+	}
+
+	return ingress.Name, nil
+}
+
+// updateBackendReferences replaces the Service and Secret names referenced by
+// an ingress with the names created for the VNF
+func updateBackendReferences(ingress *extensionsV1beta1.Ingress, renamed map[string]map[string]string) {
+	lookupName := func(resourceName string, name string) string {
+		if newName, ok := renamed[resourceName][name]; ok {
+			return newName
+		}
+		return name
+	}
+
+	if ingress.Spec.Backend != nil {
+		ingress.Spec.Backend.ServiceName = lookupName("service", ingress.Spec.Backend.ServiceName)
+	}
+
+	for i := range ingress.Spec.Rules {
+		if ingress.Spec.Rules[i].HTTP == nil {
+			continue
+		}
+		paths := ingress.Spec.Rules[i].HTTP.Paths
+		for j := range paths {
+			paths[j].Backend.ServiceName = lookupName("service", paths[j].Backend.ServiceName)
+		}
+	}
+
+	for i := range ingress.Spec.TLS {
+		ingress.Spec.TLS[i].SecretName = lookupName("secret", ingress.Spec.TLS[i].SecretName)
+	}
+}
+
+// LabelResource stamps the VNF ownership metadata on an existing ingress
+func LabelResource(name string, namespace string, kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	patch, err := krd.OwnershipPatch(kubedata)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Label Ingress error")
+	}
+
+	_, err = kubeclient.ExtensionsV1beta1().Ingresses(namespace).Patch(name, types.MergePatchType, patch)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Label Ingress error")
+	}
+
+	return nil
+}
+
+// UpdateResource replaces an existing ingress with the one read from kubedata
+func UpdateResource(kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) (string, error) {
+	kubedata.RenderOnly = true
+	_, err := CreateResource(kubedata, kubeclient)
+	kubedata.RenderOnly = false
+	if err != nil {
+		return "", err
+	}
+
+	log.Println("Updating ingress: " + kubedata.IngressData.Name)
+
+	current, err := kubeclient.ExtensionsV1beta1().Ingresses(kubedata.Namespace).Get(kubedata.IngressData.Name, metaV1.GetOptions{})
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Get Ingress error")
+	}
+	kubedata.IngressData.ResourceVersion = current.ResourceVersion
+
+	result, err := kubeclient.ExtensionsV1beta1().Ingresses(kubedata.Namespace).Update(kubedata.IngressData)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Update Ingress error")
+	}
+
+	return result.GetObjectMeta().GetName(), nil
+}
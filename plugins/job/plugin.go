@@ -0,0 +1,190 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+
+	pkgerrors "github.com/pkg/errors"
+
+	batchV1 "k8s.io/api/batch/v1"
+	coreV1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"k8-plugin-multicloud/krd"
+)
+
+func main() {}
+
+// CreateResource object in a specific Kubernetes Job
+func CreateResource(kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) (string, error) {
+	if kubedata.Namespace == "" {
+		kubedata.Namespace = "default"
+	}
+
+	log.Println("Reading job YAML")
+	rawBytes, err := krd.ReadManifest(kubedata)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Job YAML file read error")
+	}
+
+	log.Println("Decoding job YAML")
+	decode := scheme.Codecs.UniversalDeserializer().Decode
+	obj, _, err := decode(rawBytes, nil, nil)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Deserialize job error")
+	}
+
+	switch o := obj.(type) {
+	case *batchV1.Job:
+		kubedata.JobData = o
+	default:
+		return "", pkgerrors.New(kubedata.YamlFilePath + " contains another resource different than Job")
+	}
+
+	kubedata.JobData.Namespace = kubedata.Namespace
+	kubedata.JobData.Name = kubedata.InternalVNFID + "-" + kubedata.JobData.Name
+	krd.AddOwnershipMetadata(&kubedata.JobData.ObjectMeta, kubedata)
+	krd.UpdatePodReferences(&kubedata.JobData.Spec.Template.Spec, kubedata.RenamedResources)
+	krd.AddOwnershipMetadata(&kubedata.JobData.Spec.Template.ObjectMeta, kubedata)
+
+	if kubedata.DryRun || kubedata.RenderOnly {
+		return krd.DryRunCreate(kubeclient.BatchV1().RESTClient(), "jobs", kubedata.JobData, kubedata)
+	}
+
+	result, err := kubeclient.BatchV1().Jobs(kubedata.Namespace).Create(kubedata.JobData)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Create Job error")
+	}
+
+	return result.GetObjectMeta().GetName(), nil
+}
+
+// ListResources of existing jobs hosted in a specific Kubernetes namespace
+func ListResources(limit int64, namespace string, labelSelector string, kubeclient *kubernetes.Clientset) (*[]string, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	opts := metaV1.ListOptions{
+		Limit:         limit,
+		LabelSelector: labelSelector,
+	}
+	opts.APIVersion = "batch/v1"
+	opts.Kind = "Job"
+
+	list, err := kubeclient.BatchV1().Jobs(namespace).List(opts)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Get Job list error")
+	}
+
+	result := make([]string, 0, limit)
+	if list != nil {
+		for _, job := range list.Items {
+			result = append(result, job.Name)
+		}
+	}
+
+	return &result, nil
+}
+
+// DeleteResource deletes an existing Kubernetes job
+func DeleteResource(name string, namespace string, kubeclient *kubernetes.Clientset) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	log.Println("Deleting job: " + name)
+
+	deletePolicy := metaV1.DeletePropagationForeground
+	err := kubeclient.BatchV1().Jobs(namespace).Delete(name, &metaV1.DeleteOptions{
+		PropagationPolicy: &deletePolicy,
+	})
+	if err != nil {
+		return pkgerrors.Wrap(err, "Delete Job error")
+	}
+
+	return nil
+}
+
+// GetResource existing job hosted in a specific Kubernetes namespace
+func GetResource(name string, namespace string, kubeclient *kubernetes.Clientset) (string, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	job, err := kubeclient.BatchV1().Jobs(namespace).Get(name, metaV1.GetOptions{})
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", pkgerrors.Wrap(err, "Get Job error")
+	}
+
+	return job.Name, nil
+}
+
+// WaitForResource blocks until an existing job completes, returning an error
+// if the job fails or doesn't complete before the timeout expires
+func WaitForResource(name string, namespace string, timeout time.Duration, kubeclient *kubernetes.Clientset) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	log.Println("Waiting for job: " + name)
+
+	var failure error
+	err := wait.PollImmediate(2*time.Second, timeout, func() (bool, error) {
+		job, err := kubeclient.BatchV1().Jobs(namespace).Get(name, metaV1.GetOptions{})
+		if err != nil {
+			return false, pkgerrors.Wrap(err, "Get Job error")
+		}
+
+		for _, condition := range job.Status.Conditions {
+			if condition.Status != coreV1.ConditionTrue {
+				continue
+			}
+			switch condition.Type {
+			case batchV1.JobComplete:
+				return true, nil
+			case batchV1.JobFailed:
+				failure = pkgerrors.New("Job " + name + " failed: " + condition.Reason + " " + condition.Message)
+				return true, nil
+			}
+		}
+
+		return false, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return pkgerrors.New("Job " + name + " did not complete within " + timeout.String())
+	}
+	if err != nil {
+		return err
+	}
+
+	return failure
+}
+
+// LabelResource stamps the VNF ownership metadata on an existing job
+func LabelResource(name string, namespace string, kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	patch, err := krd.OwnershipPatch(kubedata)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Label Job error")
+	}
+
+	_, err = kubeclient.BatchV1().Jobs(namespace).Patch(name, types.MergePatchType, patch)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Label Job error")
+	}
+
+	return nil
+}
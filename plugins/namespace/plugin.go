@@ -4,17 +4,24 @@ import (
 	pkgerrors "github.com/pkg/errors"
 
 	coreV1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+
+	"k8-plugin-multicloud/krd"
 )
 
 func main() {}
 
-// CreateResource is used to create a new Namespace
+// CreateResource is used to create a new Namespace, labelled as managed by
+// the plugin
 func CreateResource(namespace string, client *kubernetes.Clientset) error {
 	namespaceStruct := &coreV1.Namespace{
 		ObjectMeta: metaV1.ObjectMeta{
 			Name: namespace,
+			Labels: map[string]string{
+				krd.ManagedByLabel: krd.ManagedByValue,
+			},
 		},
 	}
 	_, err := client.CoreV1().Namespaces().Create(namespaceStruct)
@@ -28,6 +35,9 @@ func CreateResource(namespace string, client *kubernetes.Clientset) error {
 func GetResource(namespace string, client *kubernetes.Clientset) (bool, error) {
 	ns, err := client.CoreV1().Namespaces().Get(namespace, metaV1.GetOptions{})
 	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return false, nil
+		}
 		return false, pkgerrors.Wrap(err, "Get Namespace list error")
 	}
 	return ns != nil, nil
@@ -46,3 +56,72 @@ func DeleteResource(namespace string, client *kubernetes.Clientset) error {
 	}
 	return nil
 }
+
+// ListResources is used to list the namespaces of a Kubernetes cluster
+func ListResources(limit int64, client *kubernetes.Clientset) (*[]string, error) {
+	opts := metaV1.ListOptions{
+		Limit: limit,
+	}
+
+	list, err := client.CoreV1().Namespaces().List(opts)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Get Namespace list error")
+	}
+
+	result := make([]string, 0, limit)
+	if list != nil {
+		for _, namespace := range list.Items {
+			result = append(result, namespace.Name)
+		}
+	}
+
+	return &result, nil
+}
+
+// ApplyQuota creates or updates the ResourceQuota and LimitRange of a quota
+// profile in a namespace
+func ApplyQuota(namespace string, profile *krd.QuotaProfile, client *kubernetes.Clientset) error {
+	labels := map[string]string{
+		krd.ManagedByLabel: krd.ManagedByValue,
+	}
+
+	if profile.ResourceQuota != nil {
+		quota := &coreV1.ResourceQuota{
+			ObjectMeta: metaV1.ObjectMeta{
+				Name:      krd.QuotaName,
+				Namespace: namespace,
+				Labels:    labels,
+			},
+			Spec: *profile.ResourceQuota,
+		}
+
+		_, err := client.CoreV1().ResourceQuotas(namespace).Create(quota)
+		if k8sErrors.IsAlreadyExists(err) {
+			_, err = client.CoreV1().ResourceQuotas(namespace).Update(quota)
+		}
+		if err != nil {
+			return pkgerrors.Wrap(err, "Apply ResourceQuota error")
+		}
+	}
+
+	if profile.LimitRange != nil {
+		limitRange := &coreV1.LimitRange{
+			ObjectMeta: metaV1.ObjectMeta{
+				Name:      krd.LimitRangeName,
+				Namespace: namespace,
+				Labels:    labels,
+			},
+			Spec: *profile.LimitRange,
+		}
+
+		_, err := client.CoreV1().LimitRanges(namespace).Create(limitRange)
+		if k8sErrors.IsAlreadyExists(err) {
+			_, err = client.CoreV1().LimitRanges(namespace).Update(limitRange)
+		}
+		if err != nil {
+			return pkgerrors.Wrap(err, "Apply LimitRange error")
+		}
+	}
+
+	return nil
+}
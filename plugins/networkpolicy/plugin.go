@@ -0,0 +1,202 @@
+package main
+
+import (
+	"log"
+
+	"k8s.io/client-go/kubernetes"
+
+	pkgerrors "github.com/pkg/errors"
+
+	networkingV1 "k8s.io/api/networking/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"k8-plugin-multicloud/krd"
+)
+
+func main() {}
+
+// CreateResource object in a specific Kubernetes NetworkPolicy
+func CreateResource(kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) (string, error) {
+	if kubedata.Namespace == "" {
+		kubedata.Namespace = "default"
+	}
+
+	log.Println("Reading networkpolicy YAML")
+	rawBytes, err := krd.ReadManifest(kubedata)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "NetworkPolicy YAML file read error")
+	}
+
+	log.Println("Decoding networkpolicy YAML")
+	decode := scheme.Codecs.UniversalDeserializer().Decode
+	obj, _, err := decode(rawBytes, nil, nil)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Deserialize networkpolicy error")
+	}
+
+	switch o := obj.(type) {
+	case *networkingV1.NetworkPolicy:
+		kubedata.NetworkPolicyData = o
+	default:
+		return "", pkgerrors.New(kubedata.YamlFilePath + " contains another resource different than NetworkPolicy")
+	}
+
+	kubedata.NetworkPolicyData.Namespace = kubedata.Namespace
+	kubedata.NetworkPolicyData.Name = kubedata.InternalVNFID + "-" + kubedata.NetworkPolicyData.Name
+	krd.AddOwnershipMetadata(&kubedata.NetworkPolicyData.ObjectMeta, kubedata)
+	selectVNFPods(kubedata.NetworkPolicyData, kubedata.ExternalVNFID)
+
+	if kubedata.DryRun || kubedata.RenderOnly {
+		return krd.DryRunCreate(kubeclient.NetworkingV1().RESTClient(), "networkpolicies", kubedata.NetworkPolicyData, kubedata)
+	}
+
+	result, err := kubeclient.NetworkingV1().NetworkPolicies(kubedata.Namespace).Create(kubedata.NetworkPolicyData)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Create NetworkPolicy error")
+	}
+
+	return result.GetObjectMeta().GetName(), nil
+}
+
+// ListResources of existing network policies hosted in a specific Kubernetes namespace
+func ListResources(limit int64, namespace string, labelSelector string, kubeclient *kubernetes.Clientset) (*[]string, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	opts := metaV1.ListOptions{
+		Limit:         limit,
+		LabelSelector: labelSelector,
+	}
+	opts.APIVersion = "networking.k8s.io/v1"
+	opts.Kind = "NetworkPolicy"
+
+	list, err := kubeclient.NetworkingV1().NetworkPolicies(namespace).List(opts)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Get NetworkPolicy list error")
+	}
+
+	result := make([]string, 0, limit)
+	if list != nil {
+		for _, networkPolicy := range list.Items {
+			result = append(result, networkPolicy.Name)
+		}
+	}
+
+	return &result, nil
+}
+
+// DeleteResource deletes an existing Kubernetes network policy
+func DeleteResource(name string, namespace string, kubeclient *kubernetes.Clientset) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	log.Println("Deleting networkpolicy: " + name)
+
+	deletePolicy := metaV1.DeletePropagationForeground
+	err := kubeclient.NetworkingV1().NetworkPolicies(namespace).Delete(name, &metaV1.DeleteOptions{
+		PropagationPolicy: &deletePolicy,
+	})
+	if err != nil {
+		return pkgerrors.Wrap(err, "Delete NetworkPolicy error")
+	}
+
+	return nil
+}
+
+// GetResource existing network policy hosted in a specific Kubernetes namespace
+func GetResource(name string, namespace string, kubeclient *kubernetes.Clientset) (string, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	networkPolicy, err := kubeclient.NetworkingV1().NetworkPolicies(namespace).Get(name, metaV1.GetOptions{})
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", pkgerrors.Wrap(err, "Get NetworkPolicy error")
+	}
+
+	return networkPolicy.Name, nil
+}
+
+// selfVNFID is the VNF ID label value of peers selecting the VNF's own pods
+const selfVNFID = "self"
+
+// selectVNFPods restricts a network policy to the pods of the VNF. Peers that
+// select pods by `k8plugin.onap.org/vnf-id: self` are updated with the ID of
+// the VNF, so they match the VNF's own pods. Peers naming another VNF ID are
+// left alone.
+func selectVNFPods(networkPolicy *networkingV1.NetworkPolicy, vnfID string) {
+	if networkPolicy.Spec.PodSelector.MatchLabels == nil {
+		networkPolicy.Spec.PodSelector.MatchLabels = make(map[string]string)
+	}
+	networkPolicy.Spec.PodSelector.MatchLabels[krd.VNFIDLabel] = vnfID
+
+	updatePeers := func(peers []networkingV1.NetworkPolicyPeer) {
+		for _, peer := range peers {
+			if peer.PodSelector == nil {
+				continue
+			}
+			if peer.PodSelector.MatchLabels[krd.VNFIDLabel] == selfVNFID {
+				peer.PodSelector.MatchLabels[krd.VNFIDLabel] = vnfID
+			}
+		}
+	}
+
+	for _, rule := range networkPolicy.Spec.Ingress {
+		updatePeers(rule.From)
+	}
+	for _, rule := range networkPolicy.Spec.Egress {
+		updatePeers(rule.To)
+	}
+}
+
+// LabelResource stamps the VNF ownership metadata on an existing network policy
+func LabelResource(name string, namespace string, kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	patch, err := krd.OwnershipPatch(kubedata)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Label NetworkPolicy error")
+	}
+
+	_, err = kubeclient.NetworkingV1().NetworkPolicies(namespace).Patch(name, types.MergePatchType, patch)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Label NetworkPolicy error")
+	}
+
+	return nil
+}
+
+// UpdateResource replaces an existing networkpolicy with the one read from kubedata
+func UpdateResource(kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) (string, error) {
+	kubedata.RenderOnly = true
+	_, err := CreateResource(kubedata, kubeclient)
+	kubedata.RenderOnly = false
+	if err != nil {
+		return "", err
+	}
+
+	log.Println("Updating networkpolicy: " + kubedata.NetworkPolicyData.Name)
+
+	current, err := kubeclient.NetworkingV1().NetworkPolicies(kubedata.Namespace).Get(kubedata.NetworkPolicyData.Name, metaV1.GetOptions{})
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Get NetworkPolicy error")
+	}
+	kubedata.NetworkPolicyData.ResourceVersion = current.ResourceVersion
+
+	result, err := kubeclient.NetworkingV1().NetworkPolicies(kubedata.Namespace).Update(kubedata.NetworkPolicyData)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Update NetworkPolicy error")
+	}
+
+	return result.GetObjectMeta().GetName(), nil
+}
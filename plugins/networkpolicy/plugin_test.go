@@ -0,0 +1,66 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	networkingV1 "k8s.io/api/networking/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8-plugin-multicloud/krd"
+)
+
+func TestSelectVNFPods(t *testing.T) {
+	peer := func(vnfID string) networkingV1.NetworkPolicyPeer {
+		return networkingV1.NetworkPolicyPeer{
+			PodSelector: &metaV1.LabelSelector{
+				MatchLabels: map[string]string{krd.VNFIDLabel: vnfID},
+			},
+		}
+	}
+
+	t.Run("Successfully select the pods of the VNF", func(t *testing.T) {
+		networkPolicy := &networkingV1.NetworkPolicy{
+			Spec: networkingV1.NetworkPolicySpec{
+				Ingress: []networkingV1.NetworkPolicyIngressRule{
+					{From: []networkingV1.NetworkPolicyPeer{peer("self"), peer("othervnf")}},
+				},
+				Egress: []networkingV1.NetworkPolicyEgressRule{
+					{To: []networkingV1.NetworkPolicyPeer{peer("self"), peer("othervnf")}},
+				},
+			},
+		}
+
+		selectVNFPods(networkPolicy, "vnfid")
+
+		if networkPolicy.Spec.PodSelector.MatchLabels[krd.VNFIDLabel] != "vnfid" {
+			t.Fatalf("TestSelectVNFPods returned an unexpected pod selector (%v)",
+				networkPolicy.Spec.PodSelector.MatchLabels)
+		}
+
+		peers := [][]networkingV1.NetworkPolicyPeer{
+			networkPolicy.Spec.Ingress[0].From,
+			networkPolicy.Spec.Egress[0].To,
+		}
+		for _, rulePeers := range peers {
+			if got := rulePeers[0].PodSelector.MatchLabels[krd.VNFIDLabel]; got != "vnfid" {
+				t.Fatalf("TestSelectVNFPods didn't update the self peer (%s)", got)
+			}
+			if got := rulePeers[1].PodSelector.MatchLabels[krd.VNFIDLabel]; got != "othervnf" {
+				t.Fatalf("TestSelectVNFPods updated the peer of another VNF (%s)", got)
+			}
+		}
+	})
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+
+	"k8s.io/client-go/kubernetes"
+
+	pkgerrors "github.com/pkg/errors"
+
+	coreV1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"k8-plugin-multicloud/krd"
+)
+
+func main() {}
+
+// CreateResource object in a specific Kubernetes PersistentVolumeClaim
+func CreateResource(kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) (string, error) {
+	if kubedata.Namespace == "" {
+		kubedata.Namespace = "default"
+	}
+
+	log.Println("Reading pvc YAML")
+	rawBytes, err := krd.ReadManifest(kubedata)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "PersistentVolumeClaim YAML file read error")
+	}
+
+	log.Println("Decoding pvc YAML")
+	decode := scheme.Codecs.UniversalDeserializer().Decode
+	obj, _, err := decode(rawBytes, nil, nil)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Deserialize pvc error")
+	}
+
+	switch o := obj.(type) {
+	case *coreV1.PersistentVolumeClaim:
+		kubedata.PVCData = o
+	default:
+		return "", pkgerrors.New(kubedata.YamlFilePath + " contains another resource different than PersistentVolumeClaim")
+	}
+
+	kubedata.PVCData.Namespace = kubedata.Namespace
+	kubedata.PVCData.Name = kubedata.InternalVNFID + "-" + kubedata.PVCData.Name
+	krd.AddOwnershipMetadata(&kubedata.PVCData.ObjectMeta, kubedata)
+
+	if kubedata.DryRun || kubedata.RenderOnly {
+		return krd.DryRunCreate(kubeclient.CoreV1().RESTClient(), "persistentvolumeclaims", kubedata.PVCData, kubedata)
+	}
+
+	result, err := kubeclient.CoreV1().PersistentVolumeClaims(kubedata.Namespace).Create(kubedata.PVCData)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Create PersistentVolumeClaim error")
+	}
+
+	return result.GetObjectMeta().GetName(), nil
+}
+
+// ListResources of existing persistent volume claims hosted in a specific Kubernetes namespace
+func ListResources(limit int64, namespace string, labelSelector string, kubeclient *kubernetes.Clientset) (*[]string, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	opts := metaV1.ListOptions{
+		Limit:         limit,
+		LabelSelector: labelSelector,
+	}
+	opts.APIVersion = "v1"
+	opts.Kind = "PersistentVolumeClaim"
+
+	list, err := kubeclient.CoreV1().PersistentVolumeClaims(namespace).List(opts)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Get PersistentVolumeClaim list error")
+	}
+
+	result := make([]string, 0, limit)
+	if list != nil {
+		for _, pvc := range list.Items {
+			result = append(result, pvc.Name)
+		}
+	}
+
+	return &result, nil
+}
+
+// DeleteResource deletes an existing Kubernetes persistent volume claim
+func DeleteResource(name string, namespace string, kubeclient *kubernetes.Clientset) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	log.Println("Deleting pvc: " + name)
+
+	deletePolicy := metaV1.DeletePropagationForeground
+	err := kubeclient.CoreV1().PersistentVolumeClaims(namespace).Delete(name, &metaV1.DeleteOptions{
+		PropagationPolicy: &deletePolicy,
+	})
+	if err != nil {
+		return pkgerrors.Wrap(err, "Delete PersistentVolumeClaim error")
+	}
+
+	return nil
+}
+
+// GetResource existing persistent volume claim hosted in a specific Kubernetes namespace
+func GetResource(name string, namespace string, kubeclient *kubernetes.Clientset) (string, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	pvc, err := kubeclient.CoreV1().PersistentVolumeClaims(namespace).Get(name, metaV1.GetOptions{})
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", pkgerrors.Wrap(err, "Get PersistentVolumeClaim error")
+	}
+
+	return pvc.Name, nil
+}
+
+// LabelResource stamps the VNF ownership metadata on an existing persistent volume claim
+func LabelResource(name string, namespace string, kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	patch, err := krd.OwnershipPatch(kubedata)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Label PersistentVolumeClaim error")
+	}
+
+	_, err = kubeclient.CoreV1().PersistentVolumeClaims(namespace).Patch(name, types.MergePatchType, patch)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Label PersistentVolumeClaim error")
+	}
+
+	return nil
+}
+
+// RetainResource sets or removes the label of the persistent volume claims
+// kept after their VNF was deleted
+func RetainResource(name string, namespace string, retained bool, kubeclient *kubernetes.Clientset) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	// A null label is removed by the merge patch
+	var value interface{}
+	if retained {
+		value = "true"
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{
+				krd.RetainedLabel: value,
+			},
+		},
+	})
+	if err != nil {
+		return pkgerrors.Wrap(err, "Retain PersistentVolumeClaim error")
+	}
+
+	_, err = kubeclient.CoreV1().PersistentVolumeClaims(namespace).Patch(name, types.MergePatchType, patch)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Retain PersistentVolumeClaim error")
+	}
+
+	return nil
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"io/ioutil"
+	"log"
+
+	"k8s.io/client-go/kubernetes"
+
+	pkgerrors "github.com/pkg/errors"
+
+	coreV1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"k8-plugin-multicloud/krd"
+)
+
+func main() {}
+
+// CreateResource object in a specific Kubernetes Secret
+func CreateResource(kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) (string, error) {
+	if kubedata.Namespace == "" {
+		kubedata.Namespace = "default"
+	}
+
+	if kubedata.YamlFilePath != "" {
+		err := readSecretYAML(kubedata)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		err := buildSecretFromFiles(kubedata)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	kubedata.SecretData.Namespace = kubedata.Namespace
+	kubedata.SecretData.Name = kubedata.InternalVNFID + "-" + kubedata.SecretData.Name
+	krd.AddOwnershipMetadata(&kubedata.SecretData.ObjectMeta, kubedata)
+
+	if kubedata.DryRun || kubedata.RenderOnly {
+		return krd.DryRunCreate(kubeclient.CoreV1().RESTClient(), "secrets", kubedata.SecretData, kubedata)
+	}
+
+	result, err := kubeclient.CoreV1().Secrets(kubedata.Namespace).Create(kubedata.SecretData)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Create Secret error")
+	}
+
+	return result.GetObjectMeta().GetName(), nil
+}
+
+func readSecretYAML(kubedata *krd.GenericKubeResourceData) error {
+	log.Println("Reading secret YAML")
+	rawBytes, err := krd.ReadManifest(kubedata)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Secret YAML file read error")
+	}
+
+	log.Println("Decoding secret YAML")
+	decode := scheme.Codecs.UniversalDeserializer().Decode
+	obj, _, err := decode(rawBytes, nil, nil)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Deserialize secret error")
+	}
+
+	switch o := obj.(type) {
+	case *coreV1.Secret:
+		kubedata.SecretData = o
+	default:
+		return pkgerrors.New(kubedata.YamlFilePath + " contains another resource different than Secret")
+	}
+
+	return nil
+}
+
+func buildSecretFromFiles(kubedata *krd.GenericKubeResourceData) error {
+	if kubedata.Name == "" {
+		return pkgerrors.New("Secret name not provided")
+	}
+
+	kubedata.SecretData = &coreV1.Secret{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name: kubedata.Name,
+		},
+		Type: coreV1.SecretTypeOpaque,
+		Data: map[string][]byte{},
+	}
+
+	for key, path := range kubedata.Files {
+		log.Println("Reading secret file: " + path)
+		rawBytes, err := ioutil.ReadFile(path)
+		if err != nil {
+			return pkgerrors.Wrap(err, "Secret file "+path+" read error")
+		}
+
+		kubedata.SecretData.Data[key] = rawBytes
+	}
+
+	return nil
+}
+
+// ListResources of existing secrets hosted in a specific Kubernetes namespace
+func ListResources(limit int64, namespace string, labelSelector string, kubeclient *kubernetes.Clientset) (*[]string, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	opts := metaV1.ListOptions{
+		Limit:         limit,
+		LabelSelector: labelSelector,
+	}
+	opts.APIVersion = "v1"
+	opts.Kind = "Secret"
+
+	list, err := kubeclient.CoreV1().Secrets(namespace).List(opts)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Get Secret list error")
+	}
+
+	result := make([]string, 0, limit)
+	if list != nil {
+		for _, secret := range list.Items {
+			result = append(result, secret.Name)
+		}
+	}
+
+	return &result, nil
+}
+
+// DeleteResource deletes an existing Kubernetes secret
+func DeleteResource(name string, namespace string, kubeclient *kubernetes.Clientset) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	log.Println("Deleting secret: " + name)
+
+	deletePolicy := metaV1.DeletePropagationForeground
+	err := kubeclient.CoreV1().Secrets(namespace).Delete(name, &metaV1.DeleteOptions{
+		PropagationPolicy: &deletePolicy,
+	})
+	if err != nil {
+		return pkgerrors.Wrap(err, "Delete Secret error")
+	}
+
+	return nil
+}
+
+// GetResource existing secret hosted in a specific Kubernetes namespace
+func GetResource(name string, namespace string, kubeclient *kubernetes.Clientset) (string, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	secret, err := kubeclient.CoreV1().Secrets(namespace).Get(name, metaV1.GetOptions{})
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", pkgerrors.Wrap(err, "Get Secret error")
+	}
+
+	return secret.Name, nil
+}
+
+// LabelResource stamps the VNF ownership metadata on an existing secret
+func LabelResource(name string, namespace string, kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	patch, err := krd.OwnershipPatch(kubedata)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Label Secret error")
+	}
+
+	_, err = kubeclient.CoreV1().Secrets(namespace).Patch(name, types.MergePatchType, patch)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Label Secret error")
+	}
+
+	return nil
+}
+
+// UpdateResource replaces an existing secret with the one read from kubedata
+func UpdateResource(kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) (string, error) {
+	kubedata.RenderOnly = true
+	_, err := CreateResource(kubedata, kubeclient)
+	kubedata.RenderOnly = false
+	if err != nil {
+		return "", err
+	}
+
+	log.Println("Updating secret: " + kubedata.SecretData.Name)
+
+	current, err := kubeclient.CoreV1().Secrets(kubedata.Namespace).Get(kubedata.SecretData.Name, metaV1.GetOptions{})
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Get Secret error")
+	}
+	kubedata.SecretData.ResourceVersion = current.ResourceVersion
+
+	result, err := kubeclient.CoreV1().Secrets(kubedata.Namespace).Update(kubedata.SecretData)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Update Secret error")
+	}
+
+	return result.GetObjectMeta().GetName(), nil
+}
@@ -1,16 +1,16 @@
 package main
 
 import (
-	"io/ioutil"
 	"log"
-	"os"
 
 	"k8s.io/client-go/kubernetes"
 
 	pkgerrors "github.com/pkg/errors"
 
 	coreV1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 
 	"k8-plugin-multicloud/krd"
@@ -24,12 +24,8 @@ func CreateResource(kubedata *krd.GenericKubeResourceData, kubeclient *kubernete
 		kubedata.Namespace = "default"
 	}
 
-	if _, err := os.Stat(kubedata.YamlFilePath); err != nil {
-		return "", pkgerrors.New("File " + kubedata.YamlFilePath + " not found")
-	}
-
 	log.Println("Reading service YAML")
-	rawBytes, err := ioutil.ReadFile(kubedata.YamlFilePath)
+	rawBytes, err := krd.ReadManifest(kubedata)
 	if err != nil {
 		return "", pkgerrors.Wrap(err, "Service YAML file read error")
 	}
@@ -50,6 +46,11 @@ func CreateResource(kubedata *krd.GenericKubeResourceData, kubeclient *kubernete
 
 	kubedata.ServiceData.Namespace = kubedata.Namespace
 	kubedata.ServiceData.Name = kubedata.InternalVNFID + "-" + kubedata.ServiceData.Name
+	krd.AddOwnershipMetadata(&kubedata.ServiceData.ObjectMeta, kubedata)
+
+	if kubedata.DryRun || kubedata.RenderOnly {
+		return krd.DryRunCreate(kubeclient.CoreV1().RESTClient(), "services", kubedata.ServiceData, kubedata)
+	}
 
 	result, err := kubeclient.CoreV1().Services(kubedata.Namespace).Create(kubedata.ServiceData)
 	if err != nil {
@@ -59,12 +60,13 @@ func CreateResource(kubedata *krd.GenericKubeResourceData, kubeclient *kubernete
 }
 
 // ListResources of existing deployments hosted in a specific Kubernetes Deployment
-func ListResources(limit int64, namespace string, kubeclient *kubernetes.Clientset) (*[]string, error) {
+func ListResources(limit int64, namespace string, labelSelector string, kubeclient *kubernetes.Clientset) (*[]string, error) {
 	if namespace == "" {
 		namespace = "default"
 	}
 	opts := metaV1.ListOptions{
-		Limit: limit,
+		Limit:         limit,
+		LabelSelector: labelSelector,
 	}
 	opts.APIVersion = "apps/v1"
 	opts.Kind = "Service"
@@ -107,22 +109,59 @@ func GetResource(name string, namespace string, kubeclient *kubernetes.Clientset
 		namespace = "default"
 	}
 
-	opts := metaV1.ListOptions{
-		Limit: 10,
+	service, err := kubeclient.CoreV1().Services(namespace).Get(name, metaV1.GetOptions{})
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", pkgerrors.Wrap(err, "Get Service error")
 	}
-	opts.APIVersion = "apps/v1"
-	opts.Kind = "Service"
 
-	list, err := kubeclient.CoreV1().Services(namespace).List(opts)
+	return service.Name, nil
+}
+
+// LabelResource stamps the VNF ownership metadata on an existing service
+func LabelResource(name string, namespace string, kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	patch, err := krd.OwnershipPatch(kubedata)
 	if err != nil {
-		return "", pkgerrors.Wrap(err, "Get Deployment error")
+		return pkgerrors.Wrap(err, "Label Service error")
 	}
 
-	for _, service := range list.Items {
-		if name == service.Name {
-			return name, nil
-		}
+	_, err = kubeclient.CoreV1().Services(namespace).Patch(name, types.MergePatchType, patch)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Label Service error")
+	}
+
+	return nil
+}
+
+// UpdateResource replaces an existing service with the one read from kubedata, keeping
+// the cluster IP allocated to it
+func UpdateResource(kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) (string, error) {
+	kubedata.RenderOnly = true
+	_, err := CreateResource(kubedata, kubeclient)
+	kubedata.RenderOnly = false
+	if err != nil {
+		return "", err
 	}
 
-	return "", nil
+	log.Println("Updating service: " + kubedata.ServiceData.Name)
+
+	current, err := kubeclient.CoreV1().Services(kubedata.Namespace).Get(kubedata.ServiceData.Name, metaV1.GetOptions{})
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Get Service error")
+	}
+	kubedata.ServiceData.ResourceVersion = current.ResourceVersion
+	kubedata.ServiceData.Spec.ClusterIP = current.Spec.ClusterIP
+
+	result, err := kubeclient.CoreV1().Services(kubedata.Namespace).Update(kubedata.ServiceData)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Update Service error")
+	}
+
+	return result.GetObjectMeta().GetName(), nil
 }
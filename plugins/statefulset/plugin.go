@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+
+	pkgerrors "github.com/pkg/errors"
+
+	appsV1 "k8s.io/api/apps/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"k8-plugin-multicloud/krd"
+)
+
+func main() {}
+
+// CreateResource object in a specific Kubernetes StatefulSet
+func CreateResource(kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) (string, error) {
+	if kubedata.Namespace == "" {
+		kubedata.Namespace = "default"
+	}
+
+	log.Println("Reading statefulset YAML")
+	rawBytes, err := krd.ReadManifest(kubedata)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "StatefulSet YAML file read error")
+	}
+
+	log.Println("Decoding statefulset YAML")
+	decode := scheme.Codecs.UniversalDeserializer().Decode
+	obj, _, err := decode(rawBytes, nil, nil)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Deserialize statefulset error")
+	}
+
+	switch o := obj.(type) {
+	case *appsV1.StatefulSet:
+		kubedata.StatefulSetData = o
+	default:
+		return "", pkgerrors.New(kubedata.YamlFilePath + " contains another resource different than StatefulSet")
+	}
+
+	kubedata.StatefulSetData.Namespace = kubedata.Namespace
+	kubedata.StatefulSetData.Name = kubedata.InternalVNFID + "-" + kubedata.StatefulSetData.Name
+	krd.AddOwnershipMetadata(&kubedata.StatefulSetData.ObjectMeta, kubedata)
+	krd.UpdatePodReferences(&kubedata.StatefulSetData.Spec.Template.Spec, kubedata.RenamedResources)
+	krd.AddOwnershipMetadata(&kubedata.StatefulSetData.Spec.Template.ObjectMeta, kubedata)
+
+	// The headless service governing the pods is usually part of the VNF
+	serviceName, ok := kubedata.RenamedResources["service"][kubedata.StatefulSetData.Spec.ServiceName]
+	if ok {
+		kubedata.StatefulSetData.Spec.ServiceName = serviceName
+	}
+	for i := range kubedata.StatefulSetData.Spec.VolumeClaimTemplates {
+		krd.AddOwnershipMetadata(&kubedata.StatefulSetData.Spec.VolumeClaimTemplates[i].ObjectMeta, kubedata)
+	}
+
+	if kubedata.DryRun || kubedata.RenderOnly {
+		return krd.DryRunCreate(kubeclient.AppsV1().RESTClient(), "statefulsets", kubedata.StatefulSetData, kubedata)
+	}
+
+	result, err := kubeclient.AppsV1().StatefulSets(kubedata.Namespace).Create(kubedata.StatefulSetData)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Create StatefulSet error")
+	}
+
+	return result.GetObjectMeta().GetName(), nil
+}
+
+// ListResources of existing statefulsets hosted in a specific Kubernetes namespace
+func ListResources(limit int64, namespace string, labelSelector string, kubeclient *kubernetes.Clientset) (*[]string, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	opts := metaV1.ListOptions{
+		Limit:         limit,
+		LabelSelector: labelSelector,
+	}
+	opts.APIVersion = "apps/v1"
+	opts.Kind = "StatefulSet"
+
+	list, err := kubeclient.AppsV1().StatefulSets(namespace).List(opts)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Get StatefulSet list error")
+	}
+
+	result := make([]string, 0, limit)
+	if list != nil {
+		for _, statefulSet := range list.Items {
+			result = append(result, statefulSet.Name)
+		}
+	}
+
+	return &result, nil
+}
+
+// DeleteResource deletes an existing Kubernetes statefulset
+func DeleteResource(name string, namespace string, kubeclient *kubernetes.Clientset) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	log.Println("Deleting statefulset: " + name)
+
+	deletePolicy := metaV1.DeletePropagationForeground
+	err := kubeclient.AppsV1().StatefulSets(namespace).Delete(name, &metaV1.DeleteOptions{
+		PropagationPolicy: &deletePolicy,
+	})
+	if err != nil {
+		return pkgerrors.Wrap(err, "Delete StatefulSet error")
+	}
+
+	return nil
+}
+
+// GetResource existing statefulset hosted in a specific Kubernetes namespace
+func GetResource(name string, namespace string, kubeclient *kubernetes.Clientset) (string, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	statefulSet, err := kubeclient.AppsV1().StatefulSets(namespace).Get(name, metaV1.GetOptions{})
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", pkgerrors.Wrap(err, "Get StatefulSet error")
+	}
+
+	return statefulSet.Name, nil
+}
+
+// LabelResource stamps the VNF ownership metadata on an existing statefulset
+func LabelResource(name string, namespace string, kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	patch, err := krd.OwnershipPatch(kubedata)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Label StatefulSet error")
+	}
+
+	_, err = kubeclient.AppsV1().StatefulSets(namespace).Patch(name, types.MergePatchType, patch)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Label StatefulSet error")
+	}
+
+	return nil
+}
+
+// ScaleResource sets the number of replicas of an existing statefulset
+func ScaleResource(name string, namespace string, replicas int32, kubeclient *kubernetes.Clientset) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	log.Println("Scaling statefulset: " + name)
+
+	patch := []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas))
+	_, err := kubeclient.AppsV1().StatefulSets(namespace).Patch(name, types.MergePatchType, patch)
+	if err != nil {
+		return pkgerrors.Wrap(err, "Scale StatefulSet error")
+	}
+
+	return nil
+}
+
+// GetReplicas returns the number of replicas requested for an existing statefulset
+func GetReplicas(name string, namespace string, kubeclient *kubernetes.Clientset) (int32, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	statefulSet, err := kubeclient.AppsV1().StatefulSets(namespace).Get(name, metaV1.GetOptions{})
+	if err != nil {
+		return 0, pkgerrors.Wrap(err, "Get StatefulSet error")
+	}
+
+	if statefulSet.Spec.Replicas == nil {
+		return 1, nil
+	}
+
+	return *statefulSet.Spec.Replicas, nil
+}
+
+// UpdateResource replaces an existing statefulset with the one read from kubedata,
+// keeping its current number of replicas. Changes to the pod template start a
+// rolling update.
+func UpdateResource(kubedata *krd.GenericKubeResourceData, kubeclient *kubernetes.Clientset) (string, error) {
+	kubedata.RenderOnly = true
+	_, err := CreateResource(kubedata, kubeclient)
+	kubedata.RenderOnly = false
+	if err != nil {
+		return "", err
+	}
+
+	log.Println("Updating statefulset: " + kubedata.StatefulSetData.Name)
+
+	current, err := kubeclient.AppsV1().StatefulSets(kubedata.Namespace).Get(kubedata.StatefulSetData.Name, metaV1.GetOptions{})
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Get StatefulSet error")
+	}
+	kubedata.StatefulSetData.ResourceVersion = current.ResourceVersion
+	kubedata.StatefulSetData.Spec.Replicas = current.Spec.Replicas
+
+	result, err := kubeclient.AppsV1().StatefulSets(kubedata.Namespace).Update(kubedata.StatefulSetData)
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "Update StatefulSet error")
+	}
+
+	return result.GetObjectMeta().GetName(), nil
+}
+
+// WaitForResource blocks until the rollout of a statefulset completes or the
+// timeout expires
+func WaitForResource(name string, namespace string, timeout time.Duration, kubeclient *kubernetes.Clientset) error {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	log.Println("Waiting for statefulset: " + name)
+
+	err := wait.PollImmediate(2*time.Second, timeout, func() (bool, error) {
+		statefulSet, err := kubeclient.AppsV1().StatefulSets(namespace).Get(name, metaV1.GetOptions{})
+		if err != nil {
+			return false, pkgerrors.Wrap(err, "Get StatefulSet error")
+		}
+
+		if statefulSet.Status.ObservedGeneration < statefulSet.Generation {
+			return false, nil
+		}
+
+		replicas := int32(1)
+		if statefulSet.Spec.Replicas != nil {
+			replicas = *statefulSet.Spec.Replicas
+		}
+
+		if statefulSet.Spec.UpdateStrategy.Type == appsV1.RollingUpdateStatefulSetStrategyType &&
+			statefulSet.Status.UpdateRevision != statefulSet.Status.CurrentRevision {
+			return false, nil
+		}
+
+		return statefulSet.Status.ReadyReplicas == replicas, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return pkgerrors.New("StatefulSet " + name + " did not roll out within " + timeout.String())
+	}
+	return err
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+
+	"k8-plugin-multicloud/csar"
+	"k8-plugin-multicloud/db"
+	"k8-plugin-multicloud/krd"
+)
+
+// Drift describes the objects of a VNF which are missing from its cluster
+type Drift struct {
+	VNFID         string              `json:"vnf_id"`
+	CloudRegionID string              `json:"cloud_region_id"`
+	Namespace     string              `json:"namespace"`
+	Missing       map[string][]string `json:"missing_components"`
+	Recreated     map[string][]string `json:"recreated_components,omitempty"`
+	Error         string              `json:"error,omitempty"`
+	CheckedAt     time.Time           `json:"checked_at"`
+}
+
+// ErrOperationInProgress is returned for the VNFs locked by an operation of
+// the API, e.g. a deletion or an upgrade
+var ErrOperationInProgress = pkgerrors.New("Operation in progress on the VNF")
+
+var (
+	mutex sync.RWMutex
+
+	// key: cloud1-default-uuid
+	drifts = make(map[string]Drift)
+)
+
+// Start runs the reconciliation loop in the background, checking every
+// stored VNF once per interval. Missing objects are created again from the
+// original CSAR when recreate is set.
+func Start(interval time.Duration, recreate bool) {
+	log.Println("Starting VNF reconciler every " + interval.String())
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			err := Run(recreate)
+			if err != nil {
+				log.Println("Reconciliation error: " + err.Error())
+			}
+		}
+	}()
+}
+
+// Run checks every stored VNF once
+func Run(recreate bool) error {
+	internalVNFIDs, err := db.DBconn.ReadAll("")
+	if err != nil {
+		return pkgerrors.Wrap(err, "Get VNF list error")
+	}
+
+	stored := make(map[string]bool)
+
+	for _, internalVNFID := range internalVNFIDs {
+		if internalVNFID == "" || strings.Contains(internalVNFID, "/") {
+			continue
+		}
+		stored[internalVNFID] = true
+
+		_, err := CheckVNF(internalVNFID, recreate)
+		if err == ErrOperationInProgress {
+			log.Println("Skipping " + internalVNFID + ": " + err.Error())
+			continue
+		}
+		if err != nil {
+			log.Println("Error reconciling " + internalVNFID + ": " + err.Error())
+		}
+	}
+
+	// Forget the VNFs deleted since the last run
+	mutex.Lock()
+	for internalVNFID := range drifts {
+		if !stored[internalVNFID] {
+			delete(drifts, internalVNFID)
+		}
+	}
+	mutex.Unlock()
+
+	return nil
+}
+
+// CheckVNF compares the stored resources of a VNF with the objects of its
+// cluster and records the result. VNFs with an operation in progress are
+// skipped, their objects being deleted or replaced.
+func CheckVNF(internalVNFID string, recreate bool) (Drift, error) {
+	unlock, ok := db.TryLockKey(internalVNFID)
+	if !ok {
+		return Drift{}, ErrOperationInProgress
+	}
+	defer unlock()
+
+	record, found, err := db.ReadVNFRecord(internalVNFID)
+	if err != nil {
+		return Drift{}, err
+	}
+	if found == false {
+		return Drift{}, pkgerrors.New("VNF " + internalVNFID + " not found")
+	}
+
+	// Entries stored without their cloud region and namespace can't be mapped
+	// to a cluster
+	if record.CloudRegionID == "" || record.Namespace == "" {
+		return Drift{}, pkgerrors.New("VNF " + internalVNFID + " has no cloud region or namespace")
+	}
+
+	drift := Drift{
+		VNFID:         strings.TrimPrefix(internalVNFID, record.CloudRegionID+"-"+record.Namespace+"-"),
+		CloudRegionID: record.CloudRegionID,
+		Namespace:     record.Namespace,
+		CheckedAt:     time.Now(),
+	}
+
+	err = checkResources(&drift, record, recreate)
+	if err != nil {
+		drift.Error = err.Error()
+	}
+
+	if len(drift.Recreated) > 0 {
+		csar.RecordRevision(internalVNFID, drift.VNFID, record, csar.OperationRecreate)
+	}
+
+	mutex.Lock()
+	drifts[internalVNFID] = drift
+	mutex.Unlock()
+
+	return drift, err
+}
+
+func checkResources(drift *Drift, record db.VNFRecord, recreate bool) error {
+	kubeclient, err := krd.GetKubeClient(os.Getenv("KUBE_CONFIG_DIR") + "/" + record.CloudRegionID)
+	if err != nil {
+		return err
+	}
+
+	drift.Missing, err = csar.FindMissingResources(record.Resources, record.Namespace, &kubeclient)
+	if err != nil {
+		return err
+	}
+
+	if !recreate || len(drift.Missing) == 0 || record.CsarID == "" {
+		return nil
+	}
+
+	drift.Recreated, err = csar.RecreateResources(record.CsarID, record.CloudRegionID, record.Namespace,
+		drift.VNFID, record.Parameters, record.Overlay, record.Resources, drift.Missing, &kubeclient)
+	if err != nil {
+		return err
+	}
+
+	return csar.RestoreReplicas(drift.Recreated, record.Namespace, record.Replicas,
+		record.State == db.VNFStateStopped, &kubeclient)
+}
+
+// ListDrifts returns the last check result of every VNF with missing objects
+func ListDrifts() []Drift {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	result := make([]Drift, 0, len(drifts))
+	for _, drift := range drifts {
+		if len(drift.Missing) > 0 || drift.Error != "" {
+			result = append(result, drift)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2018 Intel Corporation.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconcile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/kubernetes"
+
+	"k8-plugin-multicloud/csar"
+	"k8-plugin-multicloud/db"
+	"k8-plugin-multicloud/krd"
+)
+
+type mockDB struct {
+	db.DatabaseConnection
+
+	// revisions written by the reconciliation
+	revisions []string
+}
+
+func (c *mockDB) ReadEntry(key string) (string, bool, error) {
+	str := "{\"csar_id\":\"uuid\",\"cloud_region_id\":\"cloud1\",\"namespace\":\"default\"," +
+		"\"resources\":{\"deployment\":[\"cloud1-default-uuid-sisedeploy\"],\"service\":[\"cloud1-default-uuid-sisesvc\"]}}"
+	return str, true, nil
+}
+
+func (c *mockDB) ReadAll(key string) ([]string, error) {
+	returnVal := []string{"cloud1-default-uuid"}
+	return returnVal, nil
+}
+
+func (c *mockDB) CreateEntry(key string, value string) error {
+	c.revisions = append(c.revisions, value)
+	return nil
+}
+
+func TestCheckVNF(t *testing.T) {
+	oldGetKubeClient := krd.GetKubeClient
+	oldFindMissingResources := csar.FindMissingResources
+	oldRecreateResources := csar.RecreateResources
+	oldCSARDigest := csar.CSARDigest
+	oldRenderVNF := csar.RenderVNF
+
+	defer func() {
+		krd.GetKubeClient = oldGetKubeClient
+		csar.FindMissingResources = oldFindMissingResources
+		csar.RecreateResources = oldRecreateResources
+		csar.CSARDigest = oldCSARDigest
+		csar.RenderVNF = oldRenderVNF
+	}()
+
+	mockdb := &mockDB{}
+	db.DBconn = mockdb
+
+	csar.CSARDigest = func(csarID string) (string, error) {
+		return "digest", nil
+	}
+
+	csar.RenderVNF = func(d string, id string, r string, n string, v string, p map[string]string, o string) (string, error) {
+		return "", nil
+	}
+
+	krd.GetKubeClient = func(configPath string) (kubernetes.Clientset, error) {
+		return kubernetes.Clientset{}, nil
+	}
+
+	missing := map[string][]string{
+		"service": []string{"cloud1-default-uuid-sisesvc"},
+	}
+
+	csar.FindMissingResources = func(data map[string][]string, namespace string, kubeclient *kubernetes.Clientset) (map[string][]string, error) {
+		return missing, nil
+	}
+
+	t.Run("Successfully detect drift", func(t *testing.T) {
+		csar.RecreateResources = func(csarID string, cloudRegionID string, namespace string, externalVNFID string,
+			parameters map[string]string, overlay string, data map[string][]string, missing map[string][]string, kubeclient *kubernetes.Clientset) (map[string][]string, error) {
+			t.Fatalf("TestCheckVNF recreated resources without being asked to")
+			return nil, nil
+		}
+
+		drift, err := CheckVNF("cloud1-default-uuid", false)
+		if err != nil {
+			t.Fatalf("TestCheckVNF returned an error (%s)", err)
+		}
+
+		if drift.VNFID != "uuid" || !reflect.DeepEqual(drift.Missing, missing) {
+			t.Fatalf("TestCheckVNF returned unexpected drift (%v)", drift)
+		}
+	})
+
+	t.Run("Successfully recreate missing resources", func(t *testing.T) {
+		csar.RecreateResources = func(csarID string, cloudRegionID string, namespace string, externalVNFID string,
+			parameters map[string]string, overlay string, data map[string][]string, missing map[string][]string, kubeclient *kubernetes.Clientset) (map[string][]string, error) {
+			return missing, nil
+		}
+
+		err := Run(true)
+		if err != nil {
+			t.Fatalf("TestCheckVNF returned an error (%s)", err)
+		}
+
+		drifts := ListDrifts()
+		if len(drifts) != 1 || !reflect.DeepEqual(drifts[0].Recreated, missing) {
+			t.Fatalf("TestCheckVNF returned unexpected drifts (%v)", drifts)
+		}
+
+		if len(mockdb.revisions) != 1 || !strings.Contains(mockdb.revisions[0], "\"operation\":\"recreate\"") {
+			t.Fatalf("TestCheckVNF didn't record a revision (%v)", mockdb.revisions)
+		}
+	})
+
+	t.Run("Skip VNFs with an operation in progress", func(t *testing.T) {
+		csar.RecreateResources = func(csarID string, cloudRegionID string, namespace string, externalVNFID string,
+			parameters map[string]string, overlay string, data map[string][]string, missing map[string][]string, kubeclient *kubernetes.Clientset) (map[string][]string, error) {
+			t.Fatalf("TestCheckVNF recreated resources during an operation")
+			return nil, nil
+		}
+
+		unlock := db.LockKey("cloud1-default-uuid")
+		defer unlock()
+
+		_, err := CheckVNF("cloud1-default-uuid", true)
+		if err != ErrOperationInProgress {
+			t.Fatalf("TestCheckVNF returned:\n result=%v\n expected=%v", err, ErrOperationInProgress)
+		}
+	})
+}